@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"regexp"
 	"strconv"
@@ -39,15 +40,21 @@ func PaceSubtitleFile(in SubtitleFile, rate float64) (SubtitleFile, error) {
 		return res, errors.New("Input rate should be a positive, floating-point number")
 	}
 
-	whole, frac := math.Modf(1. / rate)
 	for _, sub := range in.Subtitles {
-		sub.Start = sub.Start*time.Duration(whole) + sub.Start*time.Duration(int(frac*1000))/1000
-		sub.End = sub.End*time.Duration(whole) + sub.End*time.Duration(int(frac*1000))/1000
+		sub.Start = paceDuration(sub.Start, rate)
+		sub.End = paceDuration(sub.End, rate)
 		res.Subtitles = append(res.Subtitles, sub)
 	}
 	return res, nil
 }
 
+// paceDuration scales a single timestamp the way PaceSubtitleFile does,
+// to the nanosecond, so that late subtitles don't drift for rates such as
+// 24/23.976.
+func paceDuration(d time.Duration, rate float64) time.Duration {
+	return time.Duration(math.Round(float64(d) / rate))
+}
+
 // SearchSubtitleFile scans the contents of all subtitle entries
 // in a subtitle file for matches with the provided string,
 // which can be a valid Regular Expression.
@@ -70,8 +77,13 @@ func SerializeSubtitles(subfile SubtitleFile) SubtitleFile {
 	// This is a simple operation, that normally would take place
 	// after parsing a subtitle files. Should we return some errors
 	// to help with debugging and future expansion?
-	var res SubtitleFile = subfile
-	for i := range subfile.Subtitles {
+	// The input's subtitles are copied, so that it's left unchanged
+	res := subfile
+	if subfile.Subtitles != nil {
+		res.Subtitles = make([]Subtitle, len(subfile.Subtitles))
+		copy(res.Subtitles, subfile.Subtitles)
+	}
+	for i := range res.Subtitles {
 		res.Subtitles[i].Index = i + 1
 	}
 	return res
@@ -82,7 +94,7 @@ func SerializeSubtitles(subfile SubtitleFile) SubtitleFile {
 // Returns the pair or pairs of subtitles where overlaps where detected,
 // one of which is the culprit
 func DetectOverlaps(subfile SubtitleFile) []Subtitle {
-	// Maybe in case of "longer" overlaps eg. sub 5 w/ sub 20, we should return the offending pair instead of the consecutive ones
+	// For "longer" overlaps eg. sub 5 w/ sub 20, DetectAllOverlaps returns the offending pair instead of the consecutive ones
 	var overlaps []Subtitle
 	for i := 0; i < len(subfile.Subtitles)-1; i++ {
 		if subfile.Subtitles[i].End > subfile.Subtitles[i+1].Start {
@@ -114,7 +126,7 @@ func RemoveSubtitle(subfile SubtitleFile, idx int) (SubtitleFile, error) {
 }
 
 func AddSubtitle(subfile SubtitleFile, start, end, content, metadata, header string) (SubtitleFile, error) {
-	var res SubtitleFile
+	res := SubtitleFile{Headers: subfile.Headers}
 	startTime, _ := StrToDuration(start)
 	endTime, _ := StrToDuration(end)
 	if startTime < 0 || endTime < 0 || endTime < startTime {
@@ -152,7 +164,7 @@ func AddSubtitle(subfile SubtitleFile, start, end, content, metadata, header str
 
 		if placed == true {
 			// New index is n+2, one for the new entry, one for the zero-based indexing
-			res.Subtitles = append(res.Subtitles, Subtitle{i + 2, sub.Start, sub.End, sub.Content, metadata, header})
+			res.Subtitles = append(res.Subtitles, Subtitle{i + 2, sub.Start, sub.End, sub.Content, sub.Metadata, sub.Header})
 		}
 	}
 	if placed == false {
@@ -161,35 +173,98 @@ func AddSubtitle(subfile SubtitleFile, start, end, content, metadata, header str
 	return res, nil
 }
 
-func PrintSubfileInfo(subfile SubtitleFile) {
+// UpdateOptions configures UpdateSubtitle. With Reorder, a subtitle whose
+// new Start takes it past its neighbours is moved to its place, instead of
+// the update being refused.
+type UpdateOptions struct {
+	Reorder bool
+}
 
-	cpmLo, cpmHi, cpmAvg, runtime := 10000., 0., 0., 0.
-	cpmLoIdx, cpmHiIdx, runtime := 0, 0, 0.
-	for _, sub := range subfile.Subtitles {
-		currentDur := (sub.End - sub.Start).Seconds()
-		currentCpm := float64(len(sub.Content)) / currentDur * 60
-		if currentCpm > cpmHi {
-			cpmHi = currentCpm
-			cpmHiIdx = sub.Index
+// UpdateSubtitle changes the start, end and text of the subtitle at idx, as
+// numbered from 1, in place. Empty arguments are left unchanged, as are the
+// subtitle's Metadata and Header. A new timing is checked against the other
+// subtitles: it should be ordered and should not overlap any of them. The
+// input subtitle file is not modified, and the result is only renumbered
+// if the subtitle was moved.
+func UpdateSubtitle(subfile SubtitleFile, idx int, start, end, content string, opts UpdateOptions) (SubtitleFile, error) {
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	if idx <= 0 || idx > len(subfile.Subtitles) {
+		return res, errors.New("The index marked for update is invalid :" + strconv.Itoa(idx))
+	}
+	idx -= 1
+	sub := res.Subtitles[idx]
+	for _, t := range []struct {
+		in  string
+		out *time.Duration
+	}{{start, &sub.Start}, {end, &sub.End}} {
+		if t.in == "" {
+			continue
+		}
+		d, err := StrToDuration(t.in)
+		if err != nil {
+			return res, errors.New("The provided time is invalid :`" + t.in + "`")
+		}
+		*t.out = d
+	}
+	if content != "" {
+		sub.Content = content
+	}
+	if start == "" && end == "" {
+		res.Subtitles[idx] = sub
+		return res, nil
+	}
+
+	if sub.Start < 0 || sub.End < sub.Start {
+		return res, errors.New("Start and End times should be positive and ordered, ignoring input... " + sub.Start.String() + " - " + sub.End.String())
+	}
+	for i, other := range res.Subtitles {
+		if i != idx && other.Start < sub.End && sub.Start < other.End {
+			return res, errors.New("The new timing of subtitle " + strconv.Itoa(idx+1) + " would overlap with subtitle " + strconv.Itoa(i+1))
 		}
-		if currentCpm < cpmLo {
-			cpmLo = currentCpm
-			cpmLoIdx = sub.Index
+	}
+	// Subtitles with the same Start stay in their order
+	pos := idx
+	for pos > 0 && sub.Start < res.Subtitles[pos-1].Start {
+		pos--
+	}
+	for pos < len(res.Subtitles)-1 && sub.Start > res.Subtitles[pos+1].Start {
+		pos++
+	}
+	if pos == idx {
+		res.Subtitles[idx] = sub
+		return res, nil
+	}
+	if !opts.Reorder {
+		neighbour := idx
+		if pos > idx {
+			neighbour = idx + 2
 		}
-		cpmAvg += currentCpm
-		runtime += currentDur
-	}
-	cpmAvg = cpmAvg / runtime
-
-	fmt.Printf("Headers : %v\n", subfile.Headers)
-	fmt.Printf("Number of subtitles : %d\n", len(subfile.Subtitles))
-	fmt.Printf("Start Time : %v\n", subfile.Subtitles[0].Start)
-	fmt.Printf("End Time : %v\n", subfile.Subtitles[len(subfile.Subtitles)-1].End)
-	fmt.Printf("First-to-last Runtime : %v\n", (subfile.Subtitles[len(subfile.Subtitles)-1].End - subfile.Subtitles[0].Start))
-	fmt.Printf("Subtitle Runtime : %v\n\n", time.Duration(time.Duration(runtime)*time.Second))
-
-	fmt.Printf("An average human reads at a pace of about 850 Characters Per Minute (CPM)\n")
-	fmt.Printf("Highest CPM : %.2f on subtitle index : %d\n", cpmHi, cpmHiIdx)
-	fmt.Printf("Lowest CPM : %.2f on subtitle index : %d\n", cpmLo, cpmLoIdx)
-	fmt.Printf("Average CPM : %.2f\n", cpmAvg)
+		return res, errors.New("The new timing would move subtitle " + strconv.Itoa(idx+1) + " past subtitle " + strconv.Itoa(neighbour))
+	}
+	res.Subtitles = append(res.Subtitles[:idx], res.Subtitles[idx+1:]...)
+	res.Subtitles = append(res.Subtitles[:pos], append([]Subtitle{sub}, res.Subtitles[pos:]...)...)
+	return SerializeSubtitles(res), nil
+}
+
+// PrintSubfileInfo writes the headers, timing and reading speed of the file to w.
+func PrintSubfileInfo(w io.Writer, subfile SubtitleFile) {
+
+	stats := MeasureReadingStats(subfile, ReadingOptions{})
+
+	fmt.Fprintf(w, "Headers : %v\n", subfile.Headers)
+	fmt.Fprintf(w, "Number of subtitles : %d\n", len(subfile.Subtitles))
+	fmt.Fprintf(w, "Start Time : %v\n", subfile.Subtitles[0].Start)
+	fmt.Fprintf(w, "End Time : %v\n", subfile.Subtitles[len(subfile.Subtitles)-1].End)
+	fmt.Fprintf(w, "First-to-last Runtime : %v\n", (subfile.Subtitles[len(subfile.Subtitles)-1].End - subfile.Subtitles[0].Start))
+	fmt.Fprintf(w, "Subtitle Runtime : %v\n\n", stats.Runtime)
+
+	fmt.Fprintf(w, "An average human reads at a pace of about 850 Characters Per Minute (CPM)\n")
+	fmt.Fprintf(w, "Highest CPM : %.2f on subtitle index : %d\n", stats.Fastest.CPM(), stats.Fastest.Index)
+	fmt.Fprintf(w, "Lowest CPM : %.2f on subtitle index : %d\n", stats.Slowest.CPM(), stats.Slowest.Index)
+	fmt.Fprintf(w, "Average CPM : %.2f\n", stats.AverageCPM())
+	fmt.Fprintf(w, "Average WPM : %.2f\n", stats.AverageWPM)
+	if stats.Untimed > 0 {
+		fmt.Fprintf(w, "Subtitles without running time : %d\n", stats.Untimed)
+	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"testing"
 	"time"
@@ -315,6 +316,9 @@ func TestSerializeSubtitles(t *testing.T) {
 			t.Errorf("Testing SerializeSubtitles using %v. Expected %v but got %v instead!", pair.input, pair.expected, actual)
 		}
 	}
+	if tests[0].input.Subtitles[0].Index != 0 {
+		t.Errorf("Testing SerializeSubtitles. The input subtitle file was modified")
+	}
 }
 
 func TestRemoveSubtitles(t *testing.T) {
@@ -601,6 +605,74 @@ PEW`, "", ""},
 	}
 }
 
+func TestUpdateSubtitle(t *testing.T) {
+	type testpair struct {
+		idx         int
+		start       string
+		end         string
+		content     string
+		opts        UpdateOptions
+		expected    []Subtitle
+		expectedErr error
+	}
+
+	in := SubtitleFile{[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "1 meta", "header"},
+		{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+	},
+		"",
+	}
+
+	var tests = []testpair{
+		{
+			2, "", "", `Έχουμε χάσει τους αγαπημένους μας.`, UpdateOptions{},
+			[]Subtitle{
+				in.Subtitles[0],
+				{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει τους αγαπημένους μας.`, "1 meta", "header"},
+				in.Subtitles[2],
+			},
+			nil,
+		},
+		{
+			2, "3.5s", "8s", "", UpdateOptions{},
+			[]Subtitle{
+				in.Subtitles[0],
+				{2, time.Duration(time.Second*3 + time.Millisecond*500), time.Duration(time.Second * 8), `Έχουμε χάσει αγαπημένους μας.`, "1 meta", "header"},
+				in.Subtitles[2],
+			},
+			nil,
+		},
+		{
+			1, "15s", "16s", "", UpdateOptions{Reorder: true},
+			[]Subtitle{
+				{1, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "1 meta", "header"},
+				{2, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+				{3, time.Duration(time.Second * 15), time.Duration(time.Second * 16), `Έχουμε όλοι υποφέρει.`, "", ""},
+			},
+			nil,
+		},
+		{1, "15s", "16s", "", UpdateOptions{}, in.Subtitles, errors.New("The new timing would move subtitle 1 past subtitle 2")},
+		{3, "0s", "", "", UpdateOptions{}, in.Subtitles, errors.New("The new timing of subtitle 3 would overlap with subtitle 1")},
+		{2, "", "3s", "", UpdateOptions{}, in.Subtitles, errors.New("Start and End times should be positive and ordered, ignoring input... 4s - 3s")},
+		{2, "soon", "", "", UpdateOptions{}, in.Subtitles, errors.New("The provided time is invalid :`soon`")},
+		{4, "", "", "Nope", UpdateOptions{}, in.Subtitles, errors.New("The index marked for update is invalid :4")},
+	}
+
+	for _, pair := range tests {
+		actual, actualErr := UpdateSubtitle(in, pair.idx, pair.start, pair.end, pair.content, pair.opts)
+		if (actualErr == nil) != (pair.expectedErr == nil) || (actualErr != nil && actualErr.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing UpdateSubtitle using %v. Expected error %v but got %v instead!", pair.idx, pair.expectedErr, actualErr)
+		}
+		if !cmp.Equal(actual.Subtitles, pair.expected) {
+			t.Errorf("Testing UpdateSubtitle using %v. Expected %v but got %v instead!", pair.idx, pair.expected, actual.Subtitles)
+		}
+	}
+	if in.Subtitles[0].Start != time.Second {
+		t.Errorf("Testing UpdateSubtitle. Expected the input to be left unchanged, got %v", in)
+	}
+}
+
 func TestPrintSubfileInfo(t *testing.T) {
 
 	in := SubtitleFile{
@@ -614,18 +686,24 @@ func TestPrintSubfileInfo(t *testing.T) {
 		"sample_headers",
 	}
 
-	PrintSubfileInfo(in)
-	//Headers : sample_headers
-	//Number of subtitles : 5
-	//Start Time : 1.602s
-	//End Time : 19.751s
-	//First-to-last Runtime : 18.149s
-	//Subtitle Runtime : 12s
-	//
-	//An average human reads at a pace of about 850 Characters Per Minute (CPM)
-	//Highest CPM : 131.72 on subtitle index : 5
-	//Lowest CPM : 63.31 on subtitle index : 2
-	//Average CPM : 39.57
+	var out bytes.Buffer
+	PrintSubfileInfo(&out, in)
+	expected := `Headers : sample_headers
+Number of subtitles : 5
+Start Time : 1.602s
+End Time : 19.751s
+First-to-last Runtime : 18.149s
+Subtitle Runtime : 12.746s
+
+An average human reads at a pace of about 850 Characters Per Minute (CPM)
+Highest CPM : 131.72 on subtitle index : 5
+Lowest CPM : 63.31 on subtitle index : 2
+Average CPM : 94.15
+Average WPM : 23.54
+`
+	if out.String() != expected {
+		t.Errorf("Testing PrintSubfileInfo. Expected %q but got %q instead!", expected, out.String())
+	}
 }
 
 func TestSearchSubtitleFile(t *testing.T) {
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultVADFrame is the length of the audio window that
+// voice activity detection classifies as speech or silence.
+const DefaultVADFrame = 10 * time.Millisecond
+
+// PCMAudio holds a decoded audio track, downmixed to a single
+// channel, with samples normalized to the [-1, 1] range.
+type PCMAudio struct {
+	SampleRate int
+	Samples    []float64
+}
+
+// Duration returns the running time of the audio track.
+func (a PCMAudio) Duration() time.Duration {
+	if a.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(len(a.Samples)) * time.Second / time.Duration(a.SampleRate)
+}
+
+// SpeechTimeline is the result of voice activity detection.
+// Each entry in Speech covers one Frame of audio, in order,
+// and is true if speech was detected in that frame.
+type SpeechTimeline struct {
+	Frame  time.Duration
+	Speech []bool
+}
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// ParseWAVFile reads a RIFF/WAVE file containing integer PCM
+// (8, 16, 24 or 32 bits) or 32-bit floating-point samples.
+// Multiple channels are averaged into a single one.
+func ParseWAVFile(filename string) (PCMAudio, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return PCMAudio{}, errors.New("Could not open file " + filename + " for reading")
+	}
+	return ParseWAV(content)
+}
+
+// ParseWAV decodes the contents of a WAV file, see ParseWAVFile.
+func ParseWAV(content []byte) (PCMAudio, error) {
+	var res PCMAudio
+	if len(content) < 12 || !bytes.Equal(content[0:4], []byte("RIFF")) || !bytes.Equal(content[8:12], []byte("WAVE")) {
+		return res, errors.New("The provided data is not a RIFF/WAVE file")
+	}
+
+	var format, channels, bits int
+	var data []byte
+	fmtFound, dataFound := false, false
+	for pos := 12; pos+8 <= len(content); {
+		id := string(content[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(content[pos+4 : pos+8]))
+		pos += 8
+		if size < 0 || pos+size > len(content) {
+			// Some encoders write a bogus size for the last chunk
+			size = len(content) - pos
+		}
+		chunk := content[pos : pos+size]
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return res, errors.New("The WAV fmt chunk is too short")
+			}
+			format = int(binary.LittleEndian.Uint16(chunk[0:2]))
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			res.SampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			bits = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			if format == wavFormatExtensible && size >= 26 {
+				// The actual format is stored in the first two bytes of the SubFormat GUID
+				format = int(binary.LittleEndian.Uint16(chunk[24:26]))
+			}
+			fmtFound = true
+		case "data":
+			data = chunk
+			dataFound = true
+		}
+		// Chunks are padded to an even number of bytes
+		pos += size + size%2
+	}
+	if !fmtFound || !dataFound {
+		return res, errors.New("The WAV file is missing its fmt or data chunk")
+	}
+	if channels <= 0 || res.SampleRate <= 0 {
+		return res, errors.New("The WAV file reports an invalid channel count or sample rate")
+	}
+
+	var decode func([]byte) float64
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		decode = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == wavFormatPCM && bits == 16:
+		decode = func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case format == wavFormatPCM && bits == 24:
+		decode = func(b []byte) float64 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return float64(v) / (1 << 23)
+		}
+	case format == wavFormatPCM && bits == 32:
+		decode = func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case format == wavFormatFloat && bits == 32:
+		decode = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	default:
+		return res, errors.New("Unsupported WAV sample format " + strconv.Itoa(format) + " with " + strconv.Itoa(bits) + " bits per sample")
+	}
+
+	width := bits / 8
+	block := width * channels
+	res.Samples = make([]float64, len(data)/block)
+	for i := range res.Samples {
+		var sum float64
+		for c := 0; c < channels; c++ {
+			off := i*block + c*width
+			sum += decode(data[off : off+width])
+		}
+		res.Samples[i] = sum / float64(channels)
+	}
+	return res, nil
+}
+
+// DetectVoiceActivity splits the audio into frames and marks each as
+// speech or silence, based on its energy compared to the noise floor
+// of the whole track. Short pauses inside speech are bridged and
+// short bursts of noise are dropped, so that the resulting timeline
+// resembles the way dialogue is broken into subtitles.
+func DetectVoiceActivity(audio PCMAudio, frame time.Duration) (SpeechTimeline, error) {
+	res := SpeechTimeline{Frame: frame}
+	if frame <= 0 {
+		return res, errors.New("The VAD frame length should be positive")
+	}
+	if audio.SampleRate <= 0 {
+		return res, errors.New("The audio track has an invalid sample rate")
+	}
+	perFrame := int(int64(audio.SampleRate) * int64(frame) / int64(time.Second))
+	if perFrame <= 0 {
+		return res, errors.New("The VAD frame is shorter than a single audio sample")
+	}
+
+	n := len(audio.Samples) / perFrame
+	energy := make([]float64, n)
+	for i := range energy {
+		var sum float64
+		for _, s := range audio.Samples[i*perFrame : (i+1)*perFrame] {
+			sum += s * s
+		}
+		energy[i] = math.Sqrt(sum / float64(perFrame))
+	}
+
+	// The quietest tenth of the track is taken as the noise floor;
+	// speech has to be about 10dB above it, and never below -40dBFS.
+	threshold := 0.01
+	if n > 0 {
+		sorted := make([]float64, n)
+		copy(sorted, energy)
+		sort.Float64s(sorted)
+		if floor := sorted[n/10] * 3; floor > threshold {
+			threshold = floor
+		}
+	}
+
+	res.Speech = make([]bool, n)
+	for i, e := range energy {
+		res.Speech[i] = e > threshold
+	}
+
+	minPause := int(300 * time.Millisecond / frame)
+	minBurst := int(100 * time.Millisecond / frame)
+	fillRuns(res.Speech, false, minPause)
+	fillRuns(res.Speech, true, minBurst)
+	return res, nil
+}
+
+// fillRuns flips runs of `value` shorter than `min` frames that are
+// enclosed by the opposite value. Runs touching either end of the
+// timeline are left alone when bridging pauses.
+func fillRuns(timeline []bool, value bool, min int) {
+	for i := 0; i < len(timeline); {
+		if timeline[i] != value {
+			i++
+			continue
+		}
+		j := i
+		for j < len(timeline) && timeline[j] == value {
+			j++
+		}
+		enclosed := i > 0 && j < len(timeline)
+		if j-i < min && (enclosed || value) {
+			for k := i; k < j; k++ {
+				timeline[k] = !value
+			}
+		}
+		i = j
+	}
+}
+
+// SpeechRatio returns the fraction of frames marked as speech.
+func (s SpeechTimeline) SpeechRatio() float64 {
+	if len(s.Speech) == 0 {
+		return 0
+	}
+	count := 0
+	for _, v := range s.Speech {
+		if v {
+			count++
+		}
+	}
+	return float64(count) / float64(len(s.Speech))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testInterval struct {
+	start time.Duration
+	end   time.Duration
+}
+
+// synthesizeSpeech returns a mono track with a low noise floor, and
+// a loud, modulated tone standing in for speech within each interval.
+func synthesizeSpeech(sampleRate int, length time.Duration, speech []testInterval) []float64 {
+	samples := make([]float64, int(length.Seconds()*float64(sampleRate)))
+	seed := uint32(1)
+	for i := range samples {
+		seed = seed*1664525 + 1013904223
+		samples[i] = (float64(seed>>16)/65535 - 0.5) * 0.002
+	}
+	for _, iv := range speech {
+		from := int(iv.start.Seconds() * float64(sampleRate))
+		to := int(iv.end.Seconds() * float64(sampleRate))
+		for i := from; i < to && i < len(samples); i++ {
+			t := float64(i) / float64(sampleRate)
+			samples[i] += 0.4 * math.Sin(2*math.Pi*220*t) * (0.6 + 0.4*math.Sin(2*math.Pi*4*t))
+		}
+	}
+	return samples
+}
+
+// encodeWAV builds a WAV file with identical integer PCM channels.
+func encodeWAV(samples []float64, sampleRate, channels, bits int) []byte {
+	var data bytes.Buffer
+	for _, s := range samples {
+		for c := 0; c < channels; c++ {
+			switch bits {
+			case 8:
+				data.WriteByte(byte(int(s*127) + 128))
+			case 16:
+				binary.Write(&data, binary.LittleEndian, int16(s*32767))
+			case 24:
+				v := int32(s * 8388607)
+				data.Write([]byte{byte(v), byte(v >> 8), byte(v >> 16)})
+			}
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+data.Len()))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(wavFormatPCM))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*channels*bits/8))
+	binary.Write(&buf, binary.LittleEndian, uint16(channels*bits/8))
+	binary.Write(&buf, binary.LittleEndian, uint16(bits))
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(data.Len()))
+	buf.Write(data.Bytes())
+	return buf.Bytes()
+}
+
+func TestParseWAV(t *testing.T) {
+	type testpair struct {
+		input            []byte
+		expectedRate     int
+		expectedSamples  int
+		expectedFirstVal float64
+		expectedErr      string
+	}
+
+	samples := []float64{0.5, -0.5, 0.25, 0}
+	var tests = []testpair{
+		{encodeWAV(samples, 8000, 1, 16), 8000, 4, 0.5, ""},
+		{encodeWAV(samples, 16000, 2, 16), 16000, 4, 0.5, ""},
+		{encodeWAV(samples, 8000, 1, 8), 8000, 4, 0.5, ""},
+		{encodeWAV(samples, 44100, 2, 24), 44100, 4, 0.5, ""},
+		{[]byte("1\n00:00:01,602 --> 00:00:03,314\n"), 0, 0, 0, "The provided data is not a RIFF/WAVE file"},
+		{encodeWAV(samples, 8000, 1, 12), 0, 0, 0, "Unsupported WAV sample format 1 with 12 bits per sample"},
+	}
+
+	for _, pair := range tests {
+		actual, err := ParseWAV(pair.input)
+		if pair.expectedErr != "" {
+			if err == nil || err.Error() != pair.expectedErr {
+				t.Errorf("Testing ParseWAV. Expected error %v but got %v instead!", pair.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Testing ParseWAV. Got unexpected error %v", err)
+			continue
+		}
+		if actual.SampleRate != pair.expectedRate || len(actual.Samples) != pair.expectedSamples {
+			t.Errorf("Testing ParseWAV. Expected %v samples at %vHz but got %v at %vHz instead", pair.expectedSamples, pair.expectedRate, len(actual.Samples), actual.SampleRate)
+			continue
+		}
+		if math.Abs(actual.Samples[0]-pair.expectedFirstVal) > 0.01 {
+			t.Errorf("Testing ParseWAV. Expected first sample as %v but got %v instead", pair.expectedFirstVal, actual.Samples[0])
+		}
+	}
+}
+
+func TestParseWAVFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "speech.wav")
+	if err := ioutil.WriteFile(fn, encodeWAV(synthesizeSpeech(8000, 2*time.Second, nil), 8000, 1, 16), 0600); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := ParseWAVFile(fn)
+	if err != nil {
+		t.Errorf("Testing ParseWAVFile with %v. Got unexpected error %v", fn, err)
+	}
+	if actual.Duration() != 2*time.Second {
+		t.Errorf("Testing ParseWAVFile with %v. Expected duration as %v but got %v instead", fn, 2*time.Second, actual.Duration())
+	}
+
+	_, err = ParseWAVFile("wrongfilename")
+	if err == nil || err.Error() != "Could not open file wrongfilename for reading" {
+		t.Errorf("Testing ParseWAVFile with a missing file. Got unexpected error %v", err)
+	}
+}
+
+func TestDetectVoiceActivity(t *testing.T) {
+	speech := []testInterval{
+		{1 * time.Second, 2500 * time.Millisecond},
+		// A pause shorter than 300ms should be bridged
+		{2700 * time.Millisecond, 3 * time.Second},
+		{5 * time.Second, 6 * time.Second},
+	}
+	audio := PCMAudio{8000, synthesizeSpeech(8000, 8*time.Second, speech)}
+
+	actual, err := DetectVoiceActivity(audio, DefaultVADFrame)
+	if err != nil {
+		t.Fatalf("Testing DetectVoiceActivity. Got unexpected error %v", err)
+	}
+	if len(actual.Speech) != 800 {
+		t.Fatalf("Testing DetectVoiceActivity. Expected 800 frames but got %v instead", len(actual.Speech))
+	}
+
+	type testpair struct {
+		at       time.Duration
+		expected bool
+	}
+	var tests = []testpair{
+		{500 * time.Millisecond, false},
+		{1500 * time.Millisecond, true},
+		{2600 * time.Millisecond, true},
+		{4 * time.Second, false},
+		{5500 * time.Millisecond, true},
+		{7 * time.Second, false},
+	}
+	for _, pair := range tests {
+		if got := actual.Speech[int(pair.at/actual.Frame)]; got != pair.expected {
+			t.Errorf("Testing DetectVoiceActivity at %v. Expected speech as %v but got %v instead", pair.at, pair.expected, got)
+		}
+	}
+
+	if _, err := DetectVoiceActivity(audio, 0); err == nil || err.Error() != "The VAD frame length should be positive" {
+		t.Errorf("Testing DetectVoiceActivity with a zero frame. Got unexpected error %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// BilingualPlacement decides where the secondary language is shown.
+type BilingualPlacement int
+
+const (
+	// SecondaryBelow stacks the secondary text under the primary one.
+	SecondaryBelow BilingualPlacement = iota
+	// SecondaryAbove stacks the secondary text over the primary one.
+	SecondaryAbove
+	// SecondaryOnTop shows the secondary text at the top of the screen,
+	// leaving the primary one at its place.
+	SecondaryOnTop
+)
+
+// BilingualOptions configures MergeBilingual. The secondary text takes the
+// formatting of SecondaryStyle, eg. italics or a colour, on top of its own.
+// A secondary subtitle is only matched with the primary ones it overlaps
+// by at least MinOverlap.
+type BilingualOptions struct {
+	Placement      BilingualPlacement
+	SecondaryStyle TextStyle
+	MinOverlap     time.Duration
+}
+
+// overlap returns how long two subtitles are on screen together.
+func overlap(a, b Subtitle) time.Duration {
+	start, end := a.Start, a.End
+	if b.Start > start {
+		start = b.Start
+	}
+	if b.End < end {
+		end = b.End
+	}
+	if end < start {
+		return 0
+	}
+	return end - start
+}
+
+// styleSecondary applies the secondary style to a subtitle's text,
+// dropping its position, which is decided by the placement.
+func styleSecondary(content string, style TextStyle) string {
+	styled := ParseStyledText(content)
+	styled.Position = Position{}
+	for i := range styled.Spans {
+		s := &styled.Spans[i].Style
+		s.Bold = s.Bold || style.Bold
+		s.Italic = s.Italic || style.Italic
+		s.Underline = s.Underline || style.Underline
+		s.Strikethrough = s.Strikethrough || style.Strikethrough
+		if s.Color == "" {
+			s.Color = style.Color
+		}
+	}
+	return styled.SRT()
+}
+
+// freeTime returns the longest part of the subtitle's display time that
+// none of the others cover, which is empty if they cover all of it.
+func freeTime(sub Subtitle, others []Subtitle) (time.Duration, time.Duration) {
+	var covering []Subtitle
+	for _, other := range others {
+		if overlap(sub, other) > 0 {
+			covering = append(covering, other)
+		}
+	}
+	sort.SliceStable(covering, func(i, j int) bool { return covering[i].Start < covering[j].Start })
+	var bestStart, bestEnd time.Duration
+	start := sub.Start
+	for _, other := range append(covering, Subtitle{Start: sub.End, End: sub.End}) {
+		end := other.Start
+		if end > sub.End {
+			end = sub.End
+		}
+		if end-start > bestEnd-bestStart {
+			bestStart, bestEnd = start, end
+		}
+		if other.End > start {
+			start = other.End
+		}
+	}
+	return bestStart, bestEnd
+}
+
+// shareText splits the text of a subtitle into a piece for each of the
+// subtitles it spans, at sentence and clause boundaries where possible.
+func shareText(content string, pieces int) ([]string, bool) {
+	units := reflowUnits(content)
+	if pieces > len(units) {
+		return nil, false
+	}
+	cuts, ok := splitPoints(units, pieces, lineWidth(units, 0, len(units)))
+	if !ok {
+		return nil, false
+	}
+	return splitText(units, cuts), true
+}
+
+// MergeBilingual merges two subtitle files in different languages into one,
+// so that both can be shown at once. The primary file decides the timing:
+// secondary subtitles are matched with the primary ones they overlap, the
+// text of several of them is merged when they overlap the same one, and
+// split when one spans several primary subtitles. Secondary subtitles that
+// overlap nothing keep their own timing, while the ones that overlap
+// primary subtitles by less than MinOverlap are clipped to the time those
+// leave free, or merged with the one they overlap most if there's none,
+// unless they're shown on top. The result is renumbered.
+func MergeBilingual(primary, secondary SubtitleFile, opts BilingualOptions) SubtitleFile {
+	texts := make([][]string, len(primary.Subtitles))
+	var unmatched []Subtitle
+	for _, sec := range secondary.Subtitles {
+		var matches []int
+		best, closest := -1, -1
+		for i, sub := range primary.Subtitles {
+			d := overlap(sub, sec)
+			if d > 0 && (closest < 0 || d > overlap(primary.Subtitles[closest], sec)) {
+				closest = i
+			}
+			if d == 0 || d < opts.MinOverlap {
+				continue
+			}
+			matches = append(matches, i)
+			if best < 0 || d > overlap(primary.Subtitles[best], sec) {
+				best = i
+			}
+		}
+		switch {
+		case len(matches) == 0 && closest >= 0 && opts.Placement != SecondaryOnTop:
+			start, end := freeTime(sec, primary.Subtitles)
+			if end <= start {
+				texts[closest] = append(texts[closest], sec.Content)
+				continue
+			}
+			sec.Start, sec.End = start, end
+			unmatched = append(unmatched, sec)
+		case len(matches) == 0:
+			unmatched = append(unmatched, sec)
+		case len(matches) == 1:
+			texts[best] = append(texts[best], sec.Content)
+		default:
+			pieces, ok := shareText(sec.Content, len(matches))
+			if !ok {
+				texts[best] = append(texts[best], sec.Content)
+				continue
+			}
+			for j, i := range matches {
+				texts[i] = append(texts[i], pieces[j])
+			}
+		}
+	}
+
+	var subs []Subtitle
+	for i, sub := range primary.Subtitles {
+		if len(texts[i]) == 0 {
+			subs = append(subs, sub)
+			continue
+		}
+		text := styleSecondary(strings.Join(texts[i], "\n"), opts.SecondaryStyle)
+		switch opts.Placement {
+		case SecondaryAbove:
+			pos := sub.Position()
+			sub = sub.WithPosition(Position{})
+			sub.Content = text + "\n" + sub.Content
+			sub = sub.WithPosition(pos)
+		case SecondaryOnTop:
+			top := Subtitle{sub.Index, sub.Start, sub.End, text, "", ""}
+			subs = append(subs, top.WithPosition(Position{Alignment: AlignTop}))
+		default:
+			sub.Content += "\n" + text
+		}
+		subs = append(subs, sub)
+	}
+	for _, sec := range unmatched {
+		sec.Content = styleSecondary(sec.Content, opts.SecondaryStyle)
+		if opts.Placement == SecondaryOnTop {
+			sec = sec.WithPosition(Position{Alignment: AlignTop})
+		}
+		subs = append(subs, sec)
+	}
+	sort.SliceStable(subs, func(i, j int) bool { return subs[i].Start < subs[j].Start })
+	return SerializeSubtitles(SubtitleFile{subs, primary.Headers})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeBilingual(t *testing.T) {
+	type testpair struct {
+		opts     BilingualOptions
+		expected []Subtitle
+	}
+
+	primary, _ := ParseSRTFile("samples/sample.srt")
+	secondary, _ := ParseSRTFile("samples/sample_en.srt")
+	subs := primary.Subtitles
+
+	var tests = []testpair{
+		{
+			BilingualOptions{SecondaryStyle: TextStyle{Italic: true}, MinOverlap: 250 * time.Millisecond},
+			[]Subtitle{
+				{1, subs[0].Start, subs[0].End, "Έχουμε όλοι υποφέρει.\n<i>We have all suffered.</i>", "", ""},
+				{2, subs[1].Start, subs[1].End, "Έχουμε χάσει αγαπημένους μας.\n<i>We have lost\nour loved ones.</i>", "", ""},
+				{3, subs[2].Start, subs[2].End, "Αυτό δεν αφορά τους Οίκους των ευγενών,\nαλλά τους ζωντανούς και τους νεκρούς.\n<i>This is not about the noble Houses, but the living and the dead.</i>", "", ""},
+				{4, subs[3].Start, subs[3].End, "Κι εγώ σκοπεύω να ζήσω.\n<i>And I intend to live.</i>", "", ""},
+				{5, subs[4].Start, subs[4].End, "Σας προσφέρω την επιλογή...", "", ""},
+				{6, time.Duration(time.Second*20 + time.Millisecond*500), time.Duration(time.Second * 22), "<i>I offer you a choice.</i>", "", ""},
+			},
+		},
+		{
+			BilingualOptions{Placement: SecondaryOnTop, SecondaryStyle: TextStyle{Color: "yellow"}, MinOverlap: 250 * time.Millisecond},
+			[]Subtitle{
+				{1, subs[0].Start, subs[0].End, `{\an8}<font color="yellow">We have all suffered.</font>`, "", ""},
+				{2, subs[0].Start, subs[0].End, "Έχουμε όλοι υποφέρει.", "", ""},
+				{3, subs[1].Start, subs[1].End, "{\\an8}<font color=\"yellow\">We have lost\nour loved ones.</font>", "", ""},
+				{4, subs[1].Start, subs[1].End, "Έχουμε χάσει αγαπημένους μας.", "", ""},
+				{5, subs[2].Start, subs[2].End, `{\an8}<font color="yellow">This is not about the noble Houses, but the living and the dead.</font>`, "", ""},
+				{6, subs[2].Start, subs[2].End, "Αυτό δεν αφορά τους Οίκους των ευγενών,\nαλλά τους ζωντανούς και τους νεκρούς.", "", ""},
+				{7, subs[3].Start, subs[3].End, `{\an8}<font color="yellow">And I intend to live.</font>`, "", ""},
+				{8, subs[3].Start, subs[3].End, "Κι εγώ σκοπεύω να ζήσω.", "", ""},
+				{9, subs[4].Start, subs[4].End, "Σας προσφέρω την επιλογή...", "", ""},
+				{10, time.Duration(time.Second*20 + time.Millisecond*500), time.Duration(time.Second * 22), `{\an8}<font color="yellow">I offer you a choice.</font>`, "", ""},
+			},
+		},
+	}
+
+	for _, pair := range tests {
+		res := MergeBilingual(primary, secondary, pair.opts)
+		if !cmp.Equal(res.Subtitles, pair.expected) {
+			t.Errorf("Testing MergeBilingual with %v. Expected\n%v\nbut got\n%v", pair.opts, pair.expected, res.Subtitles)
+		}
+	}
+
+	// Without a minimum overlap, the first English subtitle also overlaps the second Greek one
+	res := MergeBilingual(primary, SubtitleFile{[]Subtitle{{1, time.Second, 5 * time.Second, "We have all suffered. We have lost.", "", ""}}, ""}, BilingualOptions{})
+	if res.Subtitles[0].Content != "Έχουμε όλοι υποφέρει.\nWe have all suffered." || res.Subtitles[1].Content != "Έχουμε χάσει αγαπημένους μας.\nWe have lost." {
+		t.Error("Expected the secondary subtitle to be split over the first two, got", res.Subtitles[:2])
+	}
+
+	// Secondary subtitles that barely overlap are clipped, or merged if nothing's left
+	barely := SubtitleFile{[]Subtitle{
+		{1, 3200 * time.Millisecond, 4700 * time.Millisecond, "In between.", "", ""},
+		{2, 7300 * time.Millisecond, 7350 * time.Millisecond, "Within.", "", ""},
+	}, ""}
+	expected := []Subtitle{
+		{1, subs[0].Start, subs[0].End, "Έχουμε όλοι υποφέρει.", "", ""},
+		{2, subs[0].End, subs[1].Start, "In between.", "", ""},
+		{3, subs[1].Start, subs[1].End, "Έχουμε χάσει αγαπημένους μας.\nWithin.", "", ""},
+	}
+	res = MergeBilingual(primary, barely, BilingualOptions{MinOverlap: 250 * time.Millisecond})
+	if !cmp.Equal(res.Subtitles[:3], expected) || len(DetectAllOverlaps(res)) != 0 {
+		t.Errorf("Testing MergeBilingual with barely overlapping subtitles. Expected\n%v\nbut got\n%v", expected, res.Subtitles)
+	}
+}
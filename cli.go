@@ -0,0 +1,1014 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cliCommand is a single gophersub subcommand, eg. `gophersub shift`.
+// Handlers return the process exit code.
+type cliCommand struct {
+	usage       string
+	description string
+	run         func(args []string, stdout, stderr io.Writer) int
+}
+
+func cliCommands() map[string]cliCommand {
+	return map[string]cliCommand{
+		"bilingual": {
+			"bilingual -with FILE [-at below|above|top] [-italic] [-color COLOR] [-min-overlap TIME] [-o OUTFILE] FILE",
+			"Merge a subtitle file in a second language into another one, showing both at once",
+			runBilingual,
+		},
+		"diff": {
+			"diff [-format unified|side|json|patch] [-width N] OLDFILE NEWFILE",
+			"Show the subtitles added, removed, retimed or reworded between two files, exiting with 1 if they differ",
+			runDiff,
+		},
+		"info": {
+			"info FILE",
+			"Print practical information about a subtitle file",
+			runInfo,
+		},
+		"search": {
+			"search -e PATTERN [-i] [-plain] [-C N] [-from TIME] [-to TIME] [-min-duration TIME] [-max-duration TIME] FILE|DIR",
+			"Search subtitle text with a regular expression, in a file or in every subtitle file under a directory",
+			runSearch,
+		},
+		"sanitize": {
+			"sanitize [-format srt|vtt|ass] [-allow FEATURES] [-strip] [-o OUTFILE] FILE",
+			"Repair formatting tags, converting or removing the ones the format does not support",
+			runSanitize,
+		},
+		"shift": {
+			"shift -by OFFSET [-fps RATE] [-o OUTFILE] FILE",
+			"Timeshift a subtitle file by a duration (1.5s) or an SMPTE timecode (00:00:01:12)",
+			runShift,
+		},
+		"join": {
+			"join [-lengths LENGTH[,LENGTH...]] [-fps RATE] [-o OUTFILE] FILE FILE...",
+			"Join the subtitle files of a video split in parts, given the length of each part but the last, or up to its last subtitle",
+			runJoin,
+		},
+		"lint": {
+			"lint [-config FILE] [-sdh] [-fix -o OUTFILE] [-rules] FILE",
+			"Report problems in a subtitle file, exiting with an error code if any errors are found",
+			runLint,
+		},
+		"merge": {
+			"merge [-markers] [-conflicts JSONFILE] [-o OUTFILE] BASE OURS THEIRS",
+			"Combine the changes two editors made to the same subtitle file, exiting with 1 if they conflict",
+			runMerge,
+		},
+		"patch": {
+			"patch -p PATCHFILE [-o OUTFILE] FILE",
+			"Apply a patch made with diff -format patch, if it still matches the subtitle file",
+			runPatch,
+		},
+		"position": {
+			"position -at ALIGNMENT [-from TIME] [-to TIME] [-o OUTFILE] FILE",
+			"Move the subtitles shown in a time range to the top, bottom or middle of the screen",
+			runPosition,
+		},
+		"reflow": {
+			"reflow [-lines N] [-width N] [-balanced] [-o OUTFILE] FILE",
+			"Rewrap subtitle text into at most N lines of at most N characters",
+			runReflow,
+		},
+		"replace": {
+			"replace -find PATTERN -with TEXT [-regexp] [-i] [-w] [-from-index N] [-to-index N] [-from TIME] [-to TIME] [-n] [-o OUTFILE] FILE",
+			"Search and replace subtitle text, printing the changes with -n instead of applying them",
+			runReplace,
+		},
+		"reindex": {
+			"reindex [-dedupe] [-o OUTFILE] FILE",
+			"Sort subtitles by their timing and renumber them, reporting duplicates or removing them with -dedupe",
+			runReindex,
+		},
+		"rebase": {
+			"rebase -programme-start TIMECODE -fps RATE [-remove] [-o OUTFILE] FILE",
+			"Time a subtitle file against a start-of-programme timecode, or remove it with -remove",
+			runRebase,
+		},
+		"validate": {
+			"validate -profile NAME|FILE [-profiles] FILE",
+			"Check a subtitle file against a style-guide profile, exiting with an error code if it does not comply",
+			runValidate,
+		},
+		"sound": {
+			"sound -from TIME -to TIME -text DESCRIPTION [-parens] [-lower] [-o OUTFILE] FILE",
+			"Insert a sound or effect description, eg. [DOOR SLAMS], between existing subtitles",
+			runSound,
+		},
+		"speakers": {
+			"speakers -set INDEX=NAME[,NAME] ... [-dashes] [-o OUTFILE] FILE",
+			"Label subtitles with their speakers, or use dialogue dashes with -dashes",
+			runSpeakers,
+		},
+		"split": {
+			"split -at TIME [-fps RATE] -o1 OUTFILE -o2 OUTFILE FILE",
+			"Split a subtitle file in two at a point in time, starting the second part at zero",
+			runSplit,
+		},
+		"strip-sdh": {
+			"strip-sdh [-lang CODE] [-o OUTFILE] FILE",
+			"Remove sound descriptions, speaker labels and music from SDH subtitles",
+			runStripSDH,
+		},
+		"timecodes": {
+			"timecodes -fps RATE [-df] [-programme-start TIMECODE] FILE",
+			"List the subtitle timings as SMPTE timecodes",
+			runTimecodes,
+		},
+	}
+}
+
+// runCLI parses the command-line arguments, runs the requested
+// subcommand and returns the exit code for the process.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	commands := cliCommands()
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printCLIUsage(commands, stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", args[0])
+		printCLIUsage(commands, stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func printCLIUsage(commands map[string]cliCommand, w io.Writer) {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, "Usage: gophersub COMMAND [FLAGS] FILE\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n    \t%s\n", commands[name].usage, commands[name].description)
+	}
+}
+
+// newFlagSet returns a FlagSet for a subcommand, reporting errors to stderr
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// loadSubtitleFile parses the input file of a subcommand, in the format of
+// its extension, see ParseSubtitleFile. Parsing errors are reported as
+// warnings, unless no subtitles could be read at all.
+func loadSubtitleFile(fs *flag.FlagSet, stderr io.Writer) (SubtitleFile, bool) {
+	if fs.NArg() != 1 {
+		fmt.Fprintf(stderr, "Expected a single input file, got %d arguments\n", fs.NArg())
+		return SubtitleFile{}, false
+	}
+	subfile, errs := ParseSubtitleFile(fs.Arg(0))
+	for _, err := range errs {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+	if len(subfile.Subtitles) == 0 {
+		fmt.Fprintf(stderr, "No subtitles could be read from %v\n", fs.Arg(0))
+		return subfile, false
+	}
+	return subfile, true
+}
+
+// writeSubtitleFile writes the result of a subcommand, either to the output
+// file, which is truncated if it exists, in the format of its extension,
+// or to stdout as SRT.
+func writeSubtitleFile(subfile SubtitleFile, outfile string, stdout, stderr io.Writer) int {
+	return writeSubtitles(subfile, outfile, formatForFile(outfile), stdout, stderr)
+}
+
+// writeSubtitles writes the result of a subcommand in the given format.
+func writeSubtitles(subfile SubtitleFile, outfile string, format TextFormat, stdout, stderr io.Writer) int {
+	out := stdout
+	if outfile != "" {
+		f, err := os.Create(outfile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Could not open file %v for writing\n", outfile)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := WriteSubtitles(out, subfile, format); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// parseOffset reads either a Go duration such as "-1.5s", or an
+// SMPTE timecode such as "-00:00:01:12", which requires a frame rate.
+func parseOffset(in string, fps float64) (time.Duration, error) {
+	if !strings.ContainsAny(in, ":;") {
+		return StrToDuration(in)
+	}
+	sign := time.Duration(1)
+	if strings.HasPrefix(in, "-") {
+		sign, in = -1, in[1:]
+	}
+	tc, err := ParseTimecode(in, fps)
+	if err != nil {
+		return 0, err
+	}
+	return sign * tc.Duration(), nil
+}
+
+func runBilingual(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("bilingual", stderr)
+	with := fs.String("with", "", "subtitle file in the secondary language")
+	at := fs.String("at", "below", "where to show the secondary language, below, above or top")
+	var opts BilingualOptions
+	fs.BoolVar(&opts.SecondaryStyle.Italic, "italic", false, "show the secondary language in italics")
+	fs.StringVar(&opts.SecondaryStyle.Color, "color", "", "colour of the secondary language, eg. yellow or #ffff00")
+	minOverlap := fs.String("min-overlap", "250ms", "how long subtitles should overlap to be shown together")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	placements := map[string]BilingualPlacement{"below": SecondaryBelow, "above": SecondaryAbove, "top": SecondaryOnTop}
+	placement, ok := placements[*at]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown placement %q\n", *at)
+		return 2
+	}
+	opts.Placement = placement
+	d, err := StrToDuration(*minOverlap)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid time %q : %v\n", *minOverlap, err)
+		return 2
+	}
+	opts.MinOverlap = d
+	if *with == "" {
+		fmt.Fprintln(stderr, "The secondary subtitle file should be provided with -with")
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	secondary, errs := ParseSubtitleFile(*with)
+	for _, err := range errs {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+	if len(secondary.Subtitles) == 0 {
+		fmt.Fprintf(stderr, "No subtitles could be read from %v\n", *with)
+		return 1
+	}
+	return writeSubtitleFile(MergeBilingual(subfile, secondary, opts), *outfile, stdout, stderr)
+}
+
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", stderr)
+	format := fs.String("format", "unified", "output format, unified, side, json or patch")
+	width := fs.Int("width", 40, "width of each column in side-by-side output")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintf(stderr, "Expected two input files, got %d arguments\n", fs.NArg())
+		return 2
+	}
+	var files [2]SubtitleFile
+	for i, file := range fs.Args() {
+		subfile, errs := ParseSubtitleFile(file)
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "warning: %v\n", err)
+		}
+		if len(subfile.Subtitles) == 0 {
+			fmt.Fprintf(stderr, "No subtitles could be read from %v\n", file)
+			return 2
+		}
+		files[i] = subfile
+	}
+	changes := DiffSubtitleFiles(files[0], files[1])
+	switch *format {
+	case "unified":
+		fmt.Fprint(stdout, FormatUnifiedDiff(changes))
+	case "side":
+		fmt.Fprint(stdout, FormatSideBySide(changes, *width))
+	case "json":
+		out, err := DiffJSON(changes)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fmt.Fprintln(stdout, string(out))
+	case "patch":
+		out, err := PatchJSON(PatchFromDiff(changes))
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fmt.Fprintln(stdout, string(out))
+	default:
+		fmt.Fprintf(stderr, "Unknown output format %q\n", *format)
+		return 2
+	}
+	for _, c := range changes {
+		if c.Changed() {
+			return 1
+		}
+	}
+	return 0
+}
+
+func runInfo(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("info", stderr)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	PrintSubfileInfo(stdout, subfile)
+	return 0
+}
+
+func runShift(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("shift", stderr)
+	by := fs.String("by", "", "offset as a duration (1.5s) or an SMPTE timecode (00:00:01:12)")
+	fps := fs.Float64("fps", 0, "frame rate, required for timecode offsets")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	offset, err := parseOffset(*by, *fps)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid offset %q : %v\n", *by, err)
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	return writeSubtitleFile(TimeshiftSubtitleFile(subfile, offset), *outfile, stdout, stderr)
+}
+
+func runSplit(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("split", stderr)
+	at := fs.String("at", "", "split point as a duration (52m10s) or an SMPTE timecode (00:52:10:00)")
+	fps := fs.Float64("fps", 0, "frame rate, required for timecodes")
+	outfiles := [2]*string{fs.String("o1", "", "output file for the first part"), fs.String("o2", "", "output file for the second part")}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	point, err := parseOffset(*at, *fps)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid time %q : %v\n", *at, err)
+		return 2
+	}
+	if *outfiles[0] == "" || *outfiles[1] == "" {
+		fmt.Fprintln(stderr, "Both output files should be provided with -o1 and -o2")
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	first, second, err := SplitSubtitleFile(subfile, point)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	for i, part := range []SubtitleFile{first, second} {
+		if code := writeSubtitleFile(part, *outfiles[i], stdout, stderr); code != 0 {
+			return code
+		}
+	}
+	return 0
+}
+
+func runJoin(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("join", stderr)
+	lengthList := fs.String("lengths", "", "comma-separated lengths of the videos of every part but the last, eg. 52m10.5s; parts without one end with their last subtitle")
+	fps := fs.Float64("fps", 0, "frame rate, required for timecodes")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var lengths []time.Duration
+	if *lengthList != "" {
+		for _, in := range strings.Split(*lengthList, ",") {
+			length, err := parseOffset(in, *fps)
+			if err != nil {
+				fmt.Fprintf(stderr, "Invalid length %q : %v\n", in, err)
+				return 2
+			}
+			lengths = append(lengths, length)
+		}
+	}
+	if fs.NArg() < 2 {
+		fmt.Fprintf(stderr, "Expected at least two input files, got %d arguments\n", fs.NArg())
+		return 2
+	}
+	var parts []SubtitleFile
+	for _, file := range fs.Args() {
+		part, errs := ParseSubtitleFile(file)
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "warning: %v\n", err)
+		}
+		if len(part.Subtitles) == 0 {
+			fmt.Fprintf(stderr, "No subtitles could be read from %v\n", file)
+			return 1
+		}
+		parts = append(parts, part)
+	}
+	subfile, err := JoinSubtitleFiles(parts, lengths)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runRebase(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("rebase", stderr)
+	start := fs.String("programme-start", "", "start-of-programme timecode, eg. 10:00:00:00")
+	fps := fs.Float64("fps", 0, "frame rate of the programme")
+	remove := fs.Bool("remove", false, "convert from programme time back to media time")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	tc, err := ParseTimecode(*start, *fps)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid programme start %q : %v\n", *start, err)
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	if *remove {
+		subfile, err = FromProgrammeTime(subfile, tc)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	} else {
+		subfile = ToProgrammeTime(subfile, tc)
+	}
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runTimecodes(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("timecodes", stderr)
+	fps := fs.Float64("fps", 25, "frame rate of the video")
+	dropFrame := fs.Bool("df", false, "use drop-frame timecodes")
+	start := fs.String("programme-start", "", "start-of-programme timecode added to every subtitle")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	var offset Timecode
+	if *start != "" {
+		var err error
+		if offset, err = ParseTimecode(*start, *fps); err != nil {
+			fmt.Fprintf(stderr, "Invalid programme start %q : %v\n", *start, err)
+			return 2
+		}
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	for _, sub := range subfile.Subtitles {
+		from, err := TimecodeFromDuration(sub.Start+offset.Duration(), *fps, *dropFrame)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		to, err := TimecodeFromDuration(sub.End+offset.Duration(), *fps, *dropFrame)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%d\t%v\t%v\n", sub.Index, from, to)
+	}
+	return 0
+}
+
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("lint", stderr)
+	config := fs.String("config", "", "JSON linter configuration file")
+	fix := fs.Bool("fix", false, "apply the available fixes, writing the result to -o")
+	outfile := fs.String("o", "", "output file for the fixed subtitles")
+	listRules := fs.Bool("rules", false, "list the available rules and exit")
+	sdh := fs.Bool("sdh", false, "also check the conventions of subtitles for the deaf and hard-of-hearing")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	rules := DefaultLintRules()
+	if *sdh {
+		rules = append(rules, SDHLintRules()...)
+	}
+	if *listRules {
+		for _, rule := range rules {
+			fmt.Fprintf(stdout, "%-20s %-8v %s\n", rule.Name, rule.Severity, rule.Description)
+		}
+		return 0
+	}
+	if *fix && *outfile == "" {
+		fmt.Fprintln(stderr, "An output file is required to apply fixes, use -o")
+		return 2
+	}
+	cfg := DefaultLintConfig()
+	if *config != "" {
+		var err error
+		if cfg, err = ParseLintConfigFile(*config); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+
+	if *fix {
+		var applied []string
+		subfile, applied = LintFix(subfile, rules, cfg)
+		for _, name := range applied {
+			fmt.Fprintf(stdout, "%v: applied fixes for [%v]\n", fs.Arg(0), name)
+		}
+		if code := writeSubtitleFile(subfile, *outfile, stdout, stderr); code != 0 {
+			return code
+		}
+	}
+	issues := Lint(subfile, rules, cfg)
+	for _, issue := range issues {
+		fmt.Fprintf(stdout, "%v: %v\n", fs.Arg(0), issue)
+	}
+	if HasErrors(issues) {
+		return 1
+	}
+	return 0
+}
+
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("validate", stderr)
+	name := fs.String("profile", "adult", "built-in profile name, or a JSON profile file")
+	listProfiles := fs.Bool("profiles", false, "list the built-in profiles and exit")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *listProfiles {
+		profiles := BuiltinProfiles()
+		for _, name := range profileNames() {
+			fmt.Fprintf(stdout, "%-10s %s\n", name, profiles[name].Description)
+		}
+		return 0
+	}
+	profile, err := LookupProfile(*name)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	report := ValidateProfile(subfile, profile)
+	report.Write(stdout)
+	if !report.Compliant() {
+		return 1
+	}
+	return 0
+}
+
+func runMerge(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("merge", stderr)
+	var opts ThreeWayOptions
+	fs.BoolVar(&opts.ConflictMarkers, "markers", false, "mark conflicts in the text of the subtitles, instead of keeping ours")
+	conflictsFile := fs.String("conflicts", "", "file to write the conflicts to, as JSON")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 3 {
+		fmt.Fprintf(stderr, "Expected the base, our and their files, got %d arguments\n", fs.NArg())
+		return 2
+	}
+	var files [3]SubtitleFile
+	for i, file := range fs.Args() {
+		subfile, errs := ParseSubtitleFile(file)
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "warning: %v\n", err)
+		}
+		if len(subfile.Subtitles) == 0 {
+			fmt.Fprintf(stderr, "No subtitles could be read from %v\n", file)
+			return 2
+		}
+		files[i] = subfile
+	}
+	subfile, conflicts := MergeThreeWay(files[0], files[1], files[2], opts)
+	for _, conflict := range conflicts {
+		fmt.Fprintln(stderr, conflict)
+	}
+	if *conflictsFile != "" {
+		out, err := ConflictsJSON(conflicts)
+		if err == nil {
+			err = ioutil.WriteFile(*conflictsFile, out, 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "Could not write conflicts to %v\n", *conflictsFile)
+			return 2
+		}
+	}
+	if code := writeSubtitleFile(subfile, *outfile, stdout, stderr); code != 0 {
+		return code
+	}
+	if len(conflicts) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runPatch(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("patch", stderr)
+	patchFile := fs.String("p", "", "patch file, as written by diff -format patch")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *patchFile == "" {
+		fmt.Fprintln(stderr, "A patch file is required, use -p")
+		return 2
+	}
+	patch, err := ParsePatchFile(*patchFile)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, err = ApplyPatch(subfile, patch)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "%d operations applied\n", len(patch))
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runPosition(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("position", stderr)
+	at := fs.String("at", "", "where to place the subtitles, eg. top, bottom, middle or top-left")
+	from := fs.String("from", "", "only move subtitles shown after this time, eg. 1m30s")
+	to := fs.String("to", "", "only move subtitles shown before this time")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	alignment, err := ParseAlignment(*at)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var bounds [2]time.Duration
+	for i, in := range []string{*from, *to} {
+		if in == "" {
+			continue
+		}
+		if bounds[i], err = StrToDuration(in); err != nil {
+			fmt.Fprintf(stderr, "Invalid time %q : %v\n", in, err)
+			return 2
+		}
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, moved := MoveSubtitles(subfile, bounds[0], bounds[1], Position{Alignment: alignment})
+	fmt.Fprintf(stderr, "%d subtitles moved\n", len(moved))
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runReflow(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("reflow", stderr)
+	lines := fs.Int("lines", 2, "maximum number of lines per subtitle")
+	width := fs.Int("width", 42, "maximum number of characters per line")
+	balanced := fs.Bool("balanced", false, "balance the line lengths, instead of keeping the bottom line longer")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts := ReflowOptions{MaxLines: *lines, MaxLineLength: *width}
+	if *balanced {
+		opts.Shape = Balanced
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, errs := ReflowSubtitleFile(subfile, opts)
+	for _, err := range errs {
+		fmt.Fprintf(stderr, "warning: %v\n", err)
+	}
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runSanitize(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("sanitize", stderr)
+	formatName := fs.String("format", "", "format to sanitise for, srt, vtt or ass, by default that of the output file")
+	allow := fs.String("allow", "", "comma-separated formatting to keep, out of b,i,u,s,color,align,voice,class,ruby")
+	var opts SanitizeOptions
+	fs.BoolVar(&opts.StripFormatting, "strip", false, "remove all formatting")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts.Format = formatForFile(*outfile)
+	if *formatName != "" {
+		format, err := ParseTextFormat(*formatName)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		opts.Format = format
+	}
+	if *allow != "" {
+		opts.Allow = strings.Split(*allow, ",")
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, changed, err := SanitizeSubtitleFile(subfile, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	fmt.Fprintf(stderr, "%d subtitles changed\n", len(changed))
+	return writeSubtitles(subfile, *outfile, opts.Format, stdout, stderr)
+}
+
+func runReindex(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("reindex", stderr)
+	var opts ReindexOptions
+	fs.BoolVar(&opts.RemoveDuplicates, "dedupe", false, "remove subtitles with the same timing and text as an earlier one")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, moved, duplicates := ReindexSubtitleFile(subfile, opts)
+	for _, change := range moved {
+		fmt.Fprintln(stderr, change)
+	}
+	for _, duplicate := range duplicates {
+		fmt.Fprintln(stderr, duplicate)
+	}
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runReplace(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("replace", stderr)
+	find := fs.String("find", "", "text to search for, or a regular expression with -regexp")
+	with := fs.String("with", "", "replacement text, which can refer to capture groups as $1")
+	var opts ReplaceOptions
+	fs.BoolVar(&opts.Regexp, "regexp", false, "treat the search term as a regular expression")
+	fs.BoolVar(&opts.IgnoreCase, "i", false, "ignore case")
+	fs.BoolVar(&opts.WholeWord, "w", false, "only match whole words")
+	fs.IntVar(&opts.FromIndex, "from-index", 0, "first subtitle index to change")
+	fs.IntVar(&opts.ToIndex, "to-index", 0, "last subtitle index to change")
+	from := fs.String("from", "", "only change subtitles starting at or after this time, eg. 1m30s")
+	to := fs.String("to", "", "only change subtitles starting at or before this time")
+	fs.BoolVar(&opts.DryRun, "n", false, "print what would change, without writing any subtitles")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	for _, bound := range []struct {
+		in  string
+		out *time.Duration
+	}{{*from, &opts.From}, {*to, &opts.To}} {
+		if bound.in == "" {
+			continue
+		}
+		d, err := StrToDuration(bound.in)
+		if err != nil {
+			fmt.Fprintf(stderr, "Invalid time %q : %v\n", bound.in, err)
+			return 2
+		}
+		*bound.out = d
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, report, err := ReplaceInSubtitleFile(subfile, *find, *with, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	if opts.DryRun {
+		for _, r := range report {
+			fmt.Fprintln(stdout, r)
+		}
+		return 0
+	}
+	total := 0
+	for _, r := range report {
+		total += r.Count
+	}
+	fmt.Fprintf(stderr, "%d replacements in %d subtitles\n", total, len(report))
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runSearch(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("search", stderr)
+	pattern := fs.String("e", "", "regular expression to search for")
+	var opts SearchOptions
+	fs.BoolVar(&opts.IgnoreCase, "i", false, "ignore case")
+	fs.BoolVar(&opts.IgnoreMarkup, "plain", false, "search the text without its formatting tags")
+	fs.IntVar(&opts.Context, "C", 0, "number of subtitles to print around each match")
+	var bounds [4]string
+	fs.StringVar(&bounds[0], "from", "", "only search subtitles starting at or after this time, eg. 1m30s")
+	fs.StringVar(&bounds[1], "to", "", "only search subtitles starting at or before this time")
+	fs.StringVar(&bounds[2], "min-duration", "", "only search subtitles shown for at least this long")
+	fs.StringVar(&bounds[3], "max-duration", "", "only search subtitles shown for at most this long")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	for i, out := range []*time.Duration{&opts.From, &opts.To, &opts.MinDuration, &opts.MaxDuration} {
+		if bounds[i] == "" {
+			continue
+		}
+		d, err := StrToDuration(bounds[i])
+		if err != nil {
+			fmt.Fprintf(stderr, "Invalid time %q : %v\n", bounds[i], err)
+			return 2
+		}
+		*out = d
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintf(stderr, "Expected a single input file or directory, got %d arguments\n", fs.NArg())
+		return 2
+	}
+
+	var results []SearchResult
+	if info, err := os.Stat(fs.Arg(0)); err == nil && info.IsDir() {
+		var errs []error
+		results, errs = SearchDirectory(fs.Arg(0), *pattern, opts)
+		for _, err := range errs {
+			fmt.Fprintf(stderr, "warning: %v\n", err)
+		}
+	} else {
+		subfile, ok := loadSubtitleFile(fs, stderr)
+		if !ok {
+			return 1
+		}
+		if results, err = SearchSubtitles(subfile, *pattern, opts); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+	}
+	for i, result := range results {
+		if i > 0 && opts.Context > 0 {
+			fmt.Fprintln(stdout, "--")
+		}
+		fmt.Fprintln(stdout, result)
+	}
+	if len(results) == 0 {
+		return 1
+	}
+	return 0
+}
+
+func runStripSDH(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("strip-sdh", stderr)
+	lang := fs.String("lang", "en", "language of the subtitles, one of "+strings.Join(sdhLanguageNames(), ", "))
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	opts, ok := SDHLanguages()[*lang]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown language %q\n", *lang)
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	subfile, report, err := RemoveSDH(subfile, opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	for _, r := range report {
+		fmt.Fprintln(stderr, r)
+	}
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+func runSound(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("sound", stderr)
+	from := fs.String("from", "", "start time, eg. 1m30.5s")
+	to := fs.String("to", "", "end time")
+	text := fs.String("text", "", "description of the sound, eg. \"door slams\"")
+	parens := fs.Bool("parens", false, "use parentheses instead of square brackets")
+	lower := fs.Bool("lower", false, "write the description in lowercase instead of capitals")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	start, err := StrToDuration(*from)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid time %q : %v\n", *from, err)
+		return 2
+	}
+	end, err := StrToDuration(*to)
+	if err != nil {
+		fmt.Fprintf(stderr, "Invalid time %q : %v\n", *to, err)
+		return 2
+	}
+	style := DefaultSoundCueStyle
+	if *parens {
+		style.Open, style.Close = "(", ")"
+	}
+	style.Uppercase = !*lower
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	if subfile, err = InsertSoundCue(subfile, start, end, *text, style); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return writeSubtitleFile(subfile, *outfile, stdout, stderr)
+}
+
+// speakerFlags collects the repeated -set flags of the speakers command.
+type speakerFlags map[int][]string
+
+func (f speakerFlags) String() string {
+	return fmt.Sprint(map[int][]string(f))
+}
+
+func (f speakerFlags) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("expected INDEX=NAME, got %q", value)
+	}
+	index, err := strconv.Atoi(kv[0])
+	if err != nil {
+		return fmt.Errorf("invalid subtitle index %q", kv[0])
+	}
+	f[index] = strings.Split(kv[1], ",")
+	return nil
+}
+
+func runSpeakers(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("speakers", stderr)
+	speakers := speakerFlags{}
+	fs.Var(speakers, "set", "speakers of a subtitle, as INDEX=NAME or INDEX=NAME,NAME for dialogue, repeated")
+	dashes := fs.Bool("dashes", false, "use dialogue dashes instead of speaker labels")
+	outfile := fs.String("o", "", "output file, instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	subfile, ok := loadSubtitleFile(fs, stderr)
+	if !ok {
+		return 1
+	}
+	// The subtitles are set in order, so that errors are reported consistently
+	var indices []int
+	for index := range speakers {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	for _, index := range indices {
+		var err error
+		if subfile, err = SetSpeakers(subfile, index, speakers[index]...); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	style := SpeakerLabels
+	if *dashes {
+		style = DialogueDashes
+	}
+	return writeSubtitleFile(RenderSpeakers(subfile, style), *outfile, stdout, stderr)
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	type testpair struct {
+		args           []string
+		expectedCode   int
+		expectedStdout string
+		expectedStderr string
+	}
+
+	var tests = []testpair{
+		{
+			[]string{},
+			2,
+			"",
+			"Usage: gophersub COMMAND [FLAGS] FILE",
+		},
+		{
+			[]string{"transmogrify", "samples/sample.srt"},
+			2,
+			"",
+			`Unknown command "transmogrify"`,
+		},
+		{
+			[]string{"shift", "-by", "2s", "samples/sample.srt"},
+			0,
+			"1\n00:00:03,602 --> 00:00:05,314\nΈχουμε όλοι υποφέρει.\n\n2\n00:00:06,536 --> 00:00:09,379\n",
+			"",
+		},
+		{
+			[]string{"shift", "-by", "-00:00:01:15", "-fps", "25", "samples/sample.srt"},
+			0,
+			"1\n00:00:00,002 --> 00:00:01,714\n",
+			"",
+		},
+		{
+			[]string{"shift", "-by", "00:00:01:15", "samples/sample.srt"},
+			2,
+			"",
+			`Invalid offset "00:00:01:15" : The frame rate should be a positive number`,
+		},
+		{
+			[]string{"shift", "-by", "1s", "wrongfilename"},
+			1,
+			"",
+			"No subtitles could be read from wrongfilename",
+		},
+		{
+			[]string{"rebase", "-programme-start", "10:00:00:00", "-fps", "25", "samples/sample.srt"},
+			0,
+			"1\n10:00:01,602 --> 10:00:03,314\n",
+			"",
+		},
+		{
+			[]string{"rebase", "-programme-start", "10:00:00:00", "-fps", "25", "-remove", "samples/sample.srt"},
+			1,
+			"",
+			"Subtitle 1 starts before the programme start timecode 10:00:00:00",
+		},
+		{
+			[]string{"timecodes", "-fps", "25", "-programme-start", "10:00:00:00", "samples/sample.srt"},
+			0,
+			"1\t10:00:01:15\t10:00:03:08\n2\t10:00:04:13\t10:00:07:09\n",
+			"",
+		},
+		{
+			[]string{"reflow", "-width", "24", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\nΈχουμε όλοι υποφέρει.\n\n2\n00:00:04,536 --> 00:00:07,379\nΈχουμε χάσει\nαγαπημένους μας.\n",
+			"warning: Subtitle 3 : Text does not fit in 2 lines of 24 characters",
+		},
+		{
+			[]string{"replace", "-find", "Έχουμε", "-with", "Είχαμε", "-n", "samples/sample.srt"},
+			0,
+			"Subtitle 1 : 1 replacement\n- Έχουμε όλοι υποφέρει.\n+ Είχαμε όλοι υποφέρει.\nSubtitle 2 : 1 replacement\n",
+			"",
+		},
+		{
+			[]string{"replace", "-find", "Έχουμε", "-with", "Είχαμε", "-from", "3s", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\nΈχουμε όλοι υποφέρει.\n\n2\n00:00:04,536 --> 00:00:07,379\nΕίχαμε χάσει αγαπημένους μας.\n",
+			"1 replacements in 1 subtitles",
+		},
+		{
+			[]string{"search", "-e", "νεκρ", "-C", "1", "samples/sample.srt"},
+			0,
+			"2-00:00:04,536 --> 00:00:07,379- Έχουμε χάσει αγαπημένους μας.\n3:00:00:10,088 --> 00:00:14,500: Αυτό δεν αφορά τους Οίκους των ευγενών, | αλλά τους ζωντανούς και τους νεκρούς.\n4-",
+			"",
+		},
+		{
+			[]string{"strip-sdh", "samples/sample_sdh.srt"},
+			0,
+			"1\n00:00:03,000 --> 00:00:05,000\nWinter is coming.\n\n2\n00:00:06,000 --> 00:00:08,000\n- Who is there?\n- Only me.\n",
+			"Subtitle 1 : removed [THUNDER RUMBLING] (dropped)\nSubtitle 2 : removed JON:\nSubtitle 3 : removed (gasps), NED:\n",
+		},
+		{
+			[]string{"strip-sdh", "-lang", "xx", "samples/sample_sdh.srt"},
+			2,
+			"",
+			`Unknown language "xx"`,
+		},
+		{
+			[]string{"sanitize", "-format", "vtt", "samples/sample_lint.srt"},
+			0,
+			"WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000\n<i>Fine line</i>\n\n",
+			"1 subtitles changed",
+		},
+		{
+			[]string{"sanitize", "-allow", "i,blink", "samples/sample_lint.srt"},
+			2,
+			"",
+			"Unknown formatting :`blink`",
+		},
+		{
+			[]string{"position", "-at", "top", "-from", "3s", "-to", "5s", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\n{\\an8}Έχουμε όλοι υποφέρει.\n\n2\n00:00:04,536 --> 00:00:07,379\n{\\an8}Έχουμε",
+			"2 subtitles moved",
+		},
+		{
+			[]string{"position", "-at", "sideways", "samples/sample.srt"},
+			2,
+			"",
+			"Unknown alignment :`sideways`",
+		},
+		{
+			[]string{"bilingual", "-with", "samples/sample_en.srt", "-at", "above", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\nWe have all suffered.\nΈχουμε όλοι υποφέρει.\n\n",
+			"",
+		},
+		{
+			[]string{"bilingual", "-at", "sideways", "samples/sample.srt"},
+			2,
+			"",
+			`Unknown placement "sideways"`,
+		},
+		{
+			[]string{"join", "-lengths", "00:00:20:00", "-fps", "25", "samples/sample.srt", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\n",
+			"",
+		},
+		{
+			[]string{"join", "samples/sample.srt", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\n",
+			"",
+		},
+		{
+			[]string{"join", "-lengths", "20s", "samples/sample.srt"},
+			2,
+			"",
+			"Expected at least two input files, got 1 arguments",
+		},
+		{
+			[]string{"search", "-e", "νεκρ", "samples/sample.srt", "samples/sample_en.srt"},
+			2,
+			"",
+			"Expected a single input file or directory, got 2 arguments",
+		},
+		{
+			[]string{"split", "-at", "5s", "samples/sample.srt"},
+			2,
+			"",
+			"Both output files should be provided with -o1 and -o2",
+		},
+		{
+			[]string{"reindex", "-dedupe", "samples/sample_wrong_indices.srt"},
+			0,
+			"1\n",
+			"",
+		},
+		{
+			[]string{"diff", "samples/sample.srt", "samples/sample.srt"},
+			0,
+			"",
+			"",
+		},
+		{
+			[]string{"diff", "samples/sample.srt", "samples/sample_en.srt"},
+			1,
+			"@@ 1 -> 1 retimed+reworded -102ms @@\n-00:00:01,602 --> 00:00:03,314\n+00:00:01,500 --> 00:00:03,400\n-Έχουμε όλοι υποφέρει.\n+We have all suffered.\n",
+			"",
+		},
+		{
+			[]string{"diff", "-format", "patch", "samples/sample.srt", "samples/sample_en.srt"},
+			1,
+			"[\n  {\n    \"op\": \"text\",\n    \"from\": 1,\n    \"to\": 1,\n",
+			"",
+		},
+		{
+			[]string{"patch", "-p", "samples/sample_patch.json", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\nΈχουμε όλοι υποφέρει.\n\n2\n00:00:04,536 --> 00:00:07,379\nΈχουμε χάσει τους αγαπημένους μας.\n\n3\n00:00:11,288 --> 00:00:15,700\n",
+			"2 operations applied",
+		},
+		{
+			[]string{"patch", "-p", "samples/sample_patch.json", "samples/sample_en.srt"},
+			1,
+			"",
+			"expected subtitle 2 to read",
+		},
+		{
+			[]string{"merge", "samples/sample.srt", "samples/sample.srt", "samples/sample_en.srt"},
+			0,
+			"1\n00:00:01,500 --> 00:00:03,400\nWe have all suffered.\n",
+			"",
+		},
+		{
+			[]string{"merge", "-markers", "samples/sample.srt", "samples/sample_en.srt", "samples/sample_sdh.srt"},
+			1,
+			"1\n00:00:01,000 --> 00:00:02,000\n[THUNDER RUMBLING]\n\n2\n00:00:01,500 --> 00:00:03,400\n<<<<<<< ours (00:00:01,500 --> 00:00:03,400)\nWe have all suffered.\n=======\n>>>>>>> theirs (removed)\n",
+			"Subtitle 2 : conflicting changes to its timing",
+		},
+		{
+			[]string{"sanitize", "-format", "vtt", "samples/sample_tags.srt"},
+			0,
+			"WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000 line:0\nTom &amp; Jerry\n\n2\n00:00:04.000 --> 00:00:06.000\n<c.red>Red</c> wedding\n",
+			"1 subtitles changed",
+		},
+		{
+			[]string{"sound", "-from", "3.5s", "-to", "4.2s", "-text", "door slams", "samples/sample.srt"},
+			0,
+			"1\n00:00:01,602 --> 00:00:03,314\nΈχουμε όλοι υποφέρει.\n\n2\n00:00:03,500 --> 00:00:04,200\n[DOOR SLAMS]\n\n3\n00:00:04,536",
+			"",
+		},
+		{
+			[]string{"speakers", "-set", "2=Jon", "-set", "3=Arya,Ned", "-dashes", "samples/sample_sdh.srt"},
+			0,
+			"1\n00:00:01,000 --> 00:00:02,000\n[THUNDER RUMBLING]\n\n2\n00:00:03,000 --> 00:00:05,000\nJON: Winter is coming.\n",
+			"",
+		},
+		{
+			[]string{"speakers", "-set", "two=Jon", "samples/sample_sdh.srt"},
+			2,
+			"",
+			`invalid subtitle index "two"`,
+		},
+		{
+			[]string{"speakers", "-set", "9=Jon", "-set", "7=Arya", "samples/sample_sdh.srt"},
+			1,
+			"",
+			"No subtitle with index 7",
+		},
+		{
+			[]string{"search", "-e", "Tyrion", "samples/sample.srt"},
+			1,
+			"",
+			"",
+		},
+	}
+
+	for _, pair := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(pair.args, &stdout, &stderr)
+		if code != pair.expectedCode {
+			t.Errorf("Testing runCLI with %v. Expected exit code %v but got %v instead (%v)", pair.args, pair.expectedCode, code, stderr.String())
+		}
+		if !strings.HasPrefix(stdout.String(), pair.expectedStdout) {
+			t.Errorf("Testing runCLI with %v. Expected output to start with %q but got %q instead", pair.args, pair.expectedStdout, stdout.String())
+		}
+		if !strings.Contains(stderr.String(), pair.expectedStderr) {
+			t.Errorf("Testing runCLI with %v. Expected errors to contain %q but got %q instead", pair.args, pair.expectedStderr, stderr.String())
+		}
+	}
+}
+
+func TestSanitizeRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outfile := filepath.Join(dir, "sanitized.vtt")
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI([]string{"sanitize", "-o", outfile, "samples/sample_tags.srt"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Testing sanitize to a WebVTT file. Got exit code %v (%v)", code, stderr.String())
+	}
+	res, errs := ParseSubtitleFile(outfile)
+	expected := []string{`{\an8}Tom & Jerry`, `<font color="red">Red</font> wedding`}
+	if len(errs) != 0 || len(res.Subtitles) != 2 {
+		t.Fatalf("Testing sanitize to a WebVTT file. Could not read it back, got %v and %v", res, errs)
+	}
+	for i, sub := range res.Subtitles {
+		if sub.Content != expected[i] {
+			t.Errorf("Testing sanitize to a WebVTT file. Expected %q but read back %q", expected[i], sub.Content)
+		}
+	}
+}
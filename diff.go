@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeKind describes how a subtitle changed between two files. A
+// subtitle can be both Retimed and Reworded; a zero ChangeKind means
+// it's unchanged.
+type ChangeKind int
+
+const (
+	Added ChangeKind = 1 << iota
+	Removed
+	Retimed
+	Reworded
+)
+
+func (k ChangeKind) String() string {
+	var names []string
+	for _, kind := range []struct {
+		kind ChangeKind
+		name string
+	}{{Added, "added"}, {Removed, "removed"}, {Retimed, "retimed"}, {Reworded, "reworded"}} {
+		if k&kind.kind != 0 {
+			names = append(names, kind.name)
+		}
+	}
+	if len(names) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(names, "+")
+}
+
+// CueChange pairs a subtitle of the old file with one of the new file. Old
+// is the zero Subtitle for added subtitles, and New for removed ones.
+// Offset is how much the subtitle was moved, going by its Start, and
+// Similarity how close its text is, from 0 for nothing in common to 1.
+type CueChange struct {
+	Kind       ChangeKind
+	Old        Subtitle
+	New        Subtitle
+	Offset     time.Duration
+	Similarity float64
+}
+
+// Changed reports whether the subtitle was added, removed, retimed or
+// reworded.
+func (c CueChange) Changed() bool {
+	return c.Kind != 0
+}
+
+// similarity compares the plain text of two subtitles, as the share of
+// characters that don't need editing to turn one into the other.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(StripMarkup(a)), []rune(StripMarkup(b))
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	// Levenshtein distance, keeping a single row of the table
+	row := make([]int, len(rb)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		diag := row[0]
+		row[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			next := diag + cost
+			if row[j]+1 < next {
+				next = row[j] + 1
+			}
+			if row[j-1]+1 < next {
+				next = row[j-1] + 1
+			}
+			diag, row[j] = row[j], next
+		}
+	}
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	return 1 - float64(row[len(rb)])/float64(longest)
+}
+
+// overlapRatio returns the share of the shorter subtitle's time that both
+// subtitles are on screen together.
+func overlapRatio(a, b Subtitle) float64 {
+	shorter := a.End - a.Start
+	if d := b.End - b.Start; d < shorter {
+		shorter = d
+	}
+	if shorter <= 0 {
+		if a.Start == b.Start {
+			return 1
+		}
+		return 0
+	}
+	return float64(overlap(a, b)) / float64(shorter)
+}
+
+// commonCues returns the pairs of positions of subtitles with the same
+// text in both lists, as their longest common subsequence.
+func commonCues(a, b []Subtitle) [][2]int {
+	var res [][2]int
+	// Identical subtitles at either end need no table
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix].Content == b[prefix].Content {
+		res = append(res, [2]int{prefix, prefix})
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix].Content == b[len(b)-1-suffix].Content {
+		suffix++
+	}
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// lcs[i][j] is the length of the common subsequence of ma[i:] and mb[j:]
+	lcs := make([][]int32, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			switch {
+			case ma[i].Content == mb[j].Content:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(ma) && j < len(mb); {
+		switch {
+		case ma[i].Content == mb[j].Content:
+			res = append(res, [2]int{prefix + i, prefix + j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	for k := suffix; k > 0; k-- {
+		res = append(res, [2]int{len(a) - k, len(b) - k})
+	}
+	return res
+}
+
+// DiffSubtitleFiles compares two versions of a subtitle file, eg. before and
+// after a translator or a syncer worked on it, regardless of how their
+// subtitles are numbered. Subtitles with the same text are paired first, in
+// order, and count as retimed if their timing differs. Between those, the
+// remaining subtitles are paired if they're shown at the same time or their
+// text is similar, and count as reworded. The rest were added or removed.
+// Every subtitle is reported, ordered by the time it's shown.
+func DiffSubtitleFiles(before, after SubtitleFile) []CueChange {
+	a, _, _ := ReindexSubtitleFile(before, ReindexOptions{})
+	b, _, _ := ReindexSubtitleFile(after, ReindexOptions{})
+	var res []CueChange
+	pair := func(o, n Subtitle) CueChange {
+		c := CueChange{Old: o, New: n, Offset: n.Start - o.Start, Similarity: 1}
+		if o.Start != n.Start || o.End != n.End {
+			c.Kind |= Retimed
+		}
+		if o.Content != n.Content {
+			c.Kind |= Reworded
+			c.Similarity = similarity(o.Content, n.Content)
+		}
+		return c
+	}
+
+	// align pairs the subtitles between two common ones, by their best score
+	align := func(as, bs []Subtitle) {
+		used := make([]bool, len(bs))
+		for _, o := range as {
+			best, bestScore := -1, 0.0
+			for j, n := range bs {
+				if used[j] {
+					continue
+				}
+				timing, text := overlapRatio(o, n), similarity(o.Content, n.Content)
+				if timing < 0.5 && text < 0.5 {
+					continue
+				}
+				if score := timing + text; score > bestScore {
+					best, bestScore = j, score
+				}
+			}
+			if best < 0 {
+				res = append(res, CueChange{Kind: Removed, Old: o})
+				continue
+			}
+			used[best] = true
+			res = append(res, pair(o, bs[best]))
+		}
+		for j, n := range bs {
+			if !used[j] {
+				res = append(res, CueChange{Kind: Added, New: n})
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for _, common := range commonCues(a.Subtitles, b.Subtitles) {
+		align(a.Subtitles[i:common[0]], b.Subtitles[j:common[1]])
+		res = append(res, pair(a.Subtitles[common[0]], b.Subtitles[common[1]]))
+		i, j = common[0]+1, common[1]+1
+	}
+	align(a.Subtitles[i:], b.Subtitles[j:])
+
+	sort.SliceStable(res, func(x, y int) bool { return res[x].at() < res[y].at() })
+	return res
+}
+
+// at returns when the change takes place, for ordering.
+func (c CueChange) at() time.Duration {
+	if c.Kind&Added != 0 {
+		return c.New.Start
+	}
+	return c.Old.Start
+}
+
+// formatOffset formats an offset with its sign, eg. +1.2s
+func formatOffset(d time.Duration) string {
+	if d >= 0 {
+		return "+" + d.String()
+	}
+	return d.String()
+}
+
+// timingLine formats the timing of a subtitle as in SRT files.
+func timingLine(sub Subtitle) string {
+	return DurationToTimestampSRT(sub.Start) + " --> " + DurationToTimestampSRT(sub.End)
+}
+
+// String returns the change as a hunk of a unified diff, eg.
+//
+//	@@ 12 -> 13 retimed +1.2s @@
+//	-00:01:02,000 --> 00:01:04,000
+//	+00:01:03,200 --> 00:01:05,200
+//	 Winter is coming.
+func (c CueChange) String() string {
+	var lines []string
+	prefixed := func(prefix, text string) {
+		for _, line := range strings.Split(text, "\n") {
+			lines = append(lines, prefix+line)
+		}
+	}
+	switch {
+	case c.Kind&Added != 0:
+		lines = append(lines, "@@ -> "+strconv.Itoa(c.New.Index)+" added @@")
+		prefixed("+", timingLine(c.New)+"\n"+c.New.Content)
+	case c.Kind&Removed != 0:
+		lines = append(lines, "@@ "+strconv.Itoa(c.Old.Index)+" -> removed @@")
+		prefixed("-", timingLine(c.Old)+"\n"+c.Old.Content)
+	default:
+		header := "@@ " + strconv.Itoa(c.Old.Index) + " -> " + strconv.Itoa(c.New.Index) + " " + c.Kind.String()
+		if c.Kind&Retimed != 0 {
+			header += " " + formatOffset(c.Offset)
+		}
+		lines = append(lines, header+" @@")
+		if c.Kind&Retimed != 0 {
+			prefixed("-", timingLine(c.Old))
+			prefixed("+", timingLine(c.New))
+		} else {
+			prefixed(" ", timingLine(c.Old))
+		}
+		if c.Kind&Reworded != 0 {
+			prefixed("-", c.Old.Content)
+			prefixed("+", c.New.Content)
+		} else {
+			prefixed(" ", c.Old.Content)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatUnifiedDiff returns the changes as a unified diff, leaving out
+// unchanged subtitles.
+func FormatUnifiedDiff(changes []CueChange) string {
+	var hunks []string
+	for _, c := range changes {
+		if c.Changed() {
+			hunks = append(hunks, c.String())
+		}
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+	return strings.Join(hunks, "\n") + "\n"
+}
+
+// padColumn cuts or pads a line to the width of a column.
+func padColumn(line string, width int) string {
+	if DisplayWidth(line) <= width {
+		return line + strings.Repeat(" ", width-DisplayWidth(line))
+	}
+	var b strings.Builder
+	for _, g := range Graphemes(line) {
+		if DisplayWidth(b.String()+g) > width {
+			break
+		}
+		b.WriteString(g)
+	}
+	return b.String() + strings.Repeat(" ", width-DisplayWidth(b.String()))
+}
+
+// FormatSideBySide returns the changes in two columns of the given width,
+// the old file on the left and the new one on the right, marking changed
+// subtitles with "|", removed ones with "<" and added ones with ">".
+// Unchanged subtitles are included, to help follow along.
+func FormatSideBySide(changes []CueChange, width int) string {
+	var b strings.Builder
+	cue := func(sub Subtitle, present bool) []string {
+		if !present {
+			return nil
+		}
+		return append([]string{strconv.Itoa(sub.Index) + " " + timingLine(sub)}, strings.Split(sub.Content, "\n")...)
+	}
+	for _, c := range changes {
+		marker := " "
+		switch {
+		case c.Kind&Added != 0:
+			marker = ">"
+		case c.Kind&Removed != 0:
+			marker = "<"
+		case c.Changed():
+			marker = "|"
+		}
+		left, right := cue(c.Old, c.Kind&Added == 0), cue(c.New, c.Kind&Removed == 0)
+		for i := 0; i < len(left) || i < len(right); i++ {
+			var l, r string
+			if i < len(left) {
+				l = left[i]
+			}
+			if i < len(right) {
+				r = right[i]
+			}
+			b.WriteString(strings.TrimRight(padColumn(l, width)+" "+marker+" "+r, " ") + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// jsonCue is the JSON form of a subtitle in a diff, with times in milliseconds.
+type jsonCue struct {
+	Index   int    `json:"index"`
+	Start   int64  `json:"start_ms"`
+	End     int64  `json:"end_ms"`
+	Content string `json:"content"`
+}
+
+// jsonChange is the JSON form of a CueChange.
+type jsonChange struct {
+	Kind       string   `json:"kind"`
+	Old        *jsonCue `json:"old,omitempty"`
+	New        *jsonCue `json:"new,omitempty"`
+	Offset     int64    `json:"offset_ms,omitempty"`
+	Similarity float64  `json:"similarity"`
+}
+
+// DiffJSON returns the changes as a JSON array, leaving out unchanged subtitles.
+func DiffJSON(changes []CueChange) ([]byte, error) {
+	res := []jsonChange{}
+	toJSON := func(sub Subtitle) *jsonCue {
+		return &jsonCue{sub.Index, sub.Start.Milliseconds(), sub.End.Milliseconds(), sub.Content}
+	}
+	for _, c := range changes {
+		if !c.Changed() {
+			continue
+		}
+		change := jsonChange{Kind: c.Kind.String(), Offset: c.Offset.Milliseconds(), Similarity: c.Similarity}
+		if c.Kind&Added == 0 {
+			change.Old = toJSON(c.Old)
+		}
+		if c.Kind&Removed == 0 {
+			change.New = toJSON(c.New)
+		}
+		res = append(res, change)
+	}
+	return json.MarshalIndent(res, "", "  ")
+}
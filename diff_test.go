@@ -0,0 +1,128 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var diffTestBefore = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+		{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+		{4, time.Duration(time.Second * 15), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+	},
+	"",
+}
+
+var diffTestAfter = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει τους αγαπημένους μας.`, "", ""},
+		{3, time.Duration(time.Second*15 + time.Millisecond*500), time.Duration(time.Second*16 + time.Millisecond*500), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+		{4, time.Duration(time.Second * 18), time.Duration(time.Second * 20), `Σας προσφέρω την επιλογή.`, "", ""},
+	},
+	"",
+}
+
+func TestDiffSubtitleFiles(t *testing.T) {
+	before, after := diffTestBefore.Subtitles, diffTestAfter.Subtitles
+	expected := []CueChange{
+		{0, before[0], after[0], 0, 1},
+		{Reworded, before[1], after[1], 0, similarity(before[1].Content, after[1].Content)},
+		{Removed, before[2], Subtitle{}, 0, 0},
+		{Retimed, before[3], after[2], 500 * time.Millisecond, 1},
+		{Added, Subtitle{}, after[3], 0, 0},
+	}
+
+	res := DiffSubtitleFiles(diffTestBefore, diffTestAfter)
+	if !cmp.Equal(res, expected) {
+		t.Errorf("Testing DiffSubtitleFiles. Expected %v but got %v instead!", expected, res)
+	}
+	if s := expected[1].Similarity; s < 0.8 || s >= 1 {
+		t.Errorf("Testing DiffSubtitleFiles. Expected a high similarity for a reworded subtitle, got %v", s)
+	}
+
+	// Renumbering alone is not a change
+	shifted := SubtitleFile{append([]Subtitle{{1, 0, time.Second, "New first line", "", ""}}, before...), ""}
+	for _, c := range DiffSubtitleFiles(diffTestBefore, shifted)[1:] {
+		if c.Changed() {
+			t.Errorf("Testing DiffSubtitleFiles. Expected only an added subtitle, got %v", c)
+		}
+	}
+}
+
+func TestDiffFormats(t *testing.T) {
+	changes := DiffSubtitleFiles(diffTestBefore, diffTestAfter)
+
+	unified := FormatUnifiedDiff(changes)
+	expected := `@@ 2 -> 2 reworded @@
+ 00:00:04,000 --> 00:00:07,000
+-Έχουμε χάσει αγαπημένους μας.
++Έχουμε χάσει τους αγαπημένους μας.
+@@ 3 -> removed @@
+-00:00:10,000 --> 00:00:14,000
+-Αυτό δεν αφορά τους Οίκους των ευγενών
+@@ 4 -> 3 retimed +500ms @@
+-00:00:15,000 --> 00:00:16,000
++00:00:15,500 --> 00:00:16,500
+ Κι εγώ σκοπεύω να ζήσω.
+@@ -> 4 added @@
++00:00:18,000 --> 00:00:20,000
++Σας προσφέρω την επιλογή.
+`
+	if unified != expected {
+		t.Errorf("Testing FormatUnifiedDiff. Expected\n%v\nbut got\n%v", expected, unified)
+	}
+
+	side := strings.Split(FormatSideBySide(changes[2:4], 32), "\n")
+	expectedSide := []string{
+		"3 00:00:10,000 --> 00:00:14,000  <",
+		"Αυτό δεν αφορά τους Οίκους των ε <",
+		"",
+		"4 00:00:15,000 --> 00:00:16,000  | 3 00:00:15,500 --> 00:00:16,500",
+		"Κι εγώ σκοπεύω να ζήσω.          | Κι εγώ σκοπεύω να ζήσω.",
+		"",
+		"",
+	}
+	if !cmp.Equal(side, expectedSide) {
+		t.Errorf("Testing FormatSideBySide. Expected\n%v\nbut got\n%v", strings.Join(expectedSide, "\n"), strings.Join(side, "\n"))
+	}
+
+	out, err := DiffJSON(changes[3:])
+	expectedJSON := `[
+  {
+    "kind": "retimed",
+    "old": {
+      "index": 4,
+      "start_ms": 15000,
+      "end_ms": 16000,
+      "content": "Κι εγώ σκοπεύω να ζήσω."
+    },
+    "new": {
+      "index": 3,
+      "start_ms": 15500,
+      "end_ms": 16500,
+      "content": "Κι εγώ σκοπεύω να ζήσω."
+    },
+    "offset_ms": 500,
+    "similarity": 1
+  },
+  {
+    "kind": "added",
+    "new": {
+      "index": 4,
+      "start_ms": 18000,
+      "end_ms": 20000,
+      "content": "Σας προσφέρω την επιλογή."
+    },
+    "similarity": 0
+  }
+]`
+	if err != nil || string(out) != expectedJSON {
+		t.Errorf("Testing DiffJSON. Expected\n%v\nbut got\n%v and %v", expectedJSON, string(out), err)
+	}
+}
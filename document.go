@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// DocumentEvent is sent to the listeners of a Document after every change.
+// Action is "edit", "undo" or "redo", Name is that of the edit, and Changes
+// lists the subtitles that changed, as in DiffSubtitleFiles.
+type DocumentEvent struct {
+	Action  string
+	Name    string
+	Changes []CueChange
+}
+
+// documentEdit is an entry of the undo history, as the subtitle file before
+// and after the edit.
+type documentEdit struct {
+	id     int
+	name   string
+	before SubtitleFile
+	after  SubtitleFile
+}
+
+// Document is a subtitle file being edited, eg. in an editor. Edits are
+// made with the same functions as elsewhere, which return a new subtitle
+// file rather than changing their input, so that every edit can be undone
+// by going back to the file before it, and redone by going forward again.
+type Document struct {
+	file      SubtitleFile
+	undo      []documentEdit
+	redo      []documentEdit
+	group     *documentEdit
+	depth     int
+	nextID    int
+	saved     int
+	listeners []func(DocumentEvent)
+}
+
+// NewDocument returns a document to edit the subtitle file.
+func NewDocument(subfile SubtitleFile) *Document {
+	return &Document{file: subfile}
+}
+
+// OpenDocument reads an SRT file into a document, see ParseSRTFile.
+func OpenDocument(filename string) (*Document, []error) {
+	subfile, errs := ParseSRTFile(filename)
+	return NewDocument(subfile), errs
+}
+
+// File returns the subtitle file as it currently is.
+func (d *Document) File() SubtitleFile {
+	return d.file
+}
+
+// OnChange registers a function that's called after every edit, undo and redo.
+func (d *Document) OnChange(listener func(DocumentEvent)) {
+	d.listeners = append(d.listeners, listener)
+}
+
+// notify sends an event to the listeners, with the changes from before to after.
+func (d *Document) notify(action, name string, before, after SubtitleFile) {
+	if len(d.listeners) == 0 {
+		return
+	}
+	var changes []CueChange
+	for _, c := range DiffSubtitleFiles(before, after) {
+		if c.Changed() {
+			changes = append(changes, c)
+		}
+	}
+	for _, listener := range d.listeners {
+		listener(DocumentEvent{action, name, changes})
+	}
+}
+
+// Apply makes an edit to the document, under a name such as "Shift by
+// +1s" for the history. If the edit returns an error the document is left
+// unchanged. Making an edit clears the edits that could be redone.
+func (d *Document) Apply(name string, edit func(SubtitleFile) (SubtitleFile, error)) error {
+	before := d.file
+	after, err := edit(before)
+	if err != nil {
+		return err
+	}
+	d.file = after
+	d.redo = nil
+	if d.group == nil {
+		d.nextID++
+		d.undo = append(d.undo, documentEdit{d.nextID, name, before, after})
+	}
+	d.notify("edit", name, before, after)
+	return nil
+}
+
+// BeginGroup starts a group of edits that are undone and redone together,
+// under a single name, until the matching EndGroup. Groups can be nested,
+// in which case the outermost one makes up the single edit.
+func (d *Document) BeginGroup(name string) {
+	d.depth++
+	if d.group == nil {
+		d.group = &documentEdit{name: name, before: d.file}
+	}
+}
+
+// EndGroup ends the group of edits started by BeginGroup, adding it to the
+// history if any edits were made in it.
+func (d *Document) EndGroup() error {
+	if d.depth == 0 {
+		return errors.New("There is no group of edits to end")
+	}
+	d.depth--
+	if d.depth > 0 {
+		return nil
+	}
+	group := *d.group
+	d.group = nil
+	if sameFile(group.before, d.file) {
+		return nil
+	}
+	d.nextID++
+	group.id, group.after = d.nextID, d.file
+	d.undo = append(d.undo, group)
+	return nil
+}
+
+// sameFile reports whether two subtitle files are identical.
+func sameFile(a, b SubtitleFile) bool {
+	if a.Headers != b.Headers || len(a.Subtitles) != len(b.Subtitles) {
+		return false
+	}
+	for i := range a.Subtitles {
+		if a.Subtitles[i] != b.Subtitles[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CanUndo reports whether there's an edit to undo, and returns its name.
+func (d *Document) CanUndo() (string, bool) {
+	if len(d.undo) == 0 || d.group != nil {
+		return "", false
+	}
+	return d.undo[len(d.undo)-1].name, true
+}
+
+// CanRedo reports whether there's an edit to redo, and returns its name.
+func (d *Document) CanRedo() (string, bool) {
+	if len(d.redo) == 0 || d.group != nil {
+		return "", false
+	}
+	return d.redo[len(d.redo)-1].name, true
+}
+
+// Undo reverts the last edit, or group of edits.
+func (d *Document) Undo() error {
+	if d.group != nil {
+		return errors.New("Cannot undo while a group of edits is open")
+	}
+	if len(d.undo) == 0 {
+		return errors.New("There is nothing to undo")
+	}
+	edit := d.undo[len(d.undo)-1]
+	d.undo = d.undo[:len(d.undo)-1]
+	d.redo = append(d.redo, edit)
+	d.file = edit.before
+	d.notify("undo", edit.name, edit.after, edit.before)
+	return nil
+}
+
+// Redo makes the last undone edit, or group of edits, again.
+func (d *Document) Redo() error {
+	if d.group != nil {
+		return errors.New("Cannot redo while a group of edits is open")
+	}
+	if len(d.redo) == 0 {
+		return errors.New("There is nothing to redo")
+	}
+	edit := d.redo[len(d.redo)-1]
+	d.redo = d.redo[:len(d.redo)-1]
+	d.undo = append(d.undo, edit)
+	d.file = edit.after
+	d.notify("redo", edit.name, edit.before, edit.after)
+	return nil
+}
+
+// History returns the names of the edits that can be undone, oldest first.
+func (d *Document) History() []string {
+	var res []string
+	for _, edit := range d.undo {
+		res = append(res, edit.name)
+	}
+	return res
+}
+
+// AddSubtitle adds a subtitle to the document, see AddSubtitle.
+func (d *Document) AddSubtitle(start, end, content, metadata, header string) error {
+	return d.Apply("Add subtitle at "+start, func(subfile SubtitleFile) (SubtitleFile, error) {
+		return AddSubtitle(subfile, start, end, content, metadata, header)
+	})
+}
+
+// RemoveSubtitle removes a subtitle from the document, see RemoveSubtitle.
+func (d *Document) RemoveSubtitle(idx int) error {
+	return d.Apply("Remove subtitle "+strconv.Itoa(idx), func(subfile SubtitleFile) (SubtitleFile, error) {
+		return RemoveSubtitle(subfile, idx)
+	})
+}
+
+// Timeshift moves every subtitle of the document by shift.
+func (d *Document) Timeshift(shift time.Duration) error {
+	return d.Apply("Shift by "+formatOffset(shift), func(subfile SubtitleFile) (SubtitleFile, error) {
+		res := TimeshiftSubtitleFile(subfile, shift)
+		res.Headers = subfile.Headers
+		return res, nil
+	})
+}
+
+// SetText replaces the text of a subtitle.
+func (d *Document) SetText(idx int, content string) error {
+	return d.Apply("Edit subtitle "+strconv.Itoa(idx), func(subfile SubtitleFile) (SubtitleFile, error) {
+		if idx <= 0 || idx > len(subfile.Subtitles) {
+			return subfile, errors.New("The index of the subtitle to edit is invalid :" + strconv.Itoa(idx))
+		}
+		res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+		copy(res.Subtitles, subfile.Subtitles)
+		res.Subtitles[idx-1].Content = content
+		return res, nil
+	})
+}
+
+// UpdateSubtitle changes the timing and text of a subtitle, see UpdateSubtitle.
+func (d *Document) UpdateSubtitle(idx int, start, end, content string, opts UpdateOptions) error {
+	return d.Apply("Edit subtitle "+strconv.Itoa(idx), func(subfile SubtitleFile) (SubtitleFile, error) {
+		return UpdateSubtitle(subfile, idx, start, end, content, opts)
+	})
+}
+
+// Replace replaces the text matching a pattern, see ReplaceInSubtitleFile.
+func (d *Document) Replace(pattern, replacement string, opts ReplaceOptions) ([]Replacement, error) {
+	var replaced []Replacement
+	err := d.Apply("Replace "+pattern, func(subfile SubtitleFile) (SubtitleFile, error) {
+		res, r, err := ReplaceInSubtitleFile(subfile, pattern, replacement, opts)
+		replaced = r
+		return res, err
+	})
+	return replaced, err
+}
+
+// Dirty reports whether the document changed since it was last checkpointed.
+func (d *Document) Dirty() bool {
+	current := 0
+	if len(d.undo) > 0 {
+		current = d.undo[len(d.undo)-1].id
+	}
+	return current != d.saved || (d.group != nil && !sameFile(d.group.before, d.file))
+}
+
+// Checkpoint saves the document to a file, in the format of its
+// extension, and marks it as not dirty. The file is written next to its
+// destination first and then renamed, so that it's never left half-written.
+// The history is kept, so edits can still be undone.
+func (d *Document) Checkpoint(filename string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".")
+	if err != nil {
+		return errors.New("Could not open file " + filename + " for writing")
+	}
+	err = WriteSubtitles(tmp, d.file, formatForFile(filename))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return errors.New("Could not write file " + filename)
+	}
+	d.saved = 0
+	if len(d.undo) > 0 {
+		d.saved = d.undo[len(d.undo)-1].id
+	}
+	return nil
+}
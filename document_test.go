@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var documentTestFile = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+		{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+	},
+	"",
+}
+
+func TestDocumentUndoRedo(t *testing.T) {
+	doc := NewDocument(documentTestFile)
+	var events []string
+	doc.OnChange(func(e DocumentEvent) {
+		events = append(events, e.Action+" "+e.Name+" "+strconv.Itoa(len(e.Changes)))
+	})
+
+	if err := doc.SetText(2, `Έχουμε χάσει τους αγαπημένους μας.`); err != nil {
+		t.Fatalf("Testing Document.SetText. Got %v", err)
+	}
+	edited := doc.File()
+	doc.BeginGroup("Shift and trim")
+	doc.Timeshift(time.Second)
+	doc.RemoveSubtitle(3)
+	doc.EndGroup()
+	grouped := doc.File()
+	if len(grouped.Subtitles) != 2 || grouped.Subtitles[0].Start != 2*time.Second {
+		t.Errorf("Testing Document edits. Got %v", grouped)
+	}
+	if !cmp.Equal(doc.History(), []string{"Edit subtitle 2", "Shift and trim"}) {
+		t.Errorf("Testing Document.History. Got %v", doc.History())
+	}
+
+	// The group is undone at once
+	doc.Undo()
+	if !cmp.Equal(doc.File(), edited) {
+		t.Errorf("Testing Document.Undo. Expected %v but got %v instead!", edited, doc.File())
+	}
+	doc.Undo()
+	if !cmp.Equal(doc.File(), documentTestFile) {
+		t.Errorf("Testing Document.Undo. Expected %v but got %v instead!", documentTestFile, doc.File())
+	}
+	if err := doc.Undo(); err == nil || err.Error() != "There is nothing to undo" {
+		t.Errorf("Testing Document.Undo. Expected an error with nothing to undo, got %v", err)
+	}
+	doc.Redo()
+	doc.Redo()
+	if !cmp.Equal(doc.File(), grouped) {
+		t.Errorf("Testing Document.Redo. Expected %v but got %v instead!", grouped, doc.File())
+	}
+
+	// A new edit clears what could be redone
+	doc.Undo()
+	doc.SetText(1, `Όλοι έχουμε υποφέρει.`)
+	if name, ok := doc.CanRedo(); ok {
+		t.Errorf("Testing Document.CanRedo. Expected nothing to redo, got %v", name)
+	}
+
+	// A failed edit leaves the document unchanged
+	before := doc.File()
+	err := doc.RemoveSubtitle(7)
+	if err == nil || !cmp.Equal(doc.File(), before) || len(doc.History()) != 2 {
+		t.Errorf("Testing Document.RemoveSubtitle. Expected an error and no change, got %v and %v", err, doc.File())
+	}
+
+	expectedEvents := []string{
+		"edit Edit subtitle 2 1",
+		"edit Shift by +1s 3",
+		"edit Remove subtitle 3 1",
+		"undo Shift and trim 3",
+		"undo Edit subtitle 2 1",
+		"redo Edit subtitle 2 1",
+		"redo Shift and trim 3",
+		"undo Shift and trim 3",
+		"edit Edit subtitle 1 1",
+	}
+	if !cmp.Equal(events, expectedEvents) {
+		t.Errorf("Testing Document events. Expected %v but got %v instead!", expectedEvents, events)
+	}
+}
+
+func TestDocumentGroups(t *testing.T) {
+	type testpair struct {
+		edits       func(doc *Document) error
+		history     []string
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{func(doc *Document) error {
+			doc.BeginGroup("Outer")
+			doc.BeginGroup("Inner")
+			doc.SetText(1, "One")
+			doc.EndGroup()
+			doc.SetText(2, "Two")
+			return doc.EndGroup()
+		}, []string{"Outer"}, nil},
+		{func(doc *Document) error {
+			doc.BeginGroup("Nothing")
+			return doc.EndGroup()
+		}, nil, nil},
+		{func(doc *Document) error {
+			return doc.EndGroup()
+		}, nil, errors.New("There is no group of edits to end")},
+		{func(doc *Document) error {
+			doc.SetText(1, "One")
+			doc.BeginGroup("Open")
+			return doc.Undo()
+		}, []string{"Edit subtitle 1"}, errors.New("Cannot undo while a group of edits is open")},
+	}
+
+	for _, pair := range tests {
+		doc := NewDocument(documentTestFile)
+		err := pair.edits(doc)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing Document groups. Expected error %v but got %v instead!", pair.expectedErr, err)
+		}
+		if !cmp.Equal(doc.History(), pair.history) {
+			t.Errorf("Testing Document groups. Expected history %v but got %v instead!", pair.history, doc.History())
+		}
+	}
+}
+
+func TestDocumentCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "checkpoint.srt")
+
+	doc := NewDocument(documentTestFile)
+	doc.SetText(1, `Όλοι έχουμε υποφέρει.`)
+	if !doc.Dirty() {
+		t.Errorf("Testing Document.Dirty. Expected an edited document to be dirty")
+	}
+	if err := doc.Checkpoint(filename); err != nil || doc.Dirty() {
+		t.Errorf("Testing Document.Checkpoint. Expected a clean document, got %v", err)
+	}
+	reopened, errs := OpenDocument(filename)
+	if len(errs) != 0 || !cmp.Equal(reopened.File(), doc.File()) {
+		t.Errorf("Testing Document.Checkpoint. Expected %v but got %v and %v instead!", doc.File(), reopened.File(), errs)
+	}
+
+	// Undoing past the checkpoint, and redoing back to it
+	doc.Undo()
+	if !doc.Dirty() {
+		t.Errorf("Testing Document.Dirty. Expected an undone document to be dirty")
+	}
+	doc.Redo()
+	if doc.Dirty() {
+		t.Errorf("Testing Document.Dirty. Expected the checkpointed document to be clean")
+	}
+
+	if err := doc.Checkpoint(filepath.Join(dir, "missing", "checkpoint.srt")); err == nil {
+		t.Errorf("Testing Document.Checkpoint. Expected an error for a missing directory")
+	}
+}
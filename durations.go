@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// DurationOptions configures NormalizeDurations. Zero values disable
+// the matching rule. CharsPerSecond derives a minimum duration from the
+// length of each subtitle's text, for a comfortable reading speed,
+// counting characters as selected by Reading.
+// MinGap is often expressed in frames, eg. FramesToDuration(2, 25).
+type DurationOptions struct {
+	MinDuration    time.Duration
+	MaxDuration    time.Duration
+	MinGap         time.Duration
+	CharsPerSecond float64
+	Reading        ReadingOptions
+}
+
+// DurationAdjustment records a change to the End time of a subtitle.
+type DurationAdjustment struct {
+	Index  int
+	From   time.Duration
+	To     time.Duration
+	Reason string
+}
+
+func (a DurationAdjustment) String() string {
+	return "Subtitle " + strconv.Itoa(a.Index) + " : End moved from " +
+		DurationToTimestampSRT(a.From) + " to " + DurationToTimestampSRT(a.To) + " (" + a.Reason + ")"
+}
+
+// NormalizeDurations extends subtitles that are on screen too briefly,
+// caps the ones that linger for too long and keeps a minimum gap between
+// consecutive subtitles. Only End times are changed, and subtitles are
+// never extended into the next one, so that the result can be chained
+// after TimeshiftSubtitleFile or PaceSubtitleFile without introducing
+// overlaps. It returns the adjusted file along with a report of every change.
+func NormalizeDurations(subfile SubtitleFile, opts DurationOptions) (SubtitleFile, []DurationAdjustment, error) {
+	var report []DurationAdjustment
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+
+	if opts.MinDuration < 0 || opts.MaxDuration < 0 || opts.MinGap < 0 || opts.CharsPerSecond < 0 {
+		return res, report, errors.New("Duration limits, gap and reading speed should not be negative")
+	}
+	if opts.MaxDuration > 0 && opts.MinDuration > opts.MaxDuration {
+		return res, report, errors.New("The minimum duration should not exceed the maximum duration")
+	}
+
+	subs := res.Subtitles
+	for i := range subs {
+		sub := &subs[i]
+		end, reason := sub.End, ""
+
+		target, targetReason := opts.MinDuration, "extended to the minimum duration"
+		if opts.CharsPerSecond > 0 {
+			fromSpeed := time.Duration(float64(CountReadingChars(sub.Content, opts.Reading)) / opts.CharsPerSecond * float64(time.Second))
+			if fromSpeed > target {
+				target, targetReason = fromSpeed, "extended for reading speed"
+			}
+		}
+		if opts.MaxDuration > 0 && target > opts.MaxDuration {
+			target = opts.MaxDuration
+		}
+		if end-sub.Start < target {
+			end, reason = sub.Start+target, targetReason
+		}
+		if opts.MaxDuration > 0 && end-sub.Start > opts.MaxDuration {
+			end, reason = sub.Start+opts.MaxDuration, "capped at the maximum duration"
+		}
+
+		if i < len(subs)-1 {
+			limit := subs[i+1].Start - opts.MinGap
+			if end > limit {
+				// Extending stops short of the next subtitle, while an already
+				// colliding one is only trimmed if it keeps some running time
+				switch {
+				case end > sub.End && limit >= sub.End:
+					end = limit
+				case end > sub.End:
+					end, reason = sub.End, ""
+				}
+				if end > limit && limit > sub.Start {
+					end, reason = limit, "trimmed to keep the minimum gap"
+				}
+			}
+		}
+
+		if end != sub.End {
+			report = append(report, DurationAdjustment{sub.Index, sub.End, end, reason})
+			sub.End = end
+		}
+	}
+	return res, report, nil
+}
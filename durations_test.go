@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeDurations(t *testing.T) {
+	type testpair struct {
+		input          SubtitleFile
+		opts           DurationOptions
+		expected       SubtitleFile
+		expectedReport []DurationAdjustment
+		expectedErr    error
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, 0, time.Duration(time.Millisecond * 200), `Hi`, "", ""},
+			{2, time.Duration(time.Second * 3), time.Duration(time.Second*3 + time.Millisecond*500), `Yes`, "", ""},
+			{3, time.Duration(time.Second*4 + time.Millisecond*50), time.Duration(time.Second * 20), `three`, "", ""},
+			{4, time.Duration(time.Second * 30), time.Duration(time.Second * 32), `four`, "", ""},
+			{5, time.Duration(time.Second*32 + time.Millisecond*50), time.Duration(time.Second * 34), `five`, "", ""},
+		},
+		"headers",
+	}
+
+	var tests = []testpair{
+		{
+			input,
+			DurationOptions{MinDuration: time.Second, MaxDuration: 5 * time.Second, MinGap: FramesToDuration(2, 25)},
+			SubtitleFile{
+				[]Subtitle{
+					{1, 0, time.Duration(time.Second * 1), `Hi`, "", ""},
+					{2, time.Duration(time.Second * 3), time.Duration(time.Second*3 + time.Millisecond*970), `Yes`, "", ""},
+					{3, time.Duration(time.Second*4 + time.Millisecond*50), time.Duration(time.Second*9 + time.Millisecond*50), `three`, "", ""},
+					{4, time.Duration(time.Second * 30), time.Duration(time.Second*31 + time.Millisecond*970), `four`, "", ""},
+					{5, time.Duration(time.Second*32 + time.Millisecond*50), time.Duration(time.Second * 34), `five`, "", ""},
+				},
+				"headers",
+			},
+			[]DurationAdjustment{
+				{1, 200 * time.Millisecond, time.Second, "extended to the minimum duration"},
+				{2, 3500 * time.Millisecond, 3970 * time.Millisecond, "extended to the minimum duration"},
+				{3, 20 * time.Second, 9050 * time.Millisecond, "capped at the maximum duration"},
+				{4, 32 * time.Second, 31970 * time.Millisecond, "trimmed to keep the minimum gap"},
+			},
+			nil,
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `Έχουμε όλοι υποφέρει.`, "", ""},
+				},
+				"",
+			},
+			DurationOptions{MinDuration: time.Second, CharsPerSecond: 10},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second*3 + time.Millisecond*100), `Έχουμε όλοι υποφέρει.`, "", ""},
+				},
+				"",
+			},
+			[]DurationAdjustment{
+				{1, 2 * time.Second, 3100 * time.Millisecond, "extended for reading speed"},
+			},
+			nil,
+		},
+		{
+			input,
+			DurationOptions{MinDuration: 2 * time.Second, MaxDuration: time.Second},
+			input,
+			nil,
+			errors.New("The minimum duration should not exceed the maximum duration"),
+		},
+		{
+			input,
+			DurationOptions{CharsPerSecond: -1},
+			input,
+			nil,
+			errors.New("Duration limits, gap and reading speed should not be negative"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, report, err := NormalizeDurations(pair.input, pair.opts)
+		if pair.expectedErr != nil && (err == nil || err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing NormalizeDurations with %v. Expected error %v but got %v instead!", pair.opts, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing NormalizeDurations with %v. Expected %v but got %v instead!", pair.opts, pair.expected, actual)
+		}
+		if !cmp.Equal(report, pair.expectedReport) {
+			t.Errorf("Testing NormalizeDurations with %v. Expected report %v but got %v instead!", pair.opts, pair.expectedReport, report)
+		}
+	}
+	if input.Subtitles[0].End != 200*time.Millisecond {
+		t.Errorf("Testing NormalizeDurations. The input subtitle file was modified")
+	}
+}
+
+func TestDurationAdjustmentString(t *testing.T) {
+	actual := DurationAdjustment{3, 20 * time.Second, 9050 * time.Millisecond, "capped at the maximum duration"}.String()
+	expected := "Subtitle 3 : End moved from 00:00:20,000 to 00:00:09,050 (capped at the maximum duration)"
+	if actual != expected {
+		t.Errorf("Testing DurationAdjustment.String. Expected %v but got %v instead!", expected, actual)
+	}
+}
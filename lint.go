@@ -0,0 +1,625 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity ranks how serious a linter issue is.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "severity(" + strconv.Itoa(int(s)) + ")"
+}
+
+// ParseSeverity reads a severity as written in linter configuration files.
+func ParseSeverity(in string) (Severity, error) {
+	switch strings.ToLower(strings.TrimSpace(in)) {
+	case "info":
+		return SeverityInfo, nil
+	case "warning", "warn":
+		return SeverityWarning, nil
+	case "error":
+		return SeverityError, nil
+	}
+	return SeverityInfo, errors.New("Unknown severity :" + in)
+}
+
+// LintIssue is a single problem found by a LintRule. Position is the
+// 1-based position of the offending subtitle in the file, or zero for
+// problems concerning the whole file; Index and Start help locate it.
+type LintIssue struct {
+	Rule     string
+	Severity Severity
+	Position int
+	Index    int
+	Start    time.Duration
+	Message  string
+	Fixable  bool
+}
+
+func (i LintIssue) String() string {
+	location := "file"
+	if i.Position > 0 {
+		location = "subtitle #" + strconv.Itoa(i.Position) + " (" + DurationToTimestampSRT(i.Start) + ")"
+	}
+	return i.Severity.String() + ": " + location + ": " + i.Message + " [" + i.Rule + "]"
+}
+
+// LintRule is a single, pluggable check. Check reports the issues found
+// in a file; the optional Fix returns a corrected copy of the file.
+// Severity is the default, which can be overridden by a LintConfig.
+type LintRule struct {
+	Name        string
+	Description string
+	Severity    Severity
+	Check       func(subfile SubtitleFile, cfg LintConfig) []LintIssue
+	Fix         func(subfile SubtitleFile, cfg LintConfig) SubtitleFile
+}
+
+// LintConfig holds the thresholds used by the rules, along with
+// per-rule severity overrides, where "off" disables a rule.
+// Zero thresholds disable the matching checks. IgnorePunctuation and
+// IgnoreSpaces select what counts towards the reading speed.
+// It's usually read from a JSON file, see ParseLintConfigFile.
+type LintConfig struct {
+	MaxCPS        float64           `json:"max_cps"`
+	MaxLineLength int               `json:"max_line_length"`
+	MaxLines      int               `json:"max_lines"`
+	Rules         map[string]string `json:"rules"`
+	MinDuration   time.Duration     `json:"-"`
+	MaxDuration   time.Duration     `json:"-"`
+	MinGap        time.Duration     `json:"-"`
+	MaxChars      int               `json:"max_chars"`
+	MergeGap      time.Duration     `json:"-"`
+
+	IgnorePunctuation bool `json:"ignore_punctuation"`
+	IgnoreSpaces      bool `json:"ignore_spaces"`
+}
+
+// UnmarshalJSON reads a LintConfig, with durations written
+// the way StrToDuration expects them, eg. "833ms" or "1.5s".
+func (cfg *LintConfig) UnmarshalJSON(data []byte) error {
+	type plainConfig LintConfig
+	aux := struct {
+		*plainConfig
+		MinDuration string `json:"min_duration"`
+		MaxDuration string `json:"max_duration"`
+		MinGap      string `json:"min_gap"`
+		MergeGap    string `json:"merge_gap"`
+	}{plainConfig: (*plainConfig)(cfg)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		// Name the struct in type errors, instead of the anonymous one above
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+			typeErr.Struct = "LintConfig"
+		}
+		return err
+	}
+	for _, field := range []struct {
+		in  string
+		out *time.Duration
+	}{{aux.MinDuration, &cfg.MinDuration}, {aux.MaxDuration, &cfg.MaxDuration}, {aux.MinGap, &cfg.MinGap}, {aux.MergeGap, &cfg.MergeGap}} {
+		if field.in == "" {
+			continue
+		}
+		d, err := StrToDuration(field.in)
+		if err != nil {
+			return errors.New("Invalid duration :" + field.in)
+		}
+		*field.out = d
+	}
+	return nil
+}
+
+// DefaultLintConfig returns the thresholds used when no configuration is given.
+func DefaultLintConfig() LintConfig {
+	return LintConfig{
+		MaxCPS:        20,
+		MaxLineLength: 42,
+		MaxLines:      2,
+		Rules:         map[string]string{},
+	}
+}
+
+// ParseLintConfigFile reads a JSON linter configuration such as
+//
+//	{"max_cps": 17, "max_line_length": 37, "rules": {"trailing-whitespace": "off", "overlap": "warning"}}
+//
+// Fields missing from the file keep their default values.
+func ParseLintConfigFile(filename string) (LintConfig, error) {
+	cfg := DefaultLintConfig()
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return cfg, errors.New("Could not open file " + filename + " for reading")
+	}
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return cfg, errors.New("Could not parse linter configuration " + filename + " : " + err.Error())
+	}
+	if cfg.Rules == nil {
+		cfg.Rules = map[string]string{}
+	}
+	for rule, setting := range cfg.Rules {
+		if setting == "off" {
+			continue
+		}
+		if _, err := ParseSeverity(setting); err != nil {
+			return cfg, errors.New("Invalid setting for rule " + rule + " : " + setting)
+		}
+	}
+	return cfg, nil
+}
+
+// reflowFix rewraps the subtitles with too many or too long lines, leaving
+// the ones that cannot fit, or already fit, untouched.
+func reflowFix(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+	return mapContent(subfile, func(content string) string {
+		lines := strings.Split(content, "\n")
+		fits := cfg.MaxLines <= 0 || len(lines) <= cfg.MaxLines
+		for _, line := range lines {
+			fits = fits && (cfg.MaxLineLength <= 0 || DisplayWidth(line) <= cfg.MaxLineLength)
+		}
+		if fits {
+			return content
+		}
+		reflowed, _ := ReflowText(content, ReflowOptions{MaxLines: cfg.MaxLines, MaxLineLength: cfg.MaxLineLength})
+		return reflowed
+	})
+}
+
+// readingOptions returns what counts towards the reading speed.
+func (cfg LintConfig) readingOptions() ReadingOptions {
+	return ReadingOptions{cfg.IgnorePunctuation, cfg.IgnoreSpaces}
+}
+
+// ruleSeverity returns the configured severity of a rule,
+// and false if the rule has been turned off.
+func (cfg LintConfig) ruleSeverity(rule LintRule) (Severity, bool) {
+	setting, ok := cfg.Rules[rule.Name]
+	if !ok {
+		return rule.Severity, true
+	}
+	if setting == "off" {
+		return rule.Severity, false
+	}
+	sev, err := ParseSeverity(setting)
+	if err != nil {
+		return rule.Severity, true
+	}
+	return sev, true
+}
+
+// Lint runs every enabled rule against the subtitle file, and returns
+// the issues found, ordered by their position in the file.
+func Lint(subfile SubtitleFile, rules []LintRule, cfg LintConfig) []LintIssue {
+	var res []LintIssue
+	for _, rule := range rules {
+		sev, enabled := cfg.ruleSeverity(rule)
+		if !enabled {
+			continue
+		}
+		for _, issue := range rule.Check(subfile, cfg) {
+			issue.Rule = rule.Name
+			issue.Severity = sev
+			issue.Fixable = rule.Fix != nil
+			res = append(res, issue)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Position < res[j].Position })
+	return res
+}
+
+// LintFix applies the fix of every enabled rule that reports issues,
+// in the order the rules are given. It returns the corrected file and
+// the names of the rules whose fixes were applied.
+func LintFix(subfile SubtitleFile, rules []LintRule, cfg LintConfig) (SubtitleFile, []string) {
+	var applied []string
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	for _, rule := range rules {
+		if _, enabled := cfg.ruleSeverity(rule); !enabled || rule.Fix == nil {
+			continue
+		}
+		if len(rule.Check(res, cfg)) == 0 {
+			continue
+		}
+		res = rule.Fix(res, cfg)
+		applied = append(applied, rule.Name)
+	}
+	return res, applied
+}
+
+// HasErrors reports whether any of the issues has error severity.
+func HasErrors(issues []LintIssue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// subtitleIssue is a helper for rules reporting a problem with the i-th subtitle.
+func subtitleIssue(subfile SubtitleFile, i int, message string) LintIssue {
+	sub := subfile.Subtitles[i]
+	return LintIssue{Position: i + 1, Index: sub.Index, Start: sub.Start, Message: message}
+}
+
+// mapContent returns a copy of the subtitle file with f applied to every subtitle's Content.
+func mapContent(subfile SubtitleFile, f func(string) string) SubtitleFile {
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	for i := range res.Subtitles {
+		res.Subtitles[i].Content = f(res.Subtitles[i].Content)
+	}
+	return res
+}
+
+var tagRegexp = regexp.MustCompile(`<(/?)([a-zA-Z]+)[^>]*>`)
+
+// unbalancedTags returns a description of the first unclosed, unopened
+// or mis-nested HTML-like tag in the content, or an empty string.
+func unbalancedTags(content string) string {
+	var open []string
+	for _, m := range tagRegexp.FindAllStringSubmatch(content, -1) {
+		name := strings.ToLower(m[2])
+		if m[1] == "" {
+			open = append(open, name)
+			continue
+		}
+		if len(open) == 0 {
+			return "closing tag </" + name + "> was never opened"
+		}
+		if open[len(open)-1] != name {
+			return "tag <" + open[len(open)-1] + "> is closed by </" + name + ">"
+		}
+		open = open[:len(open)-1]
+	}
+	if len(open) > 0 {
+		return "tag <" + open[len(open)-1] + "> is never closed"
+	}
+	return ""
+}
+
+// DefaultLintRules returns the built-in linter rules. Custom rules can be
+// appended to the returned slice before passing it to Lint.
+func DefaultLintRules() []LintRule {
+	return []LintRule{
+		{
+			Name:        "empty",
+			Description: "Subtitles without any text",
+			Severity:    SeverityError,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for i, sub := range subfile.Subtitles {
+					if strings.TrimSpace(sub.Content) == "" {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle has no text"))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res := SubtitleFile{nil, subfile.Headers}
+				for _, sub := range subfile.Subtitles {
+					if strings.TrimSpace(sub.Content) != "" {
+						res.Subtitles = append(res.Subtitles, sub)
+					}
+				}
+				return SerializeSubtitles(res)
+			},
+		},
+		{
+			Name:        "duration",
+			Description: "Subtitles with a zero or negative duration",
+			Severity:    SeverityError,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for i, sub := range subfile.Subtitles {
+					if sub.End <= sub.Start {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle ends at "+DurationToTimestampSRT(sub.End)+", before or as it starts"))
+					}
+				}
+				return res
+			},
+		},
+		{
+			Name:        "unsorted",
+			Description: "Subtitles starting before the previous one",
+			Severity:    SeverityError,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for i := 1; i < len(subfile.Subtitles); i++ {
+					if subfile.Subtitles[i].Start < subfile.Subtitles[i-1].Start {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle starts before the previous one"))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				return SerializeSubtitles(SubtitleFile{sortedByStart(subfile.Subtitles), subfile.Headers})
+			},
+		},
+		{
+			Name:        "index-sequence",
+			Description: "Subtitle indices that are not sequential, starting from 1",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for i, sub := range subfile.Subtitles {
+					if sub.Index != i+1 {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle has index "+strconv.Itoa(sub.Index)+", expected "+strconv.Itoa(i+1)))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+				copy(res.Subtitles, subfile.Subtitles)
+				return SerializeSubtitles(res)
+			},
+		},
+		{
+			Name:        "overlap",
+			Description: "Consecutive subtitles whose display times overlap",
+			Severity:    SeverityError,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for j := 1; j < len(subfile.Subtitles); j++ {
+					pair := SubtitleFile{subfile.Subtitles[j-1 : j+1], ""}
+					if len(DetectOverlaps(pair)) > 0 {
+						overlap := pair.Subtitles[0].End - pair.Subtitles[1].Start
+						res = append(res, subtitleIssue(subfile, j, "Subtitle overlaps the previous one by "+overlap.String()))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _, _ := FixOverlaps(subfile, OverlapOptions{Strategy: TrimEarlier})
+				return res
+			},
+		},
+		{
+			Name:        "reading-speed",
+			Description: "Subtitles with more characters per second than max_cps",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MaxCPS <= 0 {
+					return res
+				}
+				for i, sub := range subfile.Subtitles {
+					// Subtitles without running time are reported by the duration rule
+					speed := MeasureReadingSpeed(sub, cfg.readingOptions())
+					if speed.CPS > cfg.MaxCPS {
+						res = append(res, subtitleIssue(subfile, i, fmt.Sprintf("Reading speed is %.1f characters per second, over %v", speed.CPS, cfg.MaxCPS)))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _, _ := NormalizeDurations(subfile, DurationOptions{CharsPerSecond: cfg.MaxCPS, Reading: cfg.readingOptions()})
+				return res
+			},
+		},
+		{
+			Name:        "min-duration",
+			Description: "Subtitles shown for less than min_duration",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MinDuration <= 0 {
+					return res
+				}
+				for i, sub := range subfile.Subtitles {
+					if d := sub.End - sub.Start; d > 0 && d < cfg.MinDuration {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle is shown for "+d.String()+", under "+cfg.MinDuration.String()))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _, _ := NormalizeDurations(subfile, DurationOptions{MinDuration: cfg.MinDuration, MinGap: cfg.MinGap})
+				return res
+			},
+		},
+		{
+			Name:        "max-duration",
+			Description: "Subtitles shown for more than max_duration",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MaxDuration <= 0 {
+					return res
+				}
+				for i, sub := range subfile.Subtitles {
+					if d := sub.End - sub.Start; d > cfg.MaxDuration {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle is shown for "+d.String()+", over "+cfg.MaxDuration.String()))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _, _ := NormalizeDurations(subfile, DurationOptions{MaxDuration: cfg.MaxDuration})
+				return res
+			},
+		},
+		{
+			Name:        "min-gap",
+			Description: "Consecutive subtitles closer than min_gap",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MinGap <= 0 {
+					return res
+				}
+				for i := 1; i < len(subfile.Subtitles); i++ {
+					// Overlaps are reported by their own rule
+					if gap := subfile.Subtitles[i].Start - subfile.Subtitles[i-1].End; gap >= 0 && gap < cfg.MinGap {
+						res = append(res, subtitleIssue(subfile, i, "Gap to the previous subtitle is "+gap.String()+", under "+cfg.MinGap.String()))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _, _ := NormalizeDurations(subfile, DurationOptions{MinGap: cfg.MinGap})
+				return res
+			},
+		},
+		{
+			Name:        "max-chars",
+			Description: "Subtitles with more characters than max_chars, which are best split",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MaxChars <= 0 {
+					return res
+				}
+				for i, sub := range subfile.Subtitles {
+					if chars := CountReadingChars(sub.Content, cfg.readingOptions()); chars > cfg.MaxChars {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle has "+strconv.Itoa(chars)+" characters, over "+strconv.Itoa(cfg.MaxChars)))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _ := SplitLongCues(subfile, SplitOptions{MaxChars: cfg.MaxChars, MinGap: cfg.MinGap, Layout: ReflowOptions{MaxLines: cfg.MaxLines, MaxLineLength: cfg.MaxLineLength}})
+				return res
+			},
+		},
+		{
+			Name:        "fragmented",
+			Description: "Subtitles shorter than min_duration, less than merge_gap away from the previous one",
+			Severity:    SeverityInfo,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MinDuration <= 0 || cfg.MergeGap <= 0 {
+					return res
+				}
+				for i := 1; i < len(subfile.Subtitles); i++ {
+					prev, sub := subfile.Subtitles[i-1], subfile.Subtitles[i]
+					short := prev.End-prev.Start < cfg.MinDuration || sub.End-sub.Start < cfg.MinDuration
+					if short && sub.Start >= prev.End && sub.Start-prev.End <= cfg.MergeGap {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle could be merged with the previous one"))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				res, _ := MergeShortCues(subfile, MergeOptions{
+					MinDuration: cfg.MinDuration,
+					MaxGap:      cfg.MergeGap,
+					MaxDuration: cfg.MaxDuration,
+					MaxChars:    cfg.MaxChars,
+					Layout:      ReflowOptions{MaxLines: cfg.MaxLines, MaxLineLength: cfg.MaxLineLength},
+				})
+				return res
+			},
+		},
+		{
+			Name:        "line-length",
+			Description: "Lines with more visible characters than max_line_length",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MaxLineLength <= 0 {
+					return res
+				}
+				for i, sub := range subfile.Subtitles {
+					for n, line := range strings.Split(sub.Content, "\n") {
+						if length := DisplayWidth(line); length > cfg.MaxLineLength {
+							res = append(res, subtitleIssue(subfile, i, "Line "+strconv.Itoa(n+1)+" has "+strconv.Itoa(length)+" characters, over "+strconv.Itoa(cfg.MaxLineLength)))
+						}
+					}
+				}
+				return res
+			},
+			Fix: reflowFix,
+		},
+		{
+			Name:        "max-lines",
+			Description: "Subtitles with more lines than max_lines",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				if cfg.MaxLines <= 0 {
+					return res
+				}
+				for i, sub := range subfile.Subtitles {
+					if lines := len(strings.Split(sub.Content, "\n")); lines > cfg.MaxLines {
+						res = append(res, subtitleIssue(subfile, i, "Subtitle has "+strconv.Itoa(lines)+" lines, over "+strconv.Itoa(cfg.MaxLines)))
+					}
+				}
+				return res
+			},
+			Fix: reflowFix,
+		},
+		{
+			Name:        "unbalanced-tags",
+			Description: "Formatting tags that are not opened and closed properly",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for i, sub := range subfile.Subtitles {
+					if problem := unbalancedTags(sub.Content); problem != "" {
+						res = append(res, subtitleIssue(subfile, i, "Formatting "+problem))
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				return mapContent(subfile, func(content string) string {
+					if unbalancedTags(content) == "" {
+						return content
+					}
+					fixed, _ := SanitizeText(content, SanitizeOptions{Format: FormatSRT})
+					return fixed
+				})
+			},
+		},
+		{
+			Name:        "trailing-whitespace",
+			Description: "Lines ending in spaces or tabs",
+			Severity:    SeverityInfo,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				for i, sub := range subfile.Subtitles {
+					for n, line := range strings.Split(sub.Content, "\n") {
+						if strings.TrimRight(line, " \t") != line {
+							res = append(res, subtitleIssue(subfile, i, "Line "+strconv.Itoa(n+1)+" has trailing whitespace"))
+						}
+					}
+				}
+				return res
+			},
+			Fix: func(subfile SubtitleFile, cfg LintConfig) SubtitleFile {
+				return mapContent(subfile, func(content string) string {
+					lines := strings.Split(content, "\n")
+					for n := range lines {
+						lines[n] = strings.TrimRight(lines[n], " \t")
+					}
+					return strings.Join(lines, "\n")
+				})
+			},
+		},
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var lintTestFile = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `<i>Fine line</i>`, "", ""},
+		{3, time.Duration(time.Second * 4), time.Duration(time.Second * 4), `Zero duration`, "", ""},
+		{4, time.Duration(time.Second * 5), time.Duration(time.Second * 7), `Overlapping and trailing `, "", ""},
+		{5, time.Duration(time.Second*6 + time.Millisecond*500), time.Duration(time.Second * 8), `<b>Unclosed bold`, "", ""},
+		{6, time.Duration(time.Second * 9), time.Duration(time.Second * 10), "This is a very long line that goes on and on well past the limit\ntwo\nthree", "", ""},
+		{7, time.Duration(time.Second * 11), time.Duration(time.Second * 12), `   `, "", ""},
+	},
+	"",
+}
+
+func lintStrings(issues []LintIssue) []string {
+	var res []string
+	for _, issue := range issues {
+		res = append(res, issue.String())
+	}
+	return res
+}
+
+func TestLint(t *testing.T) {
+	type testpair struct {
+		input    SubtitleFile
+		cfg      LintConfig
+		expected []string
+	}
+
+	quiet := DefaultLintConfig()
+	quiet.MaxLines = 3
+	quiet.Rules = map[string]string{"index-sequence": "off", "duration": "warning", "line-length": "error", "reading-speed": "off"}
+
+	var tests = []testpair{
+		{
+			lintTestFile,
+			DefaultLintConfig(),
+			[]string{
+				"error: subtitle #2 (00:00:04,000): Subtitle ends at 00:00:04,000, before or as it starts [duration]",
+				"warning: subtitle #2 (00:00:04,000): Subtitle has index 3, expected 2 [index-sequence]",
+				"warning: subtitle #3 (00:00:05,000): Subtitle has index 4, expected 3 [index-sequence]",
+				"info: subtitle #3 (00:00:05,000): Line 1 has trailing whitespace [trailing-whitespace]",
+				"warning: subtitle #4 (00:00:06,500): Subtitle has index 5, expected 4 [index-sequence]",
+				"error: subtitle #4 (00:00:06,500): Subtitle overlaps the previous one by 500ms [overlap]",
+				"warning: subtitle #4 (00:00:06,500): Formatting tag <b> is never closed [unbalanced-tags]",
+				"warning: subtitle #5 (00:00:09,000): Subtitle has index 6, expected 5 [index-sequence]",
+				"warning: subtitle #5 (00:00:09,000): Reading speed is 72.0 characters per second, over 20 [reading-speed]",
+				"warning: subtitle #5 (00:00:09,000): Line 1 has 64 characters, over 42 [line-length]",
+				"warning: subtitle #5 (00:00:09,000): Subtitle has 3 lines, over 2 [max-lines]",
+				"error: subtitle #6 (00:00:11,000): Subtitle has no text [empty]",
+				"warning: subtitle #6 (00:00:11,000): Subtitle has index 7, expected 6 [index-sequence]",
+				"info: subtitle #6 (00:00:11,000): Line 1 has trailing whitespace [trailing-whitespace]",
+			},
+		},
+		{
+			lintTestFile,
+			quiet,
+			[]string{
+				"warning: subtitle #2 (00:00:04,000): Subtitle ends at 00:00:04,000, before or as it starts [duration]",
+				"info: subtitle #3 (00:00:05,000): Line 1 has trailing whitespace [trailing-whitespace]",
+				"error: subtitle #4 (00:00:06,500): Subtitle overlaps the previous one by 500ms [overlap]",
+				"warning: subtitle #4 (00:00:06,500): Formatting tag <b> is never closed [unbalanced-tags]",
+				"error: subtitle #5 (00:00:09,000): Line 1 has 64 characters, over 42 [line-length]",
+				"error: subtitle #6 (00:00:11,000): Subtitle has no text [empty]",
+				"info: subtitle #6 (00:00:11,000): Line 1 has trailing whitespace [trailing-whitespace]",
+			},
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 5), time.Duration(time.Second * 7), `<i>one <b>two</i></b>`, "", ""},
+					{2, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `two</i>`, "", ""},
+				},
+				"",
+			},
+			DefaultLintConfig(),
+			[]string{
+				"warning: subtitle #1 (00:00:05,000): Formatting tag <b> is closed by </i> [unbalanced-tags]",
+				"error: subtitle #2 (00:00:01,000): Subtitle starts before the previous one [unsorted]",
+				"error: subtitle #2 (00:00:01,000): Subtitle overlaps the previous one by 6s [overlap]",
+				"warning: subtitle #2 (00:00:01,000): Formatting closing tag </i> was never opened [unbalanced-tags]",
+			},
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, 0, time.Duration(time.Millisecond * 500), `Wait,`, "", ""},
+					{2, time.Duration(time.Millisecond * 600), time.Duration(time.Second * 2), `don't go.`, "", ""},
+					{3, time.Duration(time.Second * 3), time.Duration(time.Second * 6), `We have all suffered. We have all lost people.`, "", ""},
+				},
+				"",
+			},
+			LintConfig{MaxChars: 40, MinDuration: time.Second, MergeGap: 200 * time.Millisecond},
+			[]string{
+				"warning: subtitle #1 (00:00:00,000): Subtitle is shown for 500ms, under 1s [min-duration]",
+				"info: subtitle #2 (00:00:00,600): Subtitle could be merged with the previous one [fragmented]",
+				"warning: subtitle #3 (00:00:03,000): Subtitle has 46 characters, over 40 [max-chars]",
+			},
+		},
+	}
+
+	for _, pair := range tests {
+		actual := lintStrings(Lint(pair.input, DefaultLintRules(), pair.cfg))
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing Lint with %v. Expected\n%v\nbut got\n%v\ninstead!", pair.cfg, pair.expected, actual)
+		}
+	}
+}
+
+func TestLintFix(t *testing.T) {
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `<i>Fine line</i>`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second*4 + time.Millisecond*650), `Zero duration`, "", ""},
+			{3, time.Duration(time.Second * 5), time.Duration(time.Second*6 + time.Millisecond*500), `Overlapping and trailing`, "", ""},
+			{4, time.Duration(time.Second*6 + time.Millisecond*500), time.Duration(time.Second * 8), `<b>Unclosed bold</b>`, "", ""},
+			{5, time.Duration(time.Second * 9), time.Duration(time.Second*12 + time.Millisecond*600), "This is a very long line that goes\non and on well past the limit two three", "", ""},
+		},
+		"",
+	}
+	expectedApplied := []string{"empty", "overlap", "reading-speed", "line-length", "unbalanced-tags", "trailing-whitespace"}
+
+	actual, applied := LintFix(lintTestFile, DefaultLintRules(), DefaultLintConfig())
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("Testing LintFix. Expected %v but got %v instead!", expected, actual)
+	}
+	if !cmp.Equal(applied, expectedApplied) {
+		t.Errorf("Testing LintFix. Expected fixes %v but got %v instead!", expectedApplied, applied)
+	}
+	if issues := Lint(actual, DefaultLintRules(), DefaultLintConfig()); HasErrors(issues) {
+		t.Errorf("Testing LintFix. Expected no errors after fixing, but got %v", issues)
+	}
+	if lintTestFile.Subtitles[1].Index != 3 {
+		t.Errorf("Testing LintFix. The input subtitle file was modified")
+	}
+}
+
+func TestParseLintConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	badSeverity := filepath.Join(dir, "severity.json")
+	ioutil.WriteFile(badSeverity, []byte(`{"rules": {"overlap": "fatal"}}`), 0600)
+	badJSON := filepath.Join(dir, "json.json")
+	ioutil.WriteFile(badJSON, []byte(`{"max_cps": "fast"}`), 0600)
+	badGap := filepath.Join(dir, "gap.json")
+	ioutil.WriteFile(badGap, []byte(`{"min_gap": "fast"}`), 0600)
+
+	type testpair struct {
+		input       string
+		expected    LintConfig
+		expectedErr error
+	}
+	var tests = []testpair{
+		{
+			"samples/lint_config.json",
+			LintConfig{MaxCPS: 20, MaxLineLength: 37, MaxLines: 2, Rules: map[string]string{"trailing-whitespace": "off", "overlap": "warning"}, MinGap: 80 * time.Millisecond},
+			nil,
+		},
+		{badSeverity, LintConfig{}, errors.New("Invalid setting for rule overlap : fatal")},
+		{badJSON, LintConfig{}, errors.New("Could not parse linter configuration " + badJSON + " : json: cannot unmarshal string into Go struct field LintConfig.max_cps of type float64")},
+		{badGap, LintConfig{}, errors.New("Could not parse linter configuration " + badGap + " : Invalid duration :fast")},
+		{"wrongfilename", LintConfig{}, errors.New("Could not open file wrongfilename for reading")},
+	}
+
+	for _, pair := range tests {
+		actual, err := ParseLintConfigFile(pair.input)
+		if pair.expectedErr != nil {
+			if err == nil || err.Error() != pair.expectedErr.Error() {
+				t.Errorf("Testing ParseLintConfigFile with %v. Expected error %v but got %v instead!", pair.input, pair.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil || !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing ParseLintConfigFile with %v. Expected %v but got %v (%v) instead!", pair.input, pair.expected, actual, err)
+		}
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	type testpair struct {
+		args           []string
+		expectedCode   int
+		expectedStdout string
+	}
+
+	var tests = []testpair{
+		{
+			[]string{"lint", "samples/sample_lint.srt"},
+			1,
+			"samples/sample_lint.srt: info: subtitle #2 (00:00:05,000): Line 1 has trailing whitespace [trailing-whitespace]\n" +
+				"samples/sample_lint.srt: error: subtitle #3 (00:00:06,500): Subtitle overlaps the previous one by 500ms [overlap]\n" +
+				"samples/sample_lint.srt: warning: subtitle #3 (00:00:06,500): Formatting tag <b> is never closed [unbalanced-tags]\n",
+		},
+		{
+			[]string{"lint", "-config", "samples/lint_config.json", "samples/sample_lint.srt"},
+			0,
+			"samples/sample_lint.srt: warning: subtitle #3 (00:00:06,500): Subtitle overlaps the previous one by 500ms [overlap]\n" +
+				"samples/sample_lint.srt: warning: subtitle #3 (00:00:06,500): Formatting tag <b> is never closed [unbalanced-tags]\n",
+		},
+	}
+
+	for _, pair := range tests {
+		var stdout, stderr bytes.Buffer
+		code := runCLI(pair.args, &stdout, &stderr)
+		if code != pair.expectedCode {
+			t.Errorf("Testing lint command with %v. Expected exit code %v but got %v instead (%v)", pair.args, pair.expectedCode, code, stderr.String())
+		}
+		if stdout.String() != pair.expectedStdout {
+			t.Errorf("Testing lint command with %v. Expected output\n%v\nbut got\n%v\ninstead", pair.args, pair.expectedStdout, stdout.String())
+		}
+	}
+}
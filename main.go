@@ -1,4 +1,7 @@
 package main
 
+import "os"
+
 func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+// ThreeWayOptions configures MergeThreeWay. With ConflictMarkers, the text
+// of conflicting subtitles shows both sides between conflict markers, as
+// git does, to be resolved in an editor.
+type ThreeWayOptions struct {
+	ConflictMarkers bool
+}
+
+// MergeConflict reports a subtitle that both sides changed in different
+// ways. Attribute is "timing", "text", or "removal" when one side removed
+// the subtitle that the other changed. Index is that of the subtitle in the
+// base file, once sorted, and a removed subtitle is the zero Subtitle.
+type MergeConflict struct {
+	Index     int
+	Attribute string
+	Base      Subtitle
+	Ours      Subtitle
+	Theirs    Subtitle
+}
+
+func (c MergeConflict) String() string {
+	return "Subtitle " + strconv.Itoa(c.Index) + " : conflicting changes to its " + c.Attribute
+}
+
+// conflictText returns the text of a conflicting subtitle between conflict
+// markers, labelled with the timing of each side.
+func conflictText(ours, theirs Subtitle, oursRemoved, theirsRemoved bool) string {
+	side := func(name string, sub Subtitle, removed bool) (string, string) {
+		if removed {
+			return name + " (removed)", ""
+		}
+		return name + " (" + timingLine(sub) + ")", sub.Content + "\n"
+	}
+	oursLabel, oursText := side("ours", ours, oursRemoved)
+	theirsLabel, theirsText := side("theirs", theirs, theirsRemoved)
+	return "<<<<<<< " + oursLabel + "\n" + oursText + "=======\n" + theirsText + ">>>>>>> " + theirsLabel
+}
+
+// MergeThreeWay combines the changes two editors made to the same base
+// subtitle file, eg. one fixing the timing and the other the text. Changes
+// to different subtitles, or to the timing and the text of the same one,
+// are all kept, as are the subtitles added on either side. When both sides
+// changed the same attribute of a subtitle differently, or one removed a
+// subtitle the other changed, it's reported as a conflict, and our side is
+// kept, unless the conflict is marked in the text. The result is sorted and
+// renumbered.
+func MergeThreeWay(base, ours, theirs SubtitleFile, opts ThreeWayOptions) (SubtitleFile, []MergeConflict) {
+	var conflicts []MergeConflict
+	sorted, _, _ := ReindexSubtitleFile(base, ReindexOptions{})
+	changes := func(derived SubtitleFile) (map[int]CueChange, []Subtitle) {
+		res := map[int]CueChange{}
+		var added []Subtitle
+		for _, c := range DiffSubtitleFiles(sorted, derived) {
+			if c.Kind&Added != 0 {
+				added = append(added, c.New)
+				continue
+			}
+			res[c.Old.Index] = c
+		}
+		return res, added
+	}
+	oursChanges, oursAdded := changes(ours)
+	theirsChanges, theirsAdded := changes(theirs)
+
+	var subs []Subtitle
+	for _, b := range sorted.Subtitles {
+		o, t := oursChanges[b.Index], theirsChanges[b.Index]
+		oRemoved, tRemoved := o.Kind&Removed != 0, t.Kind&Removed != 0
+		switch {
+		case oRemoved && tRemoved:
+			continue
+		case oRemoved && !t.Changed(), tRemoved && !o.Changed():
+			continue
+		case oRemoved || tRemoved:
+			conflicts = append(conflicts, MergeConflict{b.Index, "removal", b, o.New, t.New})
+			kept := o.New
+			if oRemoved {
+				kept = t.New
+			}
+			if opts.ConflictMarkers {
+				kept.Content = conflictText(o.New, t.New, oRemoved, tRemoved)
+				subs = append(subs, kept)
+			} else if !oRemoved {
+				subs = append(subs, kept)
+			}
+			continue
+		}
+
+		res := b
+		var conflicting bool
+		switch {
+		case o.Kind&Retimed != 0 && t.Kind&Retimed != 0 && (o.New.Start != t.New.Start || o.New.End != t.New.End):
+			conflicts = append(conflicts, MergeConflict{b.Index, "timing", b, o.New, t.New})
+			conflicting = true
+			res.Start, res.End = o.New.Start, o.New.End
+		case o.Kind&Retimed != 0:
+			res.Start, res.End = o.New.Start, o.New.End
+		case t.Kind&Retimed != 0:
+			res.Start, res.End = t.New.Start, t.New.End
+		}
+		switch {
+		case o.Kind&Reworded != 0 && t.Kind&Reworded != 0 && o.New.Content != t.New.Content:
+			conflicts = append(conflicts, MergeConflict{b.Index, "text", b, o.New, t.New})
+			conflicting = true
+			res.Content = o.New.Content
+		case o.Kind&Reworded != 0:
+			res.Content = o.New.Content
+		case t.Kind&Reworded != 0:
+			res.Content = t.New.Content
+		}
+		if conflicting && opts.ConflictMarkers {
+			res.Content = conflictText(o.New, t.New, false, false)
+		}
+		subs = append(subs, res)
+	}
+
+	// Subtitles added on both sides are only kept once
+	subs = append(subs, oursAdded...)
+	for _, sub := range theirsAdded {
+		duplicate := false
+		for _, other := range oursAdded {
+			duplicate = duplicate || (other.Start == sub.Start && other.End == sub.End && other.Content == sub.Content)
+		}
+		if !duplicate {
+			subs = append(subs, sub)
+		}
+	}
+	sort.SliceStable(subs, func(i, j int) bool { return subs[i].Start < subs[j].Start })
+	return SerializeSubtitles(SubtitleFile{subs, base.Headers}), conflicts
+}
+
+// ConflictsJSON returns the conflicts as a JSON array, for review tools.
+func ConflictsJSON(conflicts []MergeConflict) ([]byte, error) {
+	type jsonConflict struct {
+		Index     int      `json:"index"`
+		Attribute string   `json:"attribute"`
+		Base      *jsonCue `json:"base"`
+		Ours      *jsonCue `json:"ours"`
+		Theirs    *jsonCue `json:"theirs"`
+	}
+	toJSON := func(sub Subtitle) *jsonCue {
+		if sub == (Subtitle{}) {
+			return nil
+		}
+		return &jsonCue{sub.Index, sub.Start.Milliseconds(), sub.End.Milliseconds(), sub.Content}
+	}
+	res := []jsonConflict{}
+	for _, c := range conflicts {
+		res = append(res, jsonConflict{c.Index, c.Attribute, toJSON(c.Base), toJSON(c.Ours), toJSON(c.Theirs)})
+	}
+	return json.MarshalIndent(res, "", "  ")
+}
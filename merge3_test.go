@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeThreeWay(t *testing.T) {
+	base := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους`, "", ""},
+			{4, time.Duration(time.Second * 15), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+		},
+		"",
+	}
+	// One side fixes the timing, and adds a subtitle
+	ours := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second*1 + time.Millisecond*200), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+			{4, time.Duration(time.Second * 15), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+			{5, time.Duration(time.Second * 18), time.Duration(time.Second * 20), `Σας προσφέρω την επιλογή.`, "", ""},
+		},
+		"",
+	}
+	// The other fixes the text, and removes a subtitle
+	theirs := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Όλοι έχουμε υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει τους αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των πλουσίων`, "", ""},
+		},
+		"",
+	}
+
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second*1 + time.Millisecond*200), time.Duration(time.Second * 3), `Όλοι έχουμε υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει τους αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+			{4, time.Duration(time.Second * 18), time.Duration(time.Second * 20), `Σας προσφέρω την επιλογή.`, "", ""},
+		},
+		"",
+	}
+	expectedConflicts := []MergeConflict{{3, "text", base.Subtitles[2], ours.Subtitles[2], theirs.Subtitles[2]}}
+
+	res, conflicts := MergeThreeWay(base, ours, theirs, ThreeWayOptions{})
+	if !cmp.Equal(res, expected) {
+		t.Errorf("Testing MergeThreeWay. Expected %v but got %v instead!", expected, res)
+	}
+	if !cmp.Equal(conflicts, expectedConflicts) {
+		t.Errorf("Testing MergeThreeWay. Expected conflicts %v but got %v instead!", expectedConflicts, conflicts)
+	}
+
+	res, _ = MergeThreeWay(base, ours, theirs, ThreeWayOptions{ConflictMarkers: true})
+	expectedText := "<<<<<<< ours (00:00:10,000 --> 00:00:14,000)\nΑυτό δεν αφορά τους Οίκους των ευγενών\n=======\nΑυτό δεν αφορά τους Οίκους των πλουσίων\n>>>>>>> theirs (00:00:10,000 --> 00:00:14,000)"
+	if res.Subtitles[2].Content != expectedText {
+		t.Errorf("Testing MergeThreeWay. Expected conflict markers\n%v\nbut got\n%v", expectedText, res.Subtitles[2].Content)
+	}
+
+	// Removing a subtitle the other side changed is a conflict
+	retimed := SubtitleFile{append([]Subtitle(nil), base.Subtitles...), ""}
+	retimed.Subtitles[3].End += time.Second
+	res, conflicts = MergeThreeWay(base, retimed, theirs, ThreeWayOptions{})
+	if len(conflicts) != 1 || conflicts[0].Attribute != "removal" || len(res.Subtitles) != 4 {
+		t.Errorf("Testing MergeThreeWay. Expected a removal conflict keeping our subtitle, got %v and %v", res, conflicts)
+	}
+
+	out, err := ConflictsJSON(conflicts)
+	if err != nil || !strings.Contains(string(out), `"attribute": "removal"`) || !strings.Contains(string(out), `"theirs": null`) {
+		t.Errorf("Testing ConflictsJSON. Got %v and %v", string(out), err)
+	}
+}
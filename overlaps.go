@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OverlapStrategy selects how FixOverlaps resolves a pair of overlapping subtitles.
+type OverlapStrategy int
+
+const (
+	// TrimEarlier moves the End of the earlier subtitle back
+	TrimEarlier OverlapStrategy = iota
+	// PushLater moves the later subtitle forward, keeping its duration
+	PushLater
+	// SplitDifference moves both, meeting halfway through the overlap
+	SplitDifference
+	// MergeCues joins both subtitles into a single, two-line one
+	MergeCues
+)
+
+func (s OverlapStrategy) String() string {
+	switch s {
+	case TrimEarlier:
+		return "trimming the earlier subtitle"
+	case PushLater:
+		return "pushing the later subtitle"
+	case SplitDifference:
+		return "splitting the difference"
+	case MergeCues:
+		return "merging the subtitles"
+	}
+	return "unknown strategy " + strconv.Itoa(int(s))
+}
+
+// OverlapOptions configures FixOverlaps. After fixing, consecutive
+// subtitles are at least MinGap apart, and no adjusted subtitle is
+// shorter than MinDuration.
+type OverlapOptions struct {
+	Strategy    OverlapStrategy
+	MinGap      time.Duration
+	MinDuration time.Duration
+}
+
+// OverlapFix records how a single overlap was resolved. First and Second
+// are the indices of the subtitles before renumbering. A non-positive
+// Overlap means they were only closer than the minimum gap. Strategy may
+// differ from the requested one when the subtitles had to be merged instead.
+type OverlapFix struct {
+	First    int
+	Second   int
+	Overlap  time.Duration
+	Strategy OverlapStrategy
+}
+
+func (f OverlapFix) String() string {
+	problem := " overlapped by " + f.Overlap.String()
+	if f.Overlap <= 0 {
+		problem = " were closer than the minimum gap"
+	}
+	return "Subtitles " + strconv.Itoa(f.First) + " and " + strconv.Itoa(f.Second) + problem + ", resolved by " + f.Strategy.String()
+}
+
+// sortedByStart returns a copy of the subtitles, stably sorted by Start time.
+func sortedByStart(subs []Subtitle) []Subtitle {
+	res := make([]Subtitle, len(subs))
+	copy(res, subs)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return res
+}
+
+// DetectAllOverlaps returns every pair of subtitles whose display times
+// overlap, including ones that are not consecutive in the file,
+// eg. subtitle 5 running over subtitle 20. Within each pair, the subtitle
+// that starts earlier comes first.
+func DetectAllOverlaps(subfile SubtitleFile) [][2]Subtitle {
+	var res [][2]Subtitle
+	subs := sortedByStart(subfile.Subtitles)
+	for i := range subs {
+		for j := i + 1; j < len(subs) && subs[j].Start < subs[i].End; j++ {
+			res = append(res, [2]Subtitle{subs[i], subs[j]})
+		}
+	}
+	return res
+}
+
+// FixOverlaps resolves all overlapping subtitles using the selected strategy.
+// Subtitles are first sorted by their Start time, so that overlaps between
+// non-consecutive entries are resolved as well. When the minimum gap and
+// duration cannot be kept by moving the subtitles' timings, the pair is
+// merged instead. The result is renumbered, and every change is logged.
+func FixOverlaps(subfile SubtitleFile, opts OverlapOptions) (SubtitleFile, []OverlapFix, error) {
+	var fixes []OverlapFix
+	if opts.Strategy < TrimEarlier || opts.Strategy > MergeCues {
+		return subfile, fixes, errors.New("Unknown overlap strategy " + strconv.Itoa(int(opts.Strategy)))
+	}
+	if opts.MinGap < 0 || opts.MinDuration < 0 {
+		return subfile, fixes, errors.New("The minimum gap and duration should not be negative")
+	}
+
+	subs := sortedByStart(subfile.Subtitles)
+	for i := 0; i < len(subs)-1; {
+		a, b := &subs[i], &subs[i+1]
+		if a.End+opts.MinGap <= b.Start {
+			i++
+			continue
+		}
+		fix := OverlapFix{a.Index, b.Index, a.End - b.Start, opts.Strategy}
+		if opts.Strategy == MergeCues || !retimePair(a, b, opts) {
+			fix.Strategy = MergeCues
+			if b.End > a.End {
+				a.End = b.End
+			}
+			a.Content = a.Content + "\n" + b.Content
+			subs = append(subs[:i+1], subs[i+2:]...)
+			fixes = append(fixes, fix)
+			// The merged subtitle might now overlap the following one as well
+			continue
+		}
+		fixes = append(fixes, fix)
+		i++
+	}
+	return SerializeSubtitles(SubtitleFile{subs, subfile.Headers}), fixes, nil
+}
+
+// retimePair moves the boundary between two overlapping subtitles according
+// to the strategy. It returns false, leaving the subtitles untouched, if
+// the minimum gap and duration cannot be kept without merging them. Both
+// subtitles are kept at least a millisecond long, even with no MinDuration.
+func retimePair(a, b *Subtitle, opts OverlapOptions) bool {
+	if opts.MinDuration < time.Millisecond {
+		opts.MinDuration = time.Millisecond
+	}
+	var cut time.Duration
+	switch opts.Strategy {
+	case TrimEarlier:
+		cut = b.Start - opts.MinGap
+	case PushLater:
+		cut = a.End
+	case SplitDifference:
+		cut = (a.End + b.Start - opts.MinGap) / 2
+	}
+
+	lo := a.Start + opts.MinDuration
+	if cut < lo {
+		cut = lo
+	}
+	if opts.Strategy == PushLater {
+		// The later subtitle keeps its length, and may run into
+		// the next one, which is handled on the following pair
+		length := b.End - b.Start
+		if length < opts.MinDuration {
+			length = opts.MinDuration
+		}
+		a.End = cut
+		b.Start = cut + opts.MinGap
+		b.End = b.Start + length
+		return true
+	}
+
+	hi := b.End - opts.MinDuration - opts.MinGap
+	if cut > hi {
+		cut = hi
+	}
+	if cut < lo {
+		return false
+	}
+	a.End = cut
+	b.Start = cut + opts.MinGap
+	return true
+}
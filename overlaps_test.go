@@ -0,0 +1,225 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDetectAllOverlaps(t *testing.T) {
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 5), `one`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 8), `two`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `three`, "", ""},
+			{4, time.Duration(time.Second * 13), time.Duration(time.Second * 14), `four`, "", ""},
+			{5, time.Duration(time.Second * 11), time.Duration(time.Second*11 + time.Millisecond*500), `five`, "", ""},
+		},
+		"",
+	}
+	expected := [][2]Subtitle{
+		{input.Subtitles[0], input.Subtitles[1]},
+		{input.Subtitles[2], input.Subtitles[4]},
+	}
+
+	actual := DetectAllOverlaps(input)
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("Testing DetectAllOverlaps. Expected %v but got %v instead!", expected, actual)
+	}
+	if actual := DetectAllOverlaps(SubtitleFile{}); len(actual) != 0 {
+		t.Errorf("Testing DetectAllOverlaps with an empty file. Expected no overlaps but got %v instead!", actual)
+	}
+}
+
+func TestFixOverlaps(t *testing.T) {
+	type testpair struct {
+		input         SubtitleFile
+		opts          OverlapOptions
+		expected      SubtitleFile
+		expectedFixes []OverlapFix
+		expectedErr   error
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 5), `one`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 8), `two`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `three`, "", ""},
+			{4, time.Duration(time.Second * 13), time.Duration(time.Second * 14), `four`, "", ""},
+			{5, time.Duration(time.Second * 11), time.Duration(time.Second*11 + time.Millisecond*500), `five`, "", ""},
+		},
+		"headers",
+	}
+
+	var tests = []testpair{
+		{
+			input,
+			OverlapOptions{TrimEarlier, 100 * time.Millisecond, 500 * time.Millisecond},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second*3 + time.Millisecond*900), `one`, "", ""},
+					{2, time.Duration(time.Second * 4), time.Duration(time.Second * 8), `two`, "", ""},
+					{3, time.Duration(time.Second * 10), time.Duration(time.Second*10 + time.Millisecond*900), `three`, "", ""},
+					{4, time.Duration(time.Second * 11), time.Duration(time.Second*11 + time.Millisecond*500), `five`, "", ""},
+					{5, time.Duration(time.Second * 13), time.Duration(time.Second * 14), `four`, "", ""},
+				},
+				"headers",
+			},
+			[]OverlapFix{{1, 2, time.Second, TrimEarlier}, {3, 5, time.Second, TrimEarlier}},
+			nil,
+		},
+		{
+			input,
+			OverlapOptions{PushLater, 0, 0},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 5), `one`, "", ""},
+					{2, time.Duration(time.Second * 5), time.Duration(time.Second * 9), `two`, "", ""},
+					{3, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `three`, "", ""},
+					{4, time.Duration(time.Second * 12), time.Duration(time.Second*12 + time.Millisecond*500), `five`, "", ""},
+					{5, time.Duration(time.Second * 13), time.Duration(time.Second * 14), `four`, "", ""},
+				},
+				"headers",
+			},
+			[]OverlapFix{{1, 2, time.Second, PushLater}, {3, 5, time.Second, PushLater}},
+			nil,
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(0), time.Duration(time.Second * 10), `one`, "", ""},
+					{2, time.Duration(time.Second * 2), time.Duration(time.Second * 10), `two`, "", ""},
+				},
+				"",
+			},
+			OverlapOptions{PushLater, 0, 0},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(0), time.Duration(time.Second * 10), `one`, "", ""},
+					{2, time.Duration(time.Second * 10), time.Duration(time.Second * 18), `two`, "", ""},
+				},
+				"",
+			},
+			[]OverlapFix{{1, 2, 8 * time.Second, PushLater}},
+			nil,
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(0), time.Duration(time.Second * 10), `one`, "", ""},
+					{2, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `two`, "", ""},
+					{3, time.Duration(time.Second * 20), time.Duration(time.Second * 22), `three`, "", ""},
+					{4, time.Duration(time.Second * 20), time.Duration(time.Second*20 + time.Millisecond), `four`, "", ""},
+				},
+				"",
+			},
+			OverlapOptions{SplitDifference, 0, 0},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(0), time.Duration(time.Second*1 + time.Millisecond*999), `one`, "", ""},
+					{2, time.Duration(time.Second*1 + time.Millisecond*999), time.Duration(time.Second * 2), `two`, "", ""},
+					{3, time.Duration(time.Second * 20), time.Duration(time.Second * 22), "three\nfour", "", ""},
+				},
+				"",
+			},
+			[]OverlapFix{{1, 2, 9 * time.Second, SplitDifference}, {3, 4, 2 * time.Second, MergeCues}},
+			nil,
+		},
+		{
+			input,
+			OverlapOptions{SplitDifference, 0, 200 * time.Millisecond},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second*4 + time.Millisecond*500), `one`, "", ""},
+					{2, time.Duration(time.Second*4 + time.Millisecond*500), time.Duration(time.Second * 8), `two`, "", ""},
+					{3, time.Duration(time.Second * 10), time.Duration(time.Second*11 + time.Millisecond*300), `three`, "", ""},
+					{4, time.Duration(time.Second*11 + time.Millisecond*300), time.Duration(time.Second*11 + time.Millisecond*500), `five`, "", ""},
+					{5, time.Duration(time.Second * 13), time.Duration(time.Second * 14), `four`, "", ""},
+				},
+				"headers",
+			},
+			[]OverlapFix{{1, 2, time.Second, SplitDifference}, {3, 5, time.Second, SplitDifference}},
+			nil,
+		},
+		{
+			input,
+			OverlapOptions{MergeCues, 0, 0},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 8), "one\ntwo", "", ""},
+					{2, time.Duration(time.Second * 10), time.Duration(time.Second * 12), "three\nfive", "", ""},
+					{3, time.Duration(time.Second * 13), time.Duration(time.Second * 14), `four`, "", ""},
+				},
+				"headers",
+			},
+			[]OverlapFix{{1, 2, time.Second, MergeCues}, {3, 5, time.Second, MergeCues}},
+			nil,
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `one`, "", ""},
+					{2, time.Duration(time.Second*1 + time.Millisecond*200), time.Duration(time.Second*1 + time.Millisecond*500), `two`, "", ""},
+				},
+				"",
+			},
+			OverlapOptions{TrimEarlier, 0, 500 * time.Millisecond},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), "one\ntwo", "", ""},
+				},
+				"",
+			},
+			[]OverlapFix{{1, 2, 800 * time.Millisecond, MergeCues}},
+			nil,
+		},
+		{
+			input,
+			OverlapOptions{OverlapStrategy(7), 0, 0},
+			input,
+			nil,
+			errors.New("Unknown overlap strategy 7"),
+		},
+		{
+			input,
+			OverlapOptions{TrimEarlier, -time.Second, 0},
+			input,
+			nil,
+			errors.New("The minimum gap and duration should not be negative"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, fixes, err := FixOverlaps(pair.input, pair.opts)
+		if pair.expectedErr != nil && (err == nil || err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing FixOverlaps with %v. Expected error %v but got %v instead!", pair.opts, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing FixOverlaps with %v. Expected %v but got %v instead!", pair.opts, pair.expected, actual)
+		}
+		if !cmp.Equal(fixes, pair.expectedFixes) {
+			t.Errorf("Testing FixOverlaps with %v. Expected fixes %v but got %v instead!", pair.opts, pair.expectedFixes, fixes)
+		}
+	}
+	if input.Subtitles[0].End != 5*time.Second || input.Subtitles[4].Index != 5 {
+		t.Errorf("Testing FixOverlaps. The input subtitle file was modified")
+	}
+}
+
+func TestOverlapFixString(t *testing.T) {
+	type testpair struct {
+		input    OverlapFix
+		expected string
+	}
+	var tests = []testpair{
+		{OverlapFix{3, 5, time.Second, SplitDifference}, "Subtitles 3 and 5 overlapped by 1s, resolved by splitting the difference"},
+		{OverlapFix{1, 2, -40 * time.Millisecond, PushLater}, "Subtitles 1 and 2 were closer than the minimum gap, resolved by pushing the later subtitle"},
+	}
+	for _, pair := range tests {
+		if actual := pair.input.String(); actual != pair.expected {
+			t.Errorf("Testing OverlapFix.String. Expected %v but got %v instead!", pair.expected, actual)
+		}
+	}
+}
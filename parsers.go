@@ -9,6 +9,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type SRTReader struct {
@@ -91,3 +92,156 @@ func TimestampSplitSRT(r rune) bool {
 func EOLSplit(r rune) bool {
 	return r == '\n' || r == '\r'
 }
+
+// ParseSubtitleFile parses a subtitle file in the format of its extension,
+// see ParseWebVTTFile and ParseASSFile, defaulting to SRT.
+func ParseSubtitleFile(filename string) (SubtitleFile, []error) {
+	switch formatForFile(filename) {
+	case FormatWebVTT:
+		return ParseWebVTTFile(filename)
+	case FormatASS:
+		return ParseASSFile(filename)
+	}
+	return ParseSRTFile(filename)
+}
+
+// readTextFile reads a file as text, with Unix line endings and no BOM.
+func readTextFile(filename string) (string, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", errors.New("Something went wrong while trying to parse the provided file!")
+	}
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	return strings.Replace(text, "\r\n", "\n", -1), nil
+}
+
+var webVTTTimingRegexp = regexp.MustCompile(`^(\S+)\s+-->\s+(\S+)(.*)$`)
+
+// TimestampToDurationVTT parses a WebVTT timestamp, whose hours are optional,
+// eg. 01:02.500 or 00:01:02.500
+func TimestampToDurationVTT(in string) (time.Duration, error) {
+	if strings.Count(in, ":") == 1 {
+		in = "00:" + in
+	}
+	return TimestampToDurationSRT(in)
+}
+
+// ParseWebVTTFile parses a WebVTT file. The text of every cue goes through
+// ParseWebVTTText, and its cue settings through ParseWebVTTSettings, so that
+// the subtitles keep their formatting and position in the form used by the
+// rest of gophersub. Comments, styles and regions are skipped, and the
+// subtitles are numbered in order.
+func ParseWebVTTFile(filename string) (SubtitleFile, []error) {
+	var res SubtitleFile
+	var errCollection []error
+	text, err := readTextFile(filename)
+	if err != nil {
+		return res, []error{err}
+	}
+	if !strings.HasPrefix(text, "WEBVTT") {
+		return res, []error{errors.New("The file does not start with a WEBVTT header")}
+	}
+
+	for _, block := range regexp.MustCompile(`\n{2,}`).Split(strings.TrimSpace(text), -1)[1:] {
+		lines := strings.Split(block, "\n")
+		if !strings.Contains(lines[0], "-->") {
+			// A cue identifier, or a NOTE, STYLE or REGION block
+			lines = lines[1:]
+		}
+		if len(lines) == 0 || !strings.Contains(lines[0], "-->") {
+			continue
+		}
+		m := webVTTTimingRegexp.FindStringSubmatch(lines[0])
+		if m == nil {
+			errCollection = append(errCollection, errors.New("Could not parse the cue timing :`"+lines[0]+"`"))
+			continue
+		}
+		var current Subtitle
+		current.Index = len(res.Subtitles) + 1
+		if current.Start, err = TimestampToDurationVTT(m[1]); err != nil {
+			errCollection = append(errCollection, err)
+		}
+		if current.End, err = TimestampToDurationVTT(m[2]); err != nil {
+			errCollection = append(errCollection, err)
+		}
+		styled := ParseWebVTTText(strings.Join(lines[1:], "\n"))
+		styled.Position = ParseWebVTTSettings(m[3])
+		current.Content = styled.Markup()
+		res.Subtitles = append(res.Subtitles, current)
+	}
+	return res, errCollection
+}
+
+// assLineBreaks turns the \N and \n line breaks and the \h hard space of
+// SSA/ASS events into plain text.
+var assLineBreaks = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ")
+
+// TimestampToDurationASS parses an SSA/ASS timestamp, eg. 0:01:02.50
+func TimestampToDurationASS(in string) (time.Duration, error) {
+	if i := strings.LastIndex(in, "."); i >= 0 && len(in)-i == 3 {
+		in += "0"
+	}
+	return TimestampToDurationSRT(in)
+}
+
+// ParseASSFile parses the events of an SSA/ASS file. The text of every
+// event goes through ParseStyledText, so that its override blocks become
+// the formatting and position used by the rest of gophersub. Comments and
+// styles are skipped, and the subtitles are numbered in order.
+func ParseASSFile(filename string) (SubtitleFile, []error) {
+	var res SubtitleFile
+	var errCollection []error
+	text, err := readTextFile(filename)
+	if err != nil {
+		return res, []error{err}
+	}
+
+	var format []string
+	inEvents := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			inEvents = strings.EqualFold(line, "[Events]")
+			continue
+		}
+		if !inEvents {
+			continue
+		}
+		if strings.HasPrefix(line, "Format:") {
+			format = strings.Split(strings.TrimPrefix(line, "Format:"), ",")
+			for i := range format {
+				format[i] = strings.TrimSpace(format[i])
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "Dialogue:") {
+			continue
+		}
+		if len(format) == 0 {
+			return res, append(errCollection, errors.New("The events of the file have no Format line"))
+		}
+		fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, "Dialogue:")), ",", len(format))
+		if len(fields) != len(format) {
+			errCollection = append(errCollection, errors.New("Could not parse the event :`"+line+"`"))
+			continue
+		}
+		var current Subtitle
+		current.Index = len(res.Subtitles) + 1
+		for i, name := range format {
+			switch name {
+			case "Start":
+				if current.Start, err = TimestampToDurationASS(fields[i]); err != nil {
+					errCollection = append(errCollection, err)
+				}
+			case "End":
+				if current.End, err = TimestampToDurationASS(fields[i]); err != nil {
+					errCollection = append(errCollection, err)
+				}
+			case "Text":
+				current.Content = ParseStyledText(assLineBreaks.Replace(fields[i])).Markup()
+			}
+		}
+		res.Subtitles = append(res.Subtitles, current)
+	}
+	return res, errCollection
+}
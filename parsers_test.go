@@ -3,6 +3,9 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -191,3 +194,73 @@ func TestTimestampSplitSRT(t *testing.T) {
 		}
 	}
 }
+
+func TestParseWebVTTFile(t *testing.T) {
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second*1 + time.Millisecond*602), time.Duration(time.Second*3 + time.Millisecond*314), `Έχουμε όλοι <i>υποφέρει</i>.`, "", ""},
+			{2, time.Duration(time.Second*4 + time.Millisecond*536), time.Duration(time.Second*7 + time.Millisecond*379), `{\an8}<v Jon>Έχουμε χάσει</v> αγαπημένους & φίλους.`, "", ""},
+			{3, time.Duration(time.Second*10 + time.Millisecond*88), time.Duration(time.Second*14 + time.Millisecond*500), `{\an1\pos(115.2,57.6)}<font color="yellow">Αυτό δεν αφορά τους Οίκους των ευγενών,</font>
+<ruby>北<rt>きた</rt></ruby> <3`, "", ""},
+			{4, time.Duration(time.Second * 15), time.Duration(time.Second*16 + time.Millisecond*500), `use &lt;i&gt; for italics`, "", ""},
+		},
+		"",
+	}
+
+	res, errs := ParseSubtitleFile("samples/sample.vtt")
+	if len(errs) != 0 || !cmp.Equal(res, expected) {
+		t.Errorf("Testing ParseWebVTTFile. Expected %v but got %v and %v instead!", expected, res, errs)
+	}
+
+	if text := res.Subtitles[3].PlainText(); text != "use <i> for italics" {
+		t.Errorf("Testing ParseWebVTTFile with escaped markup. Expected the text to read %q, got %q", "use <i> for italics", text)
+	}
+
+	_, errs = ParseWebVTTFile("samples/sample.srt")
+	if !ErrorSlicesEqual(errs, []error{errors.New("The file does not start with a WEBVTT header")}) {
+		t.Errorf("Testing ParseWebVTTFile with an SRT file. Got %v", errs)
+	}
+}
+
+func TestWebVTTRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	subfile, _ := ParseSubtitleFile("samples/sample.vtt")
+	for format, ext := range map[TextFormat]string{FormatWebVTT: ".vtt", FormatSRT: ".srt"} {
+		filename := filepath.Join(dir, "roundtrip"+ext)
+		f, err := os.Create(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		WriteSubtitles(f, subfile, format)
+		f.Close()
+
+		res, errs := ParseSubtitleFile(filename)
+		if len(errs) != 0 || len(res.Subtitles) != 4 {
+			t.Fatalf("Testing a %v round trip. Could not read it back, got %v and %v", format, res, errs)
+		}
+		if res.Subtitles[3].Content != subfile.Subtitles[3].Content {
+			t.Errorf("Testing a %v round trip. Expected %q but read back %q", format, subfile.Subtitles[3].Content, res.Subtitles[3].Content)
+		}
+	}
+}
+
+func TestParseASSFile(t *testing.T) {
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second*1 + time.Millisecond*600), time.Duration(time.Second*3 + time.Millisecond*310), `Έχουμε όλοι <i>υποφέρει</i>.`, "", ""},
+			{2, time.Duration(time.Second*4 + time.Millisecond*540), time.Duration(time.Second*7 + time.Millisecond*380), "{\\an8}Έχουμε χάσει,\nαγαπημένους μας.", "", ""},
+			{3, time.Duration(time.Second*10 + time.Millisecond*90), time.Duration(time.Second*14 + time.Millisecond*500), `<font color="#ffff00">Αυτό, δεν αφορά</font> τους Οίκους`, "", ""},
+		},
+		"",
+	}
+
+	res, errs := ParseSubtitleFile("samples/sample.ass")
+	if len(errs) != 0 || !cmp.Equal(res, expected) {
+		t.Errorf("Testing ParseASSFile. Expected %v but got %v and %v instead!", expected, res, errs)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// SplitSubtitleFile splits a subtitle file in two at a point in time, eg.
+// to match a video that's split in two parts. The second part is rebased so
+// that it starts at zero. A subtitle shown at the split point is cut in two,
+// appearing at the end of the first part and the start of the second. Both
+// parts are renumbered.
+func SplitSubtitleFile(subfile SubtitleFile, at time.Duration) (SubtitleFile, SubtitleFile, error) {
+	first := SubtitleFile{nil, subfile.Headers}
+	second := SubtitleFile{nil, subfile.Headers}
+	if at <= 0 {
+		return first, second, errors.New("The split point should be positive")
+	}
+	for _, sub := range subfile.Subtitles {
+		if sub.Start < at {
+			part := sub
+			if part.End > at {
+				part.End = at
+			}
+			first.Subtitles = append(first.Subtitles, part)
+		}
+		if sub.End > at {
+			part := sub
+			if part.Start < at {
+				part.Start = at
+			}
+			part.Start, part.End = part.Start-at, part.End-at
+			second.Subtitles = append(second.Subtitles, part)
+		}
+	}
+	return SerializeSubtitles(first), SerializeSubtitles(second), nil
+}
+
+// JoinSubtitleFiles concatenates the subtitle files of a video split in
+// several parts, such as CD1 and CD2 releases, into a single one. The
+// lengths are those of the video of each part, so that every part is
+// shifted by the total length of the ones before it; the length of the
+// last part is not needed. Parts whose length is not given are taken to
+// end with their last subtitle. The headers of the first part are kept,
+// and the result is renumbered.
+func JoinSubtitleFiles(parts []SubtitleFile, lengths []time.Duration) (SubtitleFile, error) {
+	var res SubtitleFile
+	if len(parts) == 0 {
+		return res, errors.New("There are no subtitle files to join")
+	}
+	res.Headers = parts[0].Headers
+	var offset time.Duration
+	for i, part := range parts {
+		if i > 0 {
+			previous := parts[i-1].Subtitles
+			switch {
+			case i-1 < len(lengths) && lengths[i-1] <= 0:
+				return SubtitleFile{}, errors.New("The length of part " + strconv.Itoa(i) + " should be positive")
+			case i-1 < len(lengths):
+				offset += lengths[i-1]
+			case len(previous) > 0:
+				offset += previous[len(previous)-1].End
+			default:
+				return SubtitleFile{}, errors.New("The length of part " + strconv.Itoa(i) + " is needed, as it has no subtitles")
+			}
+		}
+		res.Subtitles = append(res.Subtitles, TimeshiftSubtitleFile(part, offset).Subtitles...)
+	}
+	return SerializeSubtitles(res), nil
+}
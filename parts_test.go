@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitSubtitleFile(t *testing.T) {
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+		},
+		"headers",
+	}
+	expectedFirst := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 5), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+		},
+		"headers",
+	}
+	expectedSecond := SubtitleFile{
+		[]Subtitle{
+			{1, 0, time.Duration(time.Second * 2), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{2, time.Duration(time.Second * 5), time.Duration(time.Second * 9), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+		},
+		"headers",
+	}
+
+	first, second, err := SplitSubtitleFile(input, 5*time.Second)
+	if err != nil || !cmp.Equal(first, expectedFirst) || !cmp.Equal(second, expectedSecond) {
+		t.Errorf("Testing SplitSubtitleFile. Expected %v and %v but got %v, %v and %v", expectedFirst, expectedSecond, first, second, err)
+	}
+	if input.Subtitles[1].End != 7*time.Second {
+		t.Errorf("Testing SplitSubtitleFile. The input subtitle file was modified")
+	}
+
+	// Joining the parts back gives the original file, with the cut subtitle in two
+	joined, err := JoinSubtitleFiles([]SubtitleFile{first, second}, []time.Duration{5 * time.Second})
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 5), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 5), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{4, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+		},
+		"headers",
+	}
+	if err != nil || !cmp.Equal(joined, expected) {
+		t.Errorf("Testing JoinSubtitleFiles. Expected %v but got %v and %v", expected, joined, err)
+	}
+}
+
+func TestJoinSubtitleFiles(t *testing.T) {
+	type testpair struct {
+		parts       []SubtitleFile
+		lengths     []time.Duration
+		expected    SubtitleFile
+		expectedErr error
+	}
+
+	part := SubtitleFile{[]Subtitle{{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `Κι εγώ σκοπεύω να ζήσω.`, "", ""}}, ""}
+	var tests = []testpair{
+		{
+			[]SubtitleFile{part, part, part},
+			[]time.Duration{time.Hour, 30 * time.Minute},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+					{2, time.Duration(time.Hour + time.Second*1), time.Duration(time.Hour + time.Second*2), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+					{3, time.Duration(time.Hour + 30*time.Minute + time.Second*1), time.Duration(time.Hour + 30*time.Minute + time.Second*2), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+				},
+				"",
+			},
+			nil,
+		},
+		{
+			[]SubtitleFile{part, part, part},
+			[]time.Duration{time.Hour},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+					{2, time.Duration(time.Hour + time.Second*1), time.Duration(time.Hour + time.Second*2), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+					{3, time.Duration(time.Hour + time.Second*3), time.Duration(time.Hour + time.Second*4), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+				},
+				"",
+			},
+			nil,
+		},
+		{[]SubtitleFile{{}, part}, nil, SubtitleFile{}, errors.New("The length of part 1 is needed, as it has no subtitles")},
+		{[]SubtitleFile{part, part}, []time.Duration{-time.Second}, SubtitleFile{}, errors.New("The length of part 1 should be positive")},
+		{nil, nil, SubtitleFile{}, errors.New("There are no subtitle files to join")},
+	}
+
+	for _, pair := range tests {
+		res, err := JoinSubtitleFiles(pair.parts, pair.lengths)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing JoinSubtitleFiles. Expected error %v but got %v instead!", pair.expectedErr, err)
+		}
+		if !cmp.Equal(res, pair.expected) {
+			t.Errorf("Testing JoinSubtitleFiles. Expected %v but got %v instead!", pair.expected, res)
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PatchOp is a single edit of a patch. Op is one of
+//
+//	shift   moves subtitles From to To by Offset
+//	retime  moves the Start of subtitles From to To by Offset, and their End by EndOffset
+//	pace    changes the pace of subtitles From to To by Rate, as PaceSubtitleFile does
+//	add     adds the subtitles in Cues
+//	remove  removes subtitles From to To
+//	text    replaces the text of subtitle From with Text
+//	split   splits subtitle From into the subtitles in Cues
+//	merge   merges subtitles From to To into the single subtitle in Cues
+//
+// From and To are positions in the target file once it's sorted, as in
+// DiffSubtitleFiles, and every operation refers to the target as it was
+// before the patch, so that they don't depend on each other. Expect holds
+// the subtitles the operation was made for, only the first and the last
+// for the ones that retime a range, to check that the patch matches its
+// target.
+type PatchOp struct {
+	Op        string
+	From      int
+	To        int
+	Offset    time.Duration
+	EndOffset time.Duration
+	Rate      float64
+	Expect    []Subtitle
+	Cues      []Subtitle
+	Text      string
+}
+
+// Patch is a list of edits to a subtitle file, that can be saved and
+// applied to other copies of the same file, even if they're timed
+// differently.
+type Patch []PatchOp
+
+// subtitleRange formats the subtitles an operation refers to, eg. 100-200
+func (op PatchOp) subtitleRange() string {
+	if op.From == op.To {
+		return strconv.Itoa(op.From)
+	}
+	return strconv.Itoa(op.From) + "-" + strconv.Itoa(op.To)
+}
+
+func (op PatchOp) String() string {
+	switch op.Op {
+	case "shift":
+		return "shift " + op.subtitleRange() + " " + formatOffset(op.Offset)
+	case "retime":
+		return "retime " + op.subtitleRange() + " " + formatOffset(op.Offset) + " " + formatOffset(op.EndOffset)
+	case "pace":
+		return "pace " + op.subtitleRange() + " " + strconv.FormatFloat(op.Rate, 'f', -1, 64)
+	case "add":
+		var timings []string
+		for _, sub := range op.Cues {
+			timings = append(timings, timingLine(sub))
+		}
+		return "add " + strings.Join(timings, ", ")
+	case "text":
+		return "text " + op.subtitleRange() + " `" + strings.Replace(op.Text, "\n", "|", -1) + "`"
+	case "split":
+		return "split " + op.subtitleRange() + " into " + strconv.Itoa(len(op.Cues))
+	}
+	return op.Op + " " + op.subtitleRange()
+}
+
+// at returns when the operation takes place, for ordering.
+func (op PatchOp) at() time.Duration {
+	if len(op.Expect) > 0 {
+		return op.Expect[0].Start
+	}
+	if len(op.Cues) > 0 {
+		return op.Cues[0].Start
+	}
+	return 0
+}
+
+// words returns the plain text of subtitles, with whitespace normalised, to
+// tell whether some subtitles are another one split up.
+func words(subs []Subtitle) string {
+	var res []string
+	for _, sub := range subs {
+		res = append(res, strings.Fields(StripMarkup(sub.Content))...)
+	}
+	return strings.Join(res, " ")
+}
+
+// within returns the consecutive subtitles shown during sub, among the ones
+// that are free to pair with it, if there's at least two of them and they
+// have the same text as sub.
+func within(sub Subtitle, subs []Subtitle, free func(i int) bool) []int {
+	var res []int
+	for i, other := range subs {
+		if other.Start < sub.Start || other.End > sub.End {
+			continue
+		}
+		if !free(i) || (len(res) > 0 && res[len(res)-1] != i-1) {
+			return nil
+		}
+		res = append(res, i)
+	}
+	if len(res) < 2 {
+		return nil
+	}
+	var parts []Subtitle
+	for _, i := range res {
+		parts = append(parts, subs[i])
+	}
+	if words(parts) != words([]Subtitle{sub}) {
+		return nil
+	}
+	return res
+}
+
+// pacedRate returns the rate that PaceSubtitleFile would have used to
+// change a subtitle from old to new, if there's one. The rates tried are
+// those of DefaultSyncRates, and the ones with three decimal places, or
+// whose inverse has, eg. 1.25 or 1/0.8.
+func pacedRate(old, new Subtitle) (float64, bool) {
+	if old.End <= 0 || new.End <= 0 || old.End == new.End {
+		return 0, false
+	}
+	ratio := float64(old.End) / float64(new.End)
+	rates := append([]float64{math.Round(1000*ratio) / 1000, 1000 / math.Round(1000/ratio)}, DefaultSyncRates...)
+	for _, rate := range rates {
+		if rate > 0 && pacedBy(old, new, rate) {
+			return rate, true
+		}
+	}
+	return 0, false
+}
+
+// pacedBy reports whether a subtitle was paced by rate, to the millisecond.
+func pacedBy(old, new Subtitle, rate float64) bool {
+	close := func(a, b time.Duration) bool {
+		return a.Round(time.Millisecond) == b.Round(time.Millisecond)
+	}
+	return close(paceDuration(old.Start, rate), new.Start) && close(paceDuration(old.End, rate), new.End)
+}
+
+// PatchFromDiff turns the changes between two files, as returned by
+// DiffSubtitleFiles, into a patch that turns the old file into the new one.
+// A subtitle replaced by consecutive ones with the same text, shown during
+// it, is recorded as split, and the reverse as merged. Runs of subtitles
+// moved by the same offset are recorded as a single shift, and runs paced
+// by the same rate as a single pace.
+func PatchFromDiff(changes []CueChange) Patch {
+	var olds, news []CueChange
+	for _, c := range changes {
+		if c.Kind&Added == 0 {
+			olds = append(olds, c)
+		}
+		if c.Kind&Removed == 0 {
+			news = append(news, c)
+		}
+	}
+	sort.SliceStable(olds, func(i, j int) bool { return olds[i].Old.Index < olds[j].Old.Index })
+	sort.SliceStable(news, func(i, j int) bool { return news[i].New.Index < news[j].New.Index })
+	oldSubs, newSubs := make([]Subtitle, len(olds)), make([]Subtitle, len(news))
+	for i, c := range olds {
+		oldSubs[i] = c.Old
+	}
+	for j, c := range news {
+		newSubs[j] = c.New
+	}
+
+	var res Patch
+	oldDone, newDone := map[int]bool{}, map[int]bool{}
+	for i, c := range olds {
+		if c.Kind&(Removed|Reworded) == 0 {
+			continue
+		}
+		parts := within(c.Old, newSubs, func(j int) bool {
+			return !newDone[j] && (news[j].Kind&Added != 0 || news[j].Old.Index == c.Old.Index)
+		})
+		if parts == nil {
+			continue
+		}
+		op := PatchOp{Op: "split", From: c.Old.Index, To: c.Old.Index, Expect: []Subtitle{c.Old}}
+		for _, j := range parts {
+			op.Cues = append(op.Cues, newSubs[j])
+			newDone[j] = true
+		}
+		oldDone[i] = true
+		res = append(res, op)
+	}
+	for j, c := range news {
+		if newDone[j] || c.Kind&(Added|Reworded) == 0 {
+			continue
+		}
+		parts := within(c.New, oldSubs, func(i int) bool {
+			return !oldDone[i] && (olds[i].Kind&Removed != 0 || olds[i].New.Index == c.New.Index)
+		})
+		if parts == nil {
+			continue
+		}
+		op := PatchOp{Op: "merge", From: oldSubs[parts[0]].Index, To: oldSubs[parts[len(parts)-1]].Index, Cues: []Subtitle{c.New}}
+		for _, i := range parts {
+			op.Expect = append(op.Expect, oldSubs[i])
+			oldDone[i] = true
+		}
+		newDone[j] = true
+		res = append(res, op)
+	}
+
+	// A subtitle whose counterpart was split or merged into others is
+	// removed, or added, instead
+	done := func(sub Subtitle, subs []Subtitle, done map[int]bool) bool {
+		i := sort.Search(len(subs), func(i int) bool { return subs[i].Index >= sub.Index })
+		return i < len(subs) && subs[i].Index == sub.Index && done[i]
+	}
+	var retimed []CueChange
+	for i, c := range olds {
+		switch {
+		case oldDone[i]:
+		case c.Kind&Removed != 0 || done(c.New, newSubs, newDone):
+			res = append(res, PatchOp{Op: "remove", From: c.Old.Index, To: c.Old.Index, Expect: []Subtitle{c.Old}})
+		default:
+			if c.Kind&Retimed != 0 {
+				retimed = append(retimed, c)
+			}
+			if c.Kind&Reworded != 0 {
+				res = append(res, PatchOp{Op: "text", From: c.Old.Index, To: c.Old.Index, Expect: []Subtitle{c.Old}, Text: c.New.Content})
+			}
+		}
+	}
+	for j, c := range news {
+		if !newDone[j] && (c.Kind&Added != 0 || done(c.Old, oldSubs, oldDone)) {
+			res = append(res, PatchOp{Op: "add", Cues: []Subtitle{c.New}})
+		}
+	}
+
+	for k := 0; k < len(retimed); {
+		c := retimed[k]
+		// run counts the consecutive subtitles retimed the same way as c
+		run := func(same func(o CueChange) bool) int {
+			n := 1
+			for k+n < len(retimed) && retimed[k+n].Old.Index == c.Old.Index+n && same(retimed[k+n]) {
+				n++
+			}
+			return n
+		}
+		n, op := 1, PatchOp{Op: "retime", Offset: c.Offset, EndOffset: c.New.End - c.Old.End}
+		shifted := op.Offset == op.EndOffset
+		if shifted {
+			n = run(func(o CueChange) bool { return o.Offset == c.Offset && o.New.End-o.Old.End == c.Offset })
+			op = PatchOp{Op: "shift", Offset: c.Offset}
+		}
+		if rate, ok := pacedRate(c.Old, c.New); ok && n == 1 {
+			if m := run(func(o CueChange) bool { return pacedBy(o.Old, o.New, rate) }); m > 1 {
+				n, op = m, PatchOp{Op: "pace", Rate: rate}
+			}
+		}
+		op.From, op.To, op.Expect = c.Old.Index, c.Old.Index+n-1, []Subtitle{c.Old}
+		if n > 1 {
+			op.Expect = append(op.Expect, retimed[k+n-1].Old)
+		}
+		res = append(res, op)
+		k += n
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return res[i].at() < res[j].at() })
+	return res
+}
+
+// patchError reports an operation that does not fit the subtitle file.
+func patchError(n int, op PatchOp, reason string) error {
+	return errors.New("Patch operation " + strconv.Itoa(n) + " (" + op.String() + ") " + reason)
+}
+
+// ApplyPatch applies a patch to a subtitle file, eg. to ship the same
+// corrections to other copies of it. Every operation is first checked
+// against the subtitles it refers to, going by their text, so that the
+// patch still applies to a copy that's timed differently: the subtitles of
+// a split or a merge are moved along with the ones they replace, while
+// added subtitles are kept as they are. If anything does not match, or a
+// subtitle is changed by more than one operation, the patch is not applied
+// and an error is returned. The result is sorted and renumbered.
+func ApplyPatch(target SubtitleFile, patch Patch) (SubtitleFile, error) {
+	sorted, _, _ := ReindexSubtitleFile(target, ReindexOptions{})
+	// Operations are checked against the target as it was before the
+	// patch, while they're applied to a copy of it
+	original := sorted.Subtitles
+	subs := make([]Subtitle, len(original))
+	copy(subs, original)
+	// Every subtitle can be retimed by one operation and reworded by another,
+	// while removing, splitting and merging it does both
+	const timing, text = 1, 2
+	claimed := make([]int, len(subs))
+	replaced := map[int][]Subtitle{}
+	var added []Subtitle
+
+	for n, op := range patch {
+		n++
+		var claim int
+		ranged := false
+		switch op.Op {
+		case "shift", "retime", "pace":
+			claim, ranged = timing, true
+		case "text":
+			claim = text
+		case "remove", "split", "merge":
+			claim = timing | text
+		case "add":
+			if len(op.Cues) == 0 {
+				return target, patchError(n, op, "has no subtitles to add")
+			}
+			added = append(added, op.Cues...)
+			continue
+		default:
+			return target, errors.New("Unknown patch operation :`" + op.Op + "`")
+		}
+
+		if op.From < 1 || op.To < op.From || op.To > len(subs) {
+			return target, patchError(n, op, "refers to subtitles outside the "+strconv.Itoa(len(subs))+" of the file")
+		}
+		complete := true
+		switch op.Op {
+		case "text":
+			complete = op.From == op.To
+		case "split":
+			complete = op.From == op.To && len(op.Cues) > 1
+		case "merge":
+			complete = op.From < op.To && len(op.Cues) == 1
+		case "pace":
+			complete = op.Rate > 0
+		}
+		expected := map[int]Subtitle{}
+		if ranged && len(op.Expect) > 0 && len(op.Expect) <= 2 {
+			expected[op.From], expected[op.To] = op.Expect[0], op.Expect[len(op.Expect)-1]
+		} else if !ranged && len(op.Expect) == op.To-op.From+1 {
+			for i, sub := range op.Expect {
+				expected[op.From+i] = sub
+			}
+		} else {
+			complete = false
+		}
+		if !complete {
+			return target, patchError(n, op, "is incomplete")
+		}
+		for idx, sub := range expected {
+			if original[idx-1].Content != sub.Content {
+				return target, patchError(n, op, "expected subtitle "+strconv.Itoa(idx)+" to read `"+sub.Content+"`, not `"+original[idx-1].Content+"`")
+			}
+		}
+		for idx := op.From; idx <= op.To; idx++ {
+			if claimed[idx-1]&claim != 0 {
+				return target, patchError(n, op, "changes subtitle "+strconv.Itoa(idx)+", which is changed by another operation")
+			}
+			claimed[idx-1] |= claim
+		}
+
+		drift := original[op.From-1].Start - op.Expect[0].Start
+		for idx := op.From; idx <= op.To; idx++ {
+			sub := &subs[idx-1]
+			switch op.Op {
+			case "shift":
+				sub.Start, sub.End = sub.Start+op.Offset, sub.End+op.Offset
+			case "retime":
+				sub.Start, sub.End = sub.Start+op.Offset, sub.End+op.EndOffset
+			case "pace":
+				sub.Start, sub.End = paceDuration(sub.Start, op.Rate), paceDuration(sub.End, op.Rate)
+			case "text":
+				sub.Content = op.Text
+			default:
+				replaced[idx] = nil
+			}
+		}
+		if op.Op == "split" || op.Op == "merge" {
+			for _, sub := range op.Cues {
+				sub.Start, sub.End = sub.Start+drift, sub.End+drift
+				replaced[op.From] = append(replaced[op.From], sub)
+			}
+		}
+	}
+
+	var res []Subtitle
+	for i, sub := range subs {
+		if cues, ok := replaced[i+1]; ok {
+			res = append(res, cues...)
+			continue
+		}
+		res = append(res, sub)
+	}
+	res = append(res, added...)
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Start < res[j].Start })
+	return SerializeSubtitles(SubtitleFile{res, target.Headers}), nil
+}
+
+// jsonPatchOp is the JSON form of a PatchOp.
+type jsonPatchOp struct {
+	Op        string    `json:"op"`
+	From      int       `json:"from,omitempty"`
+	To        int       `json:"to,omitempty"`
+	Offset    int64     `json:"offset_ms,omitempty"`
+	EndOffset int64     `json:"end_offset_ms,omitempty"`
+	Rate      float64   `json:"rate,omitempty"`
+	Expect    []jsonCue `json:"expect,omitempty"`
+	Cues      []jsonCue `json:"cues,omitempty"`
+	Text      string    `json:"text,omitempty"`
+}
+
+// PatchJSON returns the patch as a JSON array, to be saved and applied
+// later with ParsePatchJSON and ApplyPatch.
+func PatchJSON(patch Patch) ([]byte, error) {
+	toJSON := func(subs []Subtitle) []jsonCue {
+		var res []jsonCue
+		for _, sub := range subs {
+			res = append(res, jsonCue{sub.Index, sub.Start.Milliseconds(), sub.End.Milliseconds(), sub.Content})
+		}
+		return res
+	}
+	res := []jsonPatchOp{}
+	for _, op := range patch {
+		res = append(res, jsonPatchOp{op.Op, op.From, op.To, op.Offset.Milliseconds(), op.EndOffset.Milliseconds(),
+			op.Rate, toJSON(op.Expect), toJSON(op.Cues), op.Text})
+	}
+	return json.MarshalIndent(res, "", "  ")
+}
+
+// ParsePatchFile reads a patch saved by PatchJSON from a file.
+func ParsePatchFile(filename string) (Patch, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.New("Could not open file " + filename + " for reading")
+	}
+	return ParsePatchJSON(content)
+}
+
+// ParsePatchJSON reads a patch saved by PatchJSON.
+func ParsePatchJSON(data []byte) (Patch, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, errors.New("The patch is not valid JSON :" + err.Error())
+	}
+	fromJSON := func(cues []jsonCue) []Subtitle {
+		var res []Subtitle
+		for _, cue := range cues {
+			res = append(res, Subtitle{cue.Index, time.Duration(cue.Start) * time.Millisecond, time.Duration(cue.End) * time.Millisecond, cue.Content, "", ""})
+		}
+		return res
+	}
+	var res Patch
+	for _, op := range ops {
+		res = append(res, PatchOp{op.Op, op.From, op.To, time.Duration(op.Offset) * time.Millisecond, time.Duration(op.EndOffset) * time.Millisecond,
+			op.Rate, fromJSON(op.Expect), fromJSON(op.Cues), op.Text})
+	}
+	return res, nil
+}
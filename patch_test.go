@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var patchTestBefore = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+		{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών. Αφορά τους ζωντανούς και τους νεκρούς.`, "", ""},
+		{4, time.Duration(time.Second * 15), time.Duration(time.Second * 16), `Κι εγώ`, "", ""},
+		{5, time.Duration(time.Second * 16), time.Duration(time.Second * 17), `σκοπεύω να ζήσω.`, "", ""},
+		{6, time.Duration(time.Second * 20), time.Duration(time.Second * 22), `Σας προσφέρω μια επιλογή.`, "", ""},
+		{7, time.Duration(time.Second * 23), time.Duration(time.Second * 25), `Λυγίστε το γόνατο.`, "", ""},
+		{8, time.Duration(time.Second * 26), time.Duration(time.Second * 28), `Ή πεθάνετε.`, "", ""},
+	},
+	"",
+}
+
+var patchTestAfter = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second*1 + time.Millisecond*200), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει τους αγαπημένους μας.`, "", ""},
+		{3, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `Αυτό δεν αφορά τους Οίκους των ευγενών.`, "", ""},
+		{4, time.Duration(time.Second * 12), time.Duration(time.Second * 14), `Αφορά τους ζωντανούς και τους νεκρούς.`, "", ""},
+		{5, time.Duration(time.Second * 15), time.Duration(time.Second * 17), "Κι εγώ\nσκοπεύω να ζήσω.", "", ""},
+		{6, time.Duration(time.Second*21 + time.Millisecond*500), time.Duration(time.Second*23 + time.Millisecond*500), `Σας προσφέρω μια επιλογή.`, "", ""},
+		{7, time.Duration(time.Second*24 + time.Millisecond*500), time.Duration(time.Second*26 + time.Millisecond*500), `Λυγίστε το γόνατο.`, "", ""},
+		{8, time.Duration(time.Second * 30), time.Duration(time.Second * 32), `Θα πολεμήσουμε.`, "", ""},
+	},
+	"",
+}
+
+func TestPatchFromDiff(t *testing.T) {
+	before, after := patchTestBefore.Subtitles, patchTestAfter.Subtitles
+	expected := Patch{
+		{Op: "retime", From: 1, To: 1, Offset: 200 * time.Millisecond, Expect: []Subtitle{before[0]}},
+		{Op: "text", From: 2, To: 2, Expect: []Subtitle{before[1]}, Text: after[1].Content},
+		{Op: "split", From: 3, To: 3, Expect: []Subtitle{before[2]}, Cues: []Subtitle{after[2], after[3]}},
+		{Op: "merge", From: 4, To: 5, Expect: []Subtitle{before[3], before[4]}, Cues: []Subtitle{after[4]}},
+		{Op: "shift", From: 6, To: 7, Offset: 1500 * time.Millisecond, Expect: []Subtitle{before[5], before[6]}},
+		{Op: "remove", From: 8, To: 8, Expect: []Subtitle{before[7]}},
+		{Op: "add", Cues: []Subtitle{after[7]}},
+	}
+
+	patch := PatchFromDiff(DiffSubtitleFiles(patchTestBefore, patchTestAfter))
+	if !cmp.Equal(patch, expected) {
+		t.Errorf("Testing PatchFromDiff. Expected %v but got %v instead!", expected, patch)
+	}
+
+	res, err := ApplyPatch(patchTestBefore, patch)
+	if err != nil || !cmp.Equal(res, patchTestAfter) {
+		t.Errorf("Testing ApplyPatch. Expected %v but got %v and %v instead!", patchTestAfter, res, err)
+	}
+
+	// A subtitle both retimed and reworded is checked against its original text
+	edited := SubtitleFile{make([]Subtitle, len(patchTestBefore.Subtitles)), ""}
+	copy(edited.Subtitles, patchTestBefore.Subtitles)
+	edited.Subtitles[1].Start += 500 * time.Millisecond
+	edited.Subtitles[1].End += 500 * time.Millisecond
+	edited.Subtitles[1].Content = `Έχουμε χάσει τους αγαπημένους μας.`
+	patch = PatchFromDiff(DiffSubtitleFiles(patchTestBefore, edited))
+	if len(patch) != 2 {
+		t.Errorf("Testing PatchFromDiff. Expected a text and a shift operation, got %v", patch)
+	}
+	res, err = ApplyPatch(patchTestBefore, patch)
+	if err != nil || !cmp.Equal(res, edited) {
+		t.Errorf("Testing ApplyPatch. Expected %v but got %v and %v instead!", edited, res, err)
+	}
+
+	// Subtitles paced together make up a single operation
+	paced, _ := PaceSubtitleFile(patchTestBefore, 1.25)
+	patch = PatchFromDiff(DiffSubtitleFiles(patchTestBefore, paced))
+	if len(patch) != 1 || patch[0].String() != "pace 1-8 1.25" {
+		t.Errorf("Testing PatchFromDiff. Expected a single pace, got %v", patch)
+	}
+
+	// Including for the rates of frame rate conversions, over a feature
+	feature := TimeshiftSubtitleFile(patchTestBefore, time.Hour)
+	paced, _ = PaceSubtitleFile(feature, 24./23.976)
+	patch = PatchFromDiff(DiffSubtitleFiles(feature, paced))
+	if len(patch) != 1 || patch[0].Op != "pace" || patch[0].Rate != 24./23.976 {
+		t.Errorf("Testing PatchFromDiff. Expected a single pace by 24/23.976, got %v", patch)
+	}
+	if res, err := ApplyPatch(feature, patch); err != nil || !cmp.Equal(res, paced) {
+		t.Errorf("Testing ApplyPatch. Expected %v but got %v and %v instead!", paced, res, err)
+	}
+}
+
+func TestPatchJSON(t *testing.T) {
+	patch := PatchFromDiff(DiffSubtitleFiles(patchTestBefore, patchTestAfter))
+	out, err := PatchJSON(patch)
+	if err != nil {
+		t.Fatalf("Testing PatchJSON. Got %v", err)
+	}
+	res, err := ParsePatchJSON(out)
+	if err != nil || !cmp.Equal(res, patch) {
+		t.Errorf("Testing ParsePatchJSON. Expected %v but got %v and %v instead!", patch, res, err)
+	}
+
+	_, err = ParsePatchJSON([]byte(`{"op": "shift"}`))
+	if err == nil {
+		t.Errorf("Testing ParsePatchJSON. Expected an error for a patch that's not a list")
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	before := patchTestBefore.Subtitles
+
+	// The patch applies to a copy that's timed differently
+	copied := TimeshiftSubtitleFile(patchTestBefore, 10*time.Second)
+	patch := Patch{
+		{Op: "split", From: 3, To: 3, Expect: []Subtitle{before[2]}, Cues: patchTestAfter.Subtitles[2:4]},
+		{Op: "text", From: 6, To: 6, Expect: []Subtitle{before[5]}, Text: `Σας προσφέρω την επιλογή.`},
+	}
+	res, err := ApplyPatch(copied, patch)
+	if err != nil || len(res.Subtitles) != 9 || res.Subtitles[3].Start != 22*time.Second || res.Subtitles[6].Content != `Σας προσφέρω την επιλογή.` {
+		t.Errorf("Testing ApplyPatch on a shifted copy. Got %v and %v", res, err)
+	}
+
+	type testpair struct {
+		patch       Patch
+		expectedErr error
+	}
+	var tests = []testpair{
+		{Patch{{Op: "shift", From: 1, To: 8, Offset: time.Second, Expect: []Subtitle{before[0], before[7]}}}, nil},
+		{Patch{{Op: "rename", From: 1, To: 1}}, errors.New("Unknown patch operation :`rename`")},
+		{Patch{{Op: "remove", From: 8, To: 9, Expect: before[7:]}}, errors.New("Patch operation 1 (remove 8-9) refers to subtitles outside the 8 of the file")},
+		{Patch{{Op: "merge", From: 4, To: 5, Expect: before[3:5]}}, errors.New("Patch operation 1 (merge 4-5) is incomplete")},
+		{Patch{{Op: "text", From: 2, To: 2, Expect: before[:1], Text: "Hello"}}, errors.New("Patch operation 1 (text 2 `Hello`) expected subtitle 2 to read `Έχουμε όλοι υποφέρει.`, not `Έχουμε χάσει αγαπημένους μας.`")},
+		{Patch{
+			{Op: "shift", From: 1, To: 3, Offset: time.Second, Expect: []Subtitle{before[0], before[2]}},
+			{Op: "text", From: 2, To: 2, Expect: before[1:2], Text: "Hello"},
+			{Op: "remove", From: 3, To: 3, Expect: before[2:3]},
+		}, errors.New("Patch operation 3 (remove 3) changes subtitle 3, which is changed by another operation")},
+	}
+
+	for _, pair := range tests {
+		res, err := ApplyPatch(patchTestBefore, pair.patch)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing ApplyPatch with %v. Expected error %v but got %v instead!", pair.patch, pair.expectedErr, err)
+		}
+		if err != nil && !cmp.Equal(res, patchTestBefore) {
+			t.Errorf("Testing ApplyPatch with %v. Expected the file to be left unchanged, got %v", pair.patch, res)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Named alignments, following the numeric keypad as in SSA/ASS.
+const (
+	AlignBottomLeft = iota + 1
+	AlignBottom
+	AlignBottomRight
+	AlignLeft
+	AlignMiddle
+	AlignRight
+	AlignTopLeft
+	AlignTop
+	AlignTopRight
+)
+
+// alignmentNames are the names accepted by ParseAlignment.
+var alignmentNames = map[string]int{
+	"bottom-left": AlignBottomLeft, "bottom": AlignBottom, "bottom-right": AlignBottomRight,
+	"left": AlignLeft, "middle": AlignMiddle, "right": AlignRight,
+	"top-left": AlignTopLeft, "top": AlignTop, "top-right": AlignTopRight,
+}
+
+// ParseAlignment reads the name of an alignment, eg. "top" or "bottom-left".
+func ParseAlignment(name string) (int, error) {
+	if a, ok := alignmentNames[strings.ToLower(name)]; ok {
+		return a, nil
+	}
+	return 0, errors.New("Unknown alignment :`" + name + "`")
+}
+
+// Position places a subtitle on screen, independently of the format.
+// Alignment follows the numeric keypad, eg. AlignTop, with 0 meaning the
+// default bottom center. When Explicit is set, the point of the subtitle
+// given by its Alignment is placed X% across and Y% down the video.
+type Position struct {
+	Alignment int
+	X         float64
+	Y         float64
+	Explicit  bool
+}
+
+// The script resolution SSA/ASS positions refer to. It's also the one
+// assumed by renderers for {\pos} overrides in SRT files.
+const (
+	assPlayResX = 384
+	assPlayResY = 288
+)
+
+// row returns 0 for the bottom row, 1 for the middle one and 2 for the top.
+func (p Position) row() int {
+	if p.Alignment < 1 || p.Alignment > 9 {
+		return 0
+	}
+	return (p.Alignment - 1) / 3
+}
+
+// column returns 0 for the left column, 1 for the center and 2 for the right.
+func (p Position) column() int {
+	if p.Alignment < 1 || p.Alignment > 9 {
+		return 1
+	}
+	return (p.Alignment - 1) % 3
+}
+
+// formatDecimal formats a number with at most two decimals.
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// assOverrides returns the SSA/ASS overrides placing a subtitle, eg. \an8.
+func (p Position) assOverrides() string {
+	res := ""
+	if p.Alignment != 0 {
+		res = `\an` + strconv.Itoa(p.Alignment)
+	}
+	if p.Explicit {
+		res += `\pos(` + formatDecimal(p.X*assPlayResX/100) + "," + formatDecimal(p.Y*assPlayResY/100) + ")"
+	}
+	return res
+}
+
+// parseASSPos reads the arguments of an ASS \pos override, eg. "192,20",
+// as percentages of the script resolution.
+func parseASSPos(args string) (float64, float64, bool) {
+	xy := strings.Split(args, ",")
+	if len(xy) != 2 {
+		return 0, 0, false
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(xy[0]), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(xy[1]), 64)
+	if errX != nil || errY != nil {
+		return 0, 0, false
+	}
+	return x * 100 / assPlayResX, y * 100 / assPlayResY, true
+}
+
+// WebVTTSettings returns the WebVTT cue settings placing a subtitle, eg.
+// "line:0", or an empty string for the default position.
+func (p Position) WebVTTSettings() string {
+	var settings []string
+	if p.Explicit {
+		line := "line:" + formatDecimal(p.Y) + "%"
+		switch p.row() {
+		case 0:
+			line += ",end"
+		case 1:
+			line += ",center"
+		}
+		settings = append(settings, line, "position:"+formatDecimal(p.X)+"%")
+	} else {
+		switch p.row() {
+		case 1:
+			settings = append(settings, "line:50%,center")
+		case 2:
+			settings = append(settings, "line:0")
+		}
+	}
+	switch p.column() {
+	case 0:
+		settings = append(settings, "align:start")
+	case 2:
+		settings = append(settings, "align:end")
+	}
+	return strings.Join(settings, " ")
+}
+
+// ParseWebVTTSettings reads the placement out of WebVTT cue settings, eg.
+// "line:0 align:start". Line numbers only decide between top and bottom,
+// while a line percentage places the subtitle explicitly.
+func ParseWebVTTSettings(settings string) Position {
+	row, column := 0, 1
+	var p Position
+	xSet := false
+	for _, setting := range strings.Fields(settings) {
+		kv := strings.SplitN(setting, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value := strings.Split(kv[1], ",")
+		switch kv[0] {
+		case "line":
+			if strings.HasSuffix(value[0], "%") {
+				y, err := strconv.ParseFloat(strings.TrimSuffix(value[0], "%"), 64)
+				if err != nil {
+					continue
+				}
+				p.Y, p.Explicit, row = y, true, 2
+				if len(value) > 1 && value[1] == "center" {
+					row = 1
+				} else if len(value) > 1 && value[1] == "end" {
+					row = 0
+				}
+			} else if n, err := strconv.Atoi(value[0]); err == nil {
+				row = 0
+				if n >= 0 {
+					row = 2
+				}
+			}
+		case "position":
+			if x, err := strconv.ParseFloat(strings.TrimSuffix(value[0], "%"), 64); err == nil {
+				p.X, xSet = x, true
+			}
+		case "align":
+			switch value[0] {
+			case "start", "left":
+				column = 0
+			case "end", "right":
+				column = 2
+			default:
+				column = 1
+			}
+		}
+	}
+	if p.Explicit && !xSet {
+		p.X = 50
+	}
+	if !p.Explicit {
+		p.X = 0
+	}
+	if row != 0 || column != 1 || p.Explicit {
+		p.Alignment = row*3 + column + 1
+	}
+	return p
+}
+
+// positionOverrideRegexp matches the SSA/ASS overrides placing a subtitle.
+var positionOverrideRegexp = regexp.MustCompile(`\\(?:an[1-9]|pos\([^)]*\))`)
+
+// Position returns where the subtitle is placed on screen.
+func (s Subtitle) Position() Position {
+	return ParseStyledText(s.Content).Position
+}
+
+// WithPosition returns a copy of the subtitle placed at pos, replacing any
+// previous placement, and leaving the rest of its content untouched.
+func (s Subtitle) WithPosition(pos Position) Subtitle {
+	content := assTagRegexp.ReplaceAllStringFunc(s.Content, func(block string) string {
+		block = positionOverrideRegexp.ReplaceAllString(block, "")
+		if block == "{}" {
+			return ""
+		}
+		return block
+	})
+	if pos.Alignment == AlignBottom && !pos.Explicit {
+		pos.Alignment = 0
+	}
+	if overrides := pos.assOverrides(); overrides != "" {
+		content = "{" + overrides + "}" + content
+	}
+	s.Content = content
+	return s
+}
+
+// MoveSubtitles places every subtitle shown between from and to at pos, eg.
+// to move them to the top while there's text on screen, where a zero to
+// leaves the range open. It returns the Index of every moved subtitle.
+func MoveSubtitles(subfile SubtitleFile, from, to time.Duration, pos Position) (SubtitleFile, []int) {
+	var moved []int
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	for i, sub := range res.Subtitles {
+		if sub.End <= from || (to != 0 && sub.Start >= to) {
+			continue
+		}
+		placed := sub.WithPosition(pos)
+		if placed.Content == sub.Content {
+			continue
+		}
+		res.Subtitles[i] = placed
+		moved = append(moved, sub.Index)
+	}
+	return res, moved
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSubtitlePosition(t *testing.T) {
+	type testpair struct {
+		input    string
+		expected Position
+	}
+
+	var tests = []testpair{
+		{"Bottom center", Position{}},
+		{`{\an8}Top center`, Position{Alignment: AlignTop}},
+		{`{\i1\an7}Top left{\i0}`, Position{Alignment: AlignTopLeft}},
+		{`{\an5\pos(192,144)}Center of the screen`, Position{AlignMiddle, 50, 50, true}},
+	}
+
+	for _, pair := range tests {
+		sub := Subtitle{1, 0, time.Second, pair.input, "", ""}
+		if res := sub.Position(); !cmp.Equal(res, pair.expected) {
+			t.Error("For", pair.input, "expected", pair.expected, "got", res)
+		}
+	}
+}
+
+func TestWithPosition(t *testing.T) {
+	type testpair struct {
+		input    string
+		pos      Position
+		expected string
+	}
+
+	var tests = []testpair{
+		{"Move me", Position{Alignment: AlignTop}, `{\an8}Move me`},
+		{`{\an8}Back down`, Position{Alignment: AlignBottom}, "Back down"},
+		{`{\an8}<i>Back down</i>`, Position{}, "<i>Back down</i>"},
+		{`{\an7\i1}Keep italics{\i0}`, Position{Alignment: AlignTopRight}, `{\an9}{\i1}Keep italics{\i0}`},
+		{`{\pos(10,10)}Explicit`, Position{AlignBottom, 50, 90, true}, `{\an2\pos(192,259.2)}Explicit`},
+	}
+
+	for _, pair := range tests {
+		sub := Subtitle{1, 0, time.Second, pair.input, "", ""}
+		if res := sub.WithPosition(pair.pos).Content; res != pair.expected {
+			t.Error("For", pair.input, "expected", pair.expected, "got", res)
+		}
+	}
+}
+
+func TestWebVTTSettings(t *testing.T) {
+	type testpair struct {
+		pos      Position
+		settings string
+	}
+
+	var tests = []testpair{
+		{Position{}, ""},
+		{Position{Alignment: AlignTop}, "line:0"},
+		{Position{Alignment: AlignBottomLeft}, "align:start"},
+		{Position{Alignment: AlignTopRight}, "line:0 align:end"},
+		{Position{AlignBottom, 50, 90, true}, "line:90%,end position:50%"},
+		{Position{AlignTopLeft, 12.5, 10, true}, "line:10% position:12.5% align:start"},
+	}
+
+	for _, pair := range tests {
+		if res := pair.pos.WebVTTSettings(); res != pair.settings {
+			t.Error("For", pair.pos, "expected settings", pair.settings, "got", res)
+		}
+		if res := ParseWebVTTSettings(pair.settings); !cmp.Equal(res, pair.pos) {
+			t.Error("For", pair.settings, "expected", pair.pos, "got", res)
+		}
+	}
+
+	// WebVTT can only place subtitles in the middle with a percentage
+	if res := (Position{Alignment: AlignMiddle}).WebVTTSettings(); res != "line:50%,center" {
+		t.Error("Expected settings line:50%,center, got", res)
+	}
+	if res := ParseWebVTTSettings("line:-1 position:30% size:50%"); !cmp.Equal(res, Position{}) {
+		t.Error("Expected the default position, got", res)
+	}
+}
+
+func TestMoveSubtitles(t *testing.T) {
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Before`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `During`, "", ""},
+			{3, time.Duration(time.Second * 8), time.Duration(time.Second * 10), `{\an8}Already at the top`, "", ""},
+			{4, time.Duration(time.Second * 11), time.Duration(time.Second * 12), `After`, "", ""},
+		},
+		"",
+	}
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Before`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `{\an8}During`, "", ""},
+			{3, time.Duration(time.Second * 8), time.Duration(time.Second * 10), `{\an8}Already at the top`, "", ""},
+			{4, time.Duration(time.Second * 11), time.Duration(time.Second * 12), `After`, "", ""},
+		},
+		"",
+	}
+
+	res, moved := MoveSubtitles(input, 3*time.Second, 11*time.Second, Position{Alignment: AlignTop})
+	if !cmp.Equal(res, expected) || !cmp.Equal(moved, []int{2}) {
+		t.Error("Expected", expected, "with subtitle 2 moved, got", res, moved)
+	}
+	if input.Subtitles[1].Content != `During` {
+		t.Error("The input subtitle file was modified")
+	}
+}
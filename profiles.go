@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+// StyleProfile bundles the limits of a timed-text style guide, such as
+// the ones broadcasters hand out to subtitle vendors. MinGapFrames is
+// converted to a duration using FPS, which defaults to 25.
+type StyleProfile struct {
+	Name          string
+	Description   string
+	MaxLineLength int
+	MaxLines      int
+	MaxCPS        float64
+	MinDuration   time.Duration
+	MaxDuration   time.Duration
+	MinGapFrames  int
+	FPS           float64
+}
+
+// BuiltinProfiles returns the profiles shipped with gophersub, by name.
+func BuiltinProfiles() map[string]StyleProfile {
+	return map[string]StyleProfile{
+		"adult": {
+			Name:          "adult",
+			Description:   "Adult programming : 42 characters, 2 lines, 17 CPS, 5/6s to 7s, 2-frame gaps",
+			MaxLineLength: 42,
+			MaxLines:      2,
+			MaxCPS:        17,
+			MinDuration:   time.Second * 5 / 6,
+			MaxDuration:   7 * time.Second,
+			MinGapFrames:  2,
+			FPS:           25,
+		},
+		"children": {
+			Name:          "children",
+			Description:   "Children's programming : 42 characters, 2 lines, 13 CPS, 5/6s to 7s, 2-frame gaps",
+			MaxLineLength: 42,
+			MaxLines:      2,
+			MaxCPS:        13,
+			MinDuration:   time.Second * 5 / 6,
+			MaxDuration:   7 * time.Second,
+			MinGapFrames:  2,
+			FPS:           25,
+		},
+	}
+}
+
+// LookupProfile returns a built-in profile, or reads a user-defined one
+// if no built-in profile goes by that name, see ParseProfileFile.
+func LookupProfile(name string) (StyleProfile, error) {
+	if profile, ok := BuiltinProfiles()[name]; ok {
+		return profile, nil
+	}
+	return ParseProfileFile(name)
+}
+
+// ParseProfileFile reads a user-defined profile from a JSON file such as
+//
+//	{"name": "client-x", "max_line_length": 37, "max_lines": 2, "max_cps": 15,
+//	 "min_duration": "1s", "max_duration": "6s", "min_gap_frames": 3, "fps": 23.976}
+//
+// Limits missing from the file are not checked.
+func ParseProfileFile(filename string) (StyleProfile, error) {
+	var res StyleProfile
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return res, errors.New("Could not open file " + filename + " for reading")
+	}
+	var aux struct {
+		Name          string  `json:"name"`
+		Description   string  `json:"description"`
+		MaxLineLength int     `json:"max_line_length"`
+		MaxLines      int     `json:"max_lines"`
+		MaxCPS        float64 `json:"max_cps"`
+		MinDuration   string  `json:"min_duration"`
+		MaxDuration   string  `json:"max_duration"`
+		MinGapFrames  int     `json:"min_gap_frames"`
+		FPS           float64 `json:"fps"`
+	}
+	if err := json.Unmarshal(content, &aux); err != nil {
+		return res, errors.New("Could not parse profile " + filename + " : " + err.Error())
+	}
+	res = StyleProfile{aux.Name, aux.Description, aux.MaxLineLength, aux.MaxLines, aux.MaxCPS, 0, 0, aux.MinGapFrames, aux.FPS}
+	if res.Name == "" {
+		res.Name = filename
+	}
+	if aux.MinDuration != "" {
+		if res.MinDuration, err = StrToDuration(aux.MinDuration); err != nil {
+			return res, errors.New("Invalid duration :" + aux.MinDuration)
+		}
+	}
+	if aux.MaxDuration != "" {
+		if res.MaxDuration, err = StrToDuration(aux.MaxDuration); err != nil {
+			return res, errors.New("Invalid duration :" + aux.MaxDuration)
+		}
+	}
+	if res.FPS < 0 || res.MinGapFrames < 0 {
+		return res, errors.New("The frame rate and minimum gap of a profile should not be negative")
+	}
+	return res, nil
+}
+
+// LintConfig returns the linter configuration enforcing the profile's limits.
+func (p StyleProfile) LintConfig() LintConfig {
+	fps := p.FPS
+	if fps <= 0 {
+		fps = 25
+	}
+	return LintConfig{
+		MaxCPS:        p.MaxCPS,
+		MaxLineLength: p.MaxLineLength,
+		MaxLines:      p.MaxLines,
+		Rules:         map[string]string{},
+		MinDuration:   p.MinDuration,
+		MaxDuration:   p.MaxDuration,
+		MinGap:        FramesToDuration(p.MinGapFrames, fps),
+	}
+}
+
+// CueCompliance lists the issues found with a single subtitle.
+type CueCompliance struct {
+	Position int
+	Index    int
+	Start    time.Duration
+	End      time.Duration
+	Issues   []LintIssue
+}
+
+// Compliant reports whether the subtitle has no warnings or errors;
+// informational issues do not affect compliance.
+func (c CueCompliance) Compliant() bool {
+	for _, issue := range c.Issues {
+		if issue.Severity >= SeverityWarning {
+			return false
+		}
+	}
+	return true
+}
+
+// ComplianceReport is the result of validating a subtitle file against a
+// StyleProfile, with an entry for every subtitle in the file.
+type ComplianceReport struct {
+	Profile    string
+	Cues       []CueCompliance
+	FileIssues []LintIssue
+}
+
+// Compliant reports whether every subtitle in the file complies with the profile.
+func (r ComplianceReport) Compliant() bool {
+	for _, issue := range r.FileIssues {
+		if issue.Severity >= SeverityWarning {
+			return false
+		}
+	}
+	return r.CompliantCues() == len(r.Cues)
+}
+
+// CompliantCues returns the number of subtitles that comply with the profile.
+func (r ComplianceReport) CompliantCues() int {
+	count := 0
+	for _, cue := range r.Cues {
+		if cue.Compliant() {
+			count++
+		}
+	}
+	return count
+}
+
+// Write prints the report, one line per subtitle followed by its issues.
+func (r ComplianceReport) Write(w io.Writer) {
+	fmt.Fprintf(w, "Profile : %v\n", r.Profile)
+	for _, issue := range r.FileIssues {
+		fmt.Fprintf(w, "    %v\n", issue)
+	}
+	for _, cue := range r.Cues {
+		status := "OK"
+		if !cue.Compliant() {
+			status = "FAIL"
+		}
+		fmt.Fprintf(w, "#%d\t%v --> %v\t%v\n", cue.Position, DurationToTimestampSRT(cue.Start), DurationToTimestampSRT(cue.End), status)
+		for _, issue := range cue.Issues {
+			fmt.Fprintf(w, "    %v: %v [%v]\n", issue.Severity, issue.Message, issue.Rule)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d subtitles comply with the profile\n", r.CompliantCues(), len(r.Cues))
+}
+
+// ValidateProfile checks a subtitle file against a style profile,
+// using the linter rules, and groups the issues found per subtitle.
+func ValidateProfile(subfile SubtitleFile, profile StyleProfile) ComplianceReport {
+	res := ComplianceReport{Profile: profile.Name}
+	for i, sub := range subfile.Subtitles {
+		res.Cues = append(res.Cues, CueCompliance{i + 1, sub.Index, sub.Start, sub.End, nil})
+	}
+	for _, issue := range Lint(subfile, DefaultLintRules(), profile.LintConfig()) {
+		if issue.Position <= 0 || issue.Position > len(res.Cues) {
+			res.FileIssues = append(res.FileIssues, issue)
+			continue
+		}
+		cue := &res.Cues[issue.Position-1]
+		cue.Issues = append(cue.Issues, issue)
+	}
+	return res
+}
+
+// profileNames returns the names of the built-in profiles, sorted.
+func profileNames() []string {
+	var names []string
+	for name := range BuiltinProfiles() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
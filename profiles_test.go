@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLookupProfile(t *testing.T) {
+	type testpair struct {
+		input       string
+		expected    StyleProfile
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{"adult", BuiltinProfiles()["adult"], nil},
+		{
+			"samples/profile_client.json",
+			StyleProfile{"client-x", "Client X deliveries", 37, 2, 15, time.Second, 6 * time.Second, 3, 23.976},
+			nil,
+		},
+		{"broadcaster", StyleProfile{}, errors.New("Could not open file broadcaster for reading")},
+	}
+
+	for _, pair := range tests {
+		actual, err := LookupProfile(pair.input)
+		if pair.expectedErr != nil {
+			if err == nil || err.Error() != pair.expectedErr.Error() {
+				t.Errorf("Testing LookupProfile with %v. Expected error %v but got %v instead!", pair.input, pair.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil || !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing LookupProfile with %v. Expected %v but got %v (%v) instead!", pair.input, pair.expected, actual, err)
+		}
+	}
+}
+
+func TestStyleProfileLintConfig(t *testing.T) {
+	expected := LintConfig{
+		MaxCPS:        13,
+		MaxLineLength: 42,
+		MaxLines:      2,
+		Rules:         map[string]string{},
+		MinDuration:   time.Second * 5 / 6,
+		MaxDuration:   7 * time.Second,
+		MinGap:        80 * time.Millisecond,
+	}
+	actual := BuiltinProfiles()["children"].LintConfig()
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("Testing StyleProfile.LintConfig. Expected %v but got %v instead!", expected, actual)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Fine`, "", ""},
+			{2, time.Duration(time.Second*3 + time.Millisecond*40), time.Duration(time.Second*3 + time.Millisecond*500), `Short`, "", ""},
+			{3, time.Duration(time.Second * 5), time.Duration(time.Second * 13), `Long`, "", ""},
+			{4, time.Duration(time.Second * 14), time.Duration(time.Second * 15), `This line is definitely longer than forty-two characters`, "", ""},
+		},
+		"",
+	}
+	expected := `Profile : adult
+#1	00:00:01,000 --> 00:00:03,000	OK
+#2	00:00:03,040 --> 00:00:03,500	FAIL
+    warning: Subtitle is shown for 460ms, under 833.333333ms [min-duration]
+    warning: Gap to the previous subtitle is 40ms, under 80ms [min-gap]
+#3	00:00:05,000 --> 00:00:13,000	FAIL
+    warning: Subtitle is shown for 8s, over 7s [max-duration]
+#4	00:00:14,000 --> 00:00:15,000	FAIL
+    warning: Reading speed is 56.0 characters per second, over 17 [reading-speed]
+    warning: Line 1 has 56 characters, over 42 [line-length]
+1 of 4 subtitles comply with the profile
+`
+
+	report := ValidateProfile(input, BuiltinProfiles()["adult"])
+	if report.Compliant() || report.CompliantCues() != 1 || len(report.Cues) != 4 {
+		t.Errorf("Testing ValidateProfile. Expected 1 of 4 compliant subtitles, but got %v", report)
+	}
+	var out bytes.Buffer
+	report.Write(&out)
+	if out.String() != expected {
+		t.Errorf("Testing ValidateProfile. Expected report\n%v\nbut got\n%v\ninstead!", expected, out.String())
+	}
+
+	report = ValidateProfile(SubtitleFile{input.Subtitles[:1], ""}, BuiltinProfiles()["adult"])
+	if !report.Compliant() {
+		t.Errorf("Testing ValidateProfile. Expected a compliant file, but got %v", report)
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := runCLI([]string{"validate", "-profile", "adult", "samples/sample.srt"}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("Testing validate command. Expected exit code 1 but got %v instead (%v)", code, stderr.String())
+	}
+	for _, expected := range []string{"#3\t00:00:10,088 --> 00:00:14,500\tFAIL\n", "4 of 5 subtitles comply with the profile\n"} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("Testing validate command. Expected output to contain %q but got\n%v", expected, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	code = runCLI([]string{"validate", "-profiles"}, &stdout, &stderr)
+	if code != 0 || !strings.HasPrefix(stdout.String(), "adult ") {
+		t.Errorf("Testing validate command listing profiles. Got exit code %v and output %v", code, stdout.String())
+	}
+}
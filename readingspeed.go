@@ -0,0 +1,183 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ReadingOptions selects what counts as a character when measuring
+// reading speed. Markup and line breaks are never counted.
+type ReadingOptions struct {
+	IgnorePunctuation bool
+	IgnoreSpaces      bool
+}
+
+// ReadingSpeed is the measured reading speed of a single subtitle.
+// Subtitles without running time have a zero CPS and WPM.
+type ReadingSpeed struct {
+	Index    int
+	Chars    int
+	Words    int
+	Duration time.Duration
+	CPS      float64
+	WPM      float64
+}
+
+// CPM returns the reading speed in characters per minute.
+func (r ReadingSpeed) CPM() float64 {
+	return r.CPS * 60
+}
+
+// assTagRegexp matches SSA/ASS override blocks, eg. {\an8} or {\i1}
+var assTagRegexp = regexp.MustCompile(`\{\\[^}]*\}`)
+
+// StripMarkup removes HTML-like formatting tags, SSA/ASS override blocks and
+// ruby annotations, leaving the text a viewer actually reads.
+func StripMarkup(content string) string {
+	return ParseStyledText(content).PlainText()
+}
+
+const (
+	zeroWidthJoiner    = '\u200d'
+	regionalIndicatorA = '\U0001F1E6'
+	regionalIndicatorZ = '\U0001F1FF'
+)
+
+// extendsGrapheme reports whether r continues the grapheme cluster before it,
+// such as a combining accent, a variation selector or a skin-tone modifier.
+func extendsGrapheme(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		unicode.Is(unicode.Variation_Selector, r) ||
+		(r >= 0x1F3FB && r <= 0x1F3FF) ||
+		r == zeroWidthJoiner
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorA && r <= regionalIndicatorZ
+}
+
+// Graphemes splits a string into user-perceived characters. It follows the
+// common cases of Unicode text segmentation : combining marks, emoji
+// modifiers and ZWJ sequences, flags made of regional indicators and CRLF.
+func Graphemes(s string) []string {
+	var res []string
+	start := 0
+	var prev rune
+	regionalRun := 0
+	for i, r := range s {
+		if i > 0 {
+			joined := extendsGrapheme(r) ||
+				prev == zeroWidthJoiner ||
+				(prev == '\r' && r == '\n') ||
+				(isRegionalIndicator(prev) && isRegionalIndicator(r) && regionalRun%2 == 1)
+			if !joined {
+				res = append(res, s[start:i])
+				start = i
+			}
+		}
+		if isRegionalIndicator(r) {
+			regionalRun++
+		} else {
+			regionalRun = 0
+		}
+		prev = r
+	}
+	if start < len(s) {
+		res = append(res, s[start:])
+	}
+	return res
+}
+
+// CountGraphemes returns the number of user-perceived characters in a string.
+func CountGraphemes(s string) int {
+	return len(Graphemes(s))
+}
+
+// CountReadingChars returns the number of characters a viewer has to read
+// in the content of a subtitle, not counting markup or line breaks.
+func CountReadingChars(content string, opts ReadingOptions) int {
+	count := 0
+	for _, g := range Graphemes(StripMarkup(content)) {
+		r, _ := utf8.DecodeRuneInString(g)
+		switch {
+		case r == '\n' || r == '\r':
+			continue
+		case opts.IgnoreSpaces && unicode.IsSpace(r):
+			continue
+		case opts.IgnorePunctuation && (unicode.IsPunct(r) || unicode.IsSymbol(r)):
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// CountWords returns the number of words in the content of a subtitle.
+func CountWords(content string) int {
+	count := 0
+	for _, field := range strings.Fields(StripMarkup(content)) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// MeasureReadingSpeed returns the reading speed of a single subtitle.
+func MeasureReadingSpeed(sub Subtitle, opts ReadingOptions) ReadingSpeed {
+	res := ReadingSpeed{sub.Index, CountReadingChars(sub.Content, opts), CountWords(sub.Content), sub.End - sub.Start, 0, 0}
+	if res.Duration > 0 {
+		res.CPS = float64(res.Chars) / res.Duration.Seconds()
+		res.WPM = float64(res.Words) / res.Duration.Minutes()
+	}
+	return res
+}
+
+// ReadingStats summarizes the reading speed over a whole subtitle file.
+// Subtitles without running time are counted in Untimed, and are left out
+// of the fastest, slowest and average speeds.
+type ReadingStats struct {
+	Fastest    ReadingSpeed
+	Slowest    ReadingSpeed
+	AverageCPS float64
+	AverageWPM float64
+	Runtime    time.Duration
+	Untimed    int
+}
+
+// AverageCPM returns the average reading speed in characters per minute.
+func (s ReadingStats) AverageCPM() float64 {
+	return s.AverageCPS * 60
+}
+
+// MeasureReadingStats returns the fastest and slowest subtitles, along with
+// the average reading speed, weighted by how long each subtitle is shown.
+func MeasureReadingStats(subfile SubtitleFile, opts ReadingOptions) ReadingStats {
+	var res ReadingStats
+	chars, words, timed := 0, 0, 0
+	for _, sub := range subfile.Subtitles {
+		speed := MeasureReadingSpeed(sub, opts)
+		if speed.Duration <= 0 {
+			res.Untimed++
+			continue
+		}
+		if timed == 0 || speed.CPS > res.Fastest.CPS {
+			res.Fastest = speed
+		}
+		if timed == 0 || speed.CPS < res.Slowest.CPS {
+			res.Slowest = speed
+		}
+		timed++
+		chars += speed.Chars
+		words += speed.Words
+		res.Runtime += speed.Duration
+	}
+	if res.Runtime > 0 {
+		res.AverageCPS = float64(chars) / res.Runtime.Seconds()
+		res.AverageWPM = float64(words) / res.Runtime.Minutes()
+	}
+	return res
+}
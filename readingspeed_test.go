@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGraphemes(t *testing.T) {
+	type testpair struct {
+		input    string
+		expected []string
+	}
+
+	var noGraphemes []string
+	var tests = []testpair{
+		{"", noGraphemes},
+		{"Γειά", []string{"Γ", "ε", "ι", "ά"}},
+		{"Γειa\u0301", []string{"Γ", "ε", "ι", "a\u0301"}},
+		{"a\r\nb", []string{"a", "\r\n", "b"}},
+		{"👍🏽!", []string{"👍🏽", "!"}},
+		{"👩\u200d👧", []string{"👩\u200d👧"}},
+		{"🇬🇷🇮🇹", []string{"🇬🇷", "🇮🇹"}},
+	}
+
+	for _, pair := range tests {
+		res := Graphemes(pair.input)
+		if !cmp.Equal(res, pair.expected) {
+			t.Errorf("Error in Graphemes(%q) : expected %q, got %q", pair.input, pair.expected, res)
+		}
+	}
+}
+
+func TestCountReadingChars(t *testing.T) {
+	type testpair struct {
+		input    string
+		opts     ReadingOptions
+		expected int
+	}
+
+	var tests = []testpair{
+		{`Έχουμε όλοι υποφέρει.`, ReadingOptions{}, 21},
+		{`Έχουμε όλοι υποφέρει.`, ReadingOptions{IgnorePunctuation: true}, 20},
+		{`Έχουμε όλοι υποφέρει.`, ReadingOptions{IgnoreSpaces: true}, 19},
+		{"<i>Fine</i>\n<font color=\"red\">line</font>", ReadingOptions{}, 8},
+		{`{\an8}Top - "line"`, ReadingOptions{IgnorePunctuation: true, IgnoreSpaces: true}, 7},
+		{"", ReadingOptions{}, 0},
+	}
+
+	for _, pair := range tests {
+		res := CountReadingChars(pair.input, pair.opts)
+		if res != pair.expected {
+			t.Errorf("Error in CountReadingChars(%q, %+v) : expected %d, got %d", pair.input, pair.opts, pair.expected, res)
+		}
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	type testpair struct {
+		input    string
+		expected int
+	}
+
+	var tests = []testpair{
+		{`Αυτό δεν αφορά τους Οίκους των ευγενών,
+αλλά τους ζωντανούς και τους νεκρούς.`, 13},
+		{`<i>Wait</i> - what?`, 2},
+		{`...`, 0},
+		{"", 0},
+	}
+
+	for _, pair := range tests {
+		res := CountWords(pair.input)
+		if res != pair.expected {
+			t.Errorf("Error in CountWords(%q) : expected %d, got %d", pair.input, pair.expected, res)
+		}
+	}
+}
+
+func TestMeasureReadingSpeed(t *testing.T) {
+	type testpair struct {
+		input    Subtitle
+		expected ReadingSpeed
+	}
+
+	var tests = []testpair{
+		{
+			Subtitle{1, time.Second, 3 * time.Second, `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+			ReadingSpeed{1, 23, 5, 2 * time.Second, 11.5, 150},
+		},
+		{
+			Subtitle{2, time.Second, time.Second, `Zero duration`, "", ""},
+			ReadingSpeed{2, 13, 2, 0, 0, 0},
+		},
+		{
+			Subtitle{3, 2 * time.Second, time.Second, `Backwards`, "", ""},
+			ReadingSpeed{3, 9, 1, -time.Second, 0, 0},
+		},
+	}
+
+	for _, pair := range tests {
+		res := MeasureReadingSpeed(pair.input, ReadingOptions{})
+		if !cmp.Equal(res, pair.expected) {
+			t.Errorf("Error in MeasureReadingSpeed(%v) : expected %+v, got %+v", pair.input, pair.expected, res)
+		}
+	}
+}
+
+func TestMeasureReadingStats(t *testing.T) {
+	in := SubtitleFile{
+		[]Subtitle{
+			{1, 0, 2 * time.Second, `Slow one`, "", ""},
+			{2, 2 * time.Second, 2 * time.Second, `Untimed`, "", ""},
+			{3, 3 * time.Second, 4 * time.Second, `Much faster`, "", ""},
+		},
+		"",
+	}
+	expected := ReadingStats{
+		Fastest:    ReadingSpeed{3, 11, 2, time.Second, 11, 120},
+		Slowest:    ReadingSpeed{1, 8, 2, 2 * time.Second, 4, 60},
+		AverageCPS: 19. / 3,
+		AverageWPM: 80,
+		Runtime:    3 * time.Second,
+		Untimed:    1,
+	}
+
+	res := MeasureReadingStats(in, ReadingOptions{})
+	if !cmp.Equal(res, expected) {
+		t.Errorf("Error in MeasureReadingStats : expected %+v, got %+v", expected, res)
+	}
+
+	empty := MeasureReadingStats(SubtitleFile{}, ReadingOptions{})
+	if !cmp.Equal(empty, ReadingStats{}) {
+		t.Errorf("Error in MeasureReadingStats of an empty file : got %+v", empty)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LineShape selects how ReflowText distributes words between lines.
+type LineShape int
+
+const (
+	// BottomHeavy keeps upper lines no longer than the ones below them,
+	// the "pyramid" shape most style guides ask for
+	BottomHeavy LineShape = iota
+	// Balanced makes all lines as close in length as possible
+	Balanced
+)
+
+// ReflowOptions configures ReflowText. Subtitles are kept on as few lines
+// as possible, at most MaxLines lines of at most MaxLineLength columns.
+type ReflowOptions struct {
+	MaxLines      int
+	MaxLineLength int
+	Shape         LineShape
+}
+
+// isWide reports whether a character takes up two columns,
+// following the East Asian Wide and Fullwidth classes.
+func isWide(r rune) bool {
+	switch {
+	case r >= 0x1100 && r <= 0x115F,
+		r >= 0x2E80 && r <= 0x303E,
+		r >= 0x3041 && r <= 0x33FF,
+		r >= 0x3400 && r <= 0x4DBF,
+		r >= 0x4E00 && r <= 0x9FFF,
+		r >= 0xA000 && r <= 0xA4CF,
+		r >= 0xAC00 && r <= 0xD7A3,
+		r >= 0xF900 && r <= 0xFAFF,
+		r >= 0xFE30 && r <= 0xFE4F,
+		r >= 0xFF00 && r <= 0xFF60,
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F,
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD:
+		return true
+	}
+	return false
+}
+
+// DisplayWidth returns the number of columns a line of subtitle text takes
+// on screen, where East Asian wide characters count twice and markup is ignored.
+func DisplayWidth(s string) int {
+	width := 0
+	for _, g := range Graphemes(StripMarkup(s)) {
+		r, _ := utf8.DecodeRuneInString(g)
+		if isWide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// Characters that may not start or end a line in Chinese and Japanese text (kinsoku)
+const (
+	noLineStart = "、。，．・：；？！」』）】〕〉》ー…ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮ々"
+	noLineEnd   = "「『（【〔〈《"
+)
+
+// breakBefore are the words a line is best broken before, conjunctions
+// and prepositions, which also should not be left dangling at the end of a line.
+var breakBefore = map[string]bool{
+	"and": true, "or": true, "but": true, "nor": true, "so": true, "yet": true,
+	"because": true, "if": true, "that": true, "which": true, "who": true, "when": true,
+	"while": true, "of": true, "to": true, "in": true, "on": true, "at": true,
+	"for": true, "with": true, "from": true, "by": true, "about": true, "into": true,
+	"the": true, "a": true, "an": true,
+	"και": true, "κι": true, "ή": true, "αλλά": true, "όμως": true, "ότι": true,
+	"που": true, "να": true, "σε": true, "από": true, "για": true, "με": true,
+	"ο": true, "η": true, "το": true, "οι": true, "τα": true, "των": true,
+	"τον": true, "την": true, "τους": true, "τις": true,
+}
+
+// reflowUnit is a piece of text that is never broken, usually a word
+// along with any tags attached to it, or a single East Asian character.
+// A unit starting a line of dialogue always starts a new line.
+type reflowUnit struct {
+	text        string
+	spaceBefore bool
+	width       int
+	lineStart   bool
+}
+
+// markupAt returns the length of the tag starting at the beginning of s, or 0.
+func markupAt(s string) int {
+	for _, re := range []interface {
+		FindStringIndex(string) []int
+	}{tagRegexp, assTagRegexp} {
+		if loc := re.FindStringIndex(s); loc != nil && loc[0] == 0 {
+			return loc[1]
+		}
+	}
+	return 0
+}
+
+// reflowUnits splits subtitle text into the units lines are built from.
+// Line breaks between East Asian characters are dropped without a space,
+// while those before a line of dialogue, eg. "- Hello.", are kept.
+func reflowUnits(content string) []reflowUnit {
+	var res []reflowUnit
+	var cur strings.Builder
+	var sep string
+	var last rune
+	spaceBefore, visible, lineStart := false, false, false
+
+	flush := func() {
+		if cur.Len() > 0 {
+			res = append(res, reflowUnit{cur.String(), spaceBefore && len(res) > 0, DisplayWidth(cur.String()), lineStart && len(res) > 0})
+			cur.Reset()
+		}
+		visible = false
+	}
+	// start begins a new unit at i, after the whitespace read since the last one
+	start := func(r rune, i int) {
+		spaceBefore = sep != "" && !(strings.Trim(sep, "\n") == "" && isWide(last) && isWide(r))
+		line := content[i:]
+		if end := strings.Index(line, "\n"); end >= 0 {
+			line = line[:end]
+		}
+		lineStart = strings.Contains(sep, "\n") && isDialogue(line)
+		sep = ""
+	}
+
+	for i := 0; i < len(content); {
+		if n := markupAt(content[i:]); n > 0 {
+			if cur.Len() == 0 {
+				start(0, i)
+			}
+			cur.WriteString(content[i : i+n])
+			i += n
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(content[i:])
+		if unicode.IsSpace(r) {
+			flush()
+			sep += string(r)
+			i += n
+			continue
+		}
+		switch {
+		case cur.Len() == 0:
+			start(r, i)
+		case visible && (isWide(r) || isWide(last)) &&
+			!strings.ContainsRune(noLineStart, r) && !strings.ContainsRune(noLineEnd, last):
+			flush()
+			spaceBefore, lineStart = false, false
+		}
+		g := Graphemes(content[i:])[0]
+		cur.WriteString(g)
+		visible, last = true, r
+		i += len(g)
+	}
+	flush()
+	return res
+}
+
+// trimPunctuation strips the punctuation around a word, and lowercases it.
+func trimPunctuation(word string) string {
+	return strings.ToLower(strings.TrimFunc(StripMarkup(word), func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
+
+// breakPenalty rates breaking a line between two units, lower being better.
+func breakPenalty(prev, next reflowUnit) int {
+	text := strings.TrimRightFunc(StripMarkup(prev.text), func(r rune) bool { return r == '"' || r == '\'' || r == '»' })
+	last, _ := utf8.DecodeLastRuneInString(text)
+	switch {
+	case strings.ContainsRune(".!?…。！？", last):
+		return 0
+	case strings.ContainsRune(",;:、，；：", last):
+		return 10
+	case breakBefore[trimPunctuation(prev.text)]:
+		return 80
+	case breakBefore[trimPunctuation(next.text)] || strings.HasPrefix(StripMarkup(next.text), "-"):
+		return 20
+	}
+	return 40
+}
+
+// shapePenalty rates the lengths of two consecutive lines.
+func shapePenalty(upper, lower int, shape LineShape) int {
+	diff := upper - lower
+	if shape == BottomHeavy && diff > 0 {
+		return 3 * diff * diff
+	}
+	if shape == BottomHeavy {
+		return diff * diff / 4
+	}
+	return diff * diff
+}
+
+// lineWidth returns the width of the line made of units[from:to].
+func lineWidth(units []reflowUnit, from, to int) int {
+	width := 0
+	for i := from; i < to; i++ {
+		width += units[i].width
+		if i > from && units[i].spaceBefore {
+			width++
+		}
+	}
+	return width
+}
+
+// fitsLine reports whether units[from:to] can make up a single line, being
+// short enough and not running into the start of a line of dialogue.
+func fitsLine(units []reflowUnit, from, to int, opts ReflowOptions) bool {
+	for i := from + 1; i < to; i++ {
+		if units[i].lineStart {
+			return false
+		}
+	}
+	return lineWidth(units, from, to) <= opts.MaxLineLength
+}
+
+// lineBreaks is the best way found to split the units from a given one onwards.
+type lineBreaks struct {
+	breaks []int
+	cost   int
+	ok     bool
+}
+
+// bestBreaks returns the indices of the units starting each line after the
+// first, splitting units[from:] into exactly lines lines, along with its cost.
+// Results are memoized, keyed by from and lines.
+func bestBreaks(units []reflowUnit, from, lines int, opts ReflowOptions, memo map[[2]int]lineBreaks) lineBreaks {
+	if lines == 1 {
+		return lineBreaks{nil, 0, fitsLine(units, from, len(units), opts)}
+	}
+	if res, ok := memo[[2]int{from, lines}]; ok {
+		return res
+	}
+	var best lineBreaks
+	for cut := from + 1; cut <= len(units)-lines+1; cut++ {
+		if !fitsLine(units, from, cut, opts) {
+			break
+		}
+		width := lineWidth(units, from, cut)
+		rest := bestBreaks(units, cut, lines-1, opts, memo)
+		if !rest.ok {
+			continue
+		}
+		next := len(units)
+		if len(rest.breaks) > 0 {
+			next = rest.breaks[0]
+		}
+		cost := rest.cost + breakPenalty(units[cut-1], units[cut]) + shapePenalty(width, lineWidth(units, cut, next), opts.Shape)
+		if !best.ok || cost < best.cost {
+			best = lineBreaks{append([]int{cut}, rest.breaks...), cost, true}
+		}
+	}
+	memo[[2]int{from, lines}] = best
+	return best
+}
+
+// ReflowText rebalances the text of a subtitle into as few lines as
+// possible, preferring to break lines after punctuation and before
+// conjunctions and prepositions. Lines of dialogue starting with a dash
+// stay on lines of their own. Lines are never broken inside formatting
+// tags, and East Asian text is broken between characters, following the
+// kinsoku rules. The text is returned unchanged, along with an error, if it
+// cannot fit. Zero limits mean no limit.
+func ReflowText(content string, opts ReflowOptions) (string, error) {
+	if opts.MaxLines < 0 || opts.MaxLineLength < 0 {
+		return content, errors.New("The maximum number of lines and line length should not be negative")
+	}
+	units := reflowUnits(content)
+	if len(units) == 0 {
+		return content, nil
+	}
+	if opts.MaxLineLength == 0 {
+		opts.MaxLineLength = lineWidth(units, 0, len(units))
+	}
+	maxLines := opts.MaxLines
+	if maxLines == 0 || maxLines > len(units) {
+		maxLines = len(units)
+	}
+
+	for lines := 1; lines <= maxLines; lines++ {
+		best := bestBreaks(units, 0, lines, opts, map[[2]int]lineBreaks{})
+		if !best.ok {
+			continue
+		}
+		breaks := best.breaks
+		var b strings.Builder
+		for i, unit := range units {
+			switch {
+			case len(breaks) > 0 && breaks[0] == i:
+				b.WriteString("\n")
+				breaks = breaks[1:]
+			case unit.spaceBefore:
+				b.WriteString(" ")
+			}
+			b.WriteString(unit.text)
+		}
+		return b.String(), nil
+	}
+	if opts.MaxLines == 0 {
+		return content, errors.New("Text does not fit in lines of " + strconv.Itoa(opts.MaxLineLength) + " characters")
+	}
+	return content, errors.New("Text does not fit in " + strconv.Itoa(opts.MaxLines) + " lines of " + strconv.Itoa(opts.MaxLineLength) + " characters")
+}
+
+// ReflowSubtitleFile reflows the text of every subtitle in the file.
+// Subtitles that cannot fit are left unchanged, and reported as errors.
+func ReflowSubtitleFile(subfile SubtitleFile, opts ReflowOptions) (SubtitleFile, []error) {
+	var errs []error
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	for i := range res.Subtitles {
+		content, err := ReflowText(res.Subtitles[i].Content, opts)
+		if err != nil {
+			errs = append(errs, errors.New("Subtitle "+strconv.Itoa(res.Subtitles[i].Index)+" : "+err.Error()))
+			continue
+		}
+		res.Subtitles[i].Content = content
+	}
+	return res, errs
+}
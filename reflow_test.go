@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	type testpair struct {
+		input    string
+		expected int
+	}
+
+	var tests = []testpair{
+		{`Έχουμε όλοι υποφέρει.`, 21},
+		{`<i>Fine line</i>`, 9},
+		{`東京駅で`, 8},
+		{`ｆｕｌｌ width`, 14},
+		{"", 0},
+	}
+
+	for _, pair := range tests {
+		res := DisplayWidth(pair.input)
+		if res != pair.expected {
+			t.Errorf("Error in DisplayWidth(%q) : expected %d, got %d", pair.input, pair.expected, res)
+		}
+	}
+}
+
+func TestReflowText(t *testing.T) {
+	type testpair struct {
+		input       string
+		opts        ReflowOptions
+		expected    string
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{
+			"This is a long line that translators hand us, and it overflows the screen.",
+			ReflowOptions{2, 42, BottomHeavy},
+			"This is a long line that translators\nhand us, and it overflows the screen.",
+			nil,
+		},
+		{
+			"I told you that I would come back for the money and the car.",
+			ReflowOptions{2, 37, BottomHeavy},
+			"I told you that I would come\nback for the money and the car.",
+			nil,
+		},
+		{
+			"We need to leave now because the storm is coming",
+			ReflowOptions{2, 30, BottomHeavy},
+			"We need to leave now\nbecause the storm is coming",
+			nil,
+		},
+		{
+			"aaaaaa bbbb cccc ddd",
+			ReflowOptions{2, 16, BottomHeavy},
+			"aaaaaa\nbbbb cccc ddd",
+			nil,
+		},
+		{
+			"aaaaaa bbbb cccc ddd",
+			ReflowOptions{2, 16, Balanced},
+			"aaaaaa bbbb\ncccc ddd",
+			nil,
+		},
+		{
+			"- Hi there.\n- Hello.",
+			ReflowOptions{2, 42, BottomHeavy},
+			"- Hi there.\n- Hello.",
+			nil,
+		},
+		{
+			"- Who is\nthere?\n<i>- Only me.</i>",
+			ReflowOptions{2, 42, BottomHeavy},
+			"- Who is there?\n<i>- Only me.</i>",
+			nil,
+		},
+		{
+			"- Hi there.\n- Hello.",
+			ReflowOptions{1, 42, BottomHeavy},
+			"- Hi there.\n- Hello.",
+			errors.New("Text does not fit in 1 lines of 42 characters"),
+		},
+		{
+			"Αυτό δεν αφορά τους Οίκους\nτων ευγενών, αλλά τους ζωντανούς και τους νεκρούς.",
+			ReflowOptions{2, 42, BottomHeavy},
+			"Αυτό δεν αφορά τους Οίκους των ευγενών,\nαλλά τους ζωντανούς και τους νεκρούς.",
+			nil,
+		},
+		{
+			"<i>We have all suffered. We have all lost people we love.</i>",
+			ReflowOptions{2, 32, BottomHeavy},
+			"<i>We have all suffered.\nWe have all lost people we love.</i>",
+			nil,
+		},
+		{
+			`<font color="red">Stop</font> right there, you scoundrel!`,
+			ReflowOptions{2, 20, BottomHeavy},
+			"<font color=\"red\">Stop</font> right there,\nyou scoundrel!",
+			nil,
+		},
+		{
+			"「こんにちは」と彼は言った。",
+			ReflowOptions{2, 16, Balanced},
+			"「こんにちは」\nと彼は言った。",
+			nil,
+		},
+		{
+			"私は昨日\n東京駅で",
+			ReflowOptions{2, 40, Balanced},
+			"私は昨日東京駅で",
+			nil,
+		},
+		{
+			"one two three four five six",
+			ReflowOptions{0, 10, Balanced},
+			"one two\nthree four\nfive six",
+			nil,
+		},
+		{
+			"Short\none",
+			ReflowOptions{2, 42, BottomHeavy},
+			"Short one",
+			nil,
+		},
+		{
+			"Supercalifragilisticexpialidocious",
+			ReflowOptions{2, 10, BottomHeavy},
+			"Supercalifragilisticexpialidocious",
+			errors.New("Text does not fit in 2 lines of 10 characters"),
+		},
+		{
+			"Short one",
+			ReflowOptions{-1, 10, BottomHeavy},
+			"Short one",
+			errors.New("The maximum number of lines and line length should not be negative"),
+		},
+	}
+
+	for _, pair := range tests {
+		res, err := ReflowText(pair.input, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing ReflowText with %q. Expected error %v but got %v instead!", pair.input, pair.expectedErr, err)
+		}
+		if res != pair.expected {
+			t.Errorf("Testing ReflowText with %q and %+v. Expected %q but got %q instead!", pair.input, pair.opts, pair.expected, res)
+		}
+	}
+}
+
+func TestReflowSubtitleFile(t *testing.T) {
+	in := SubtitleFile{
+		[]Subtitle{
+			{1, time.Second, 3 * time.Second, `We have all suffered. We have all lost people we love.`, "", ""},
+			{2, 4 * time.Second, 6 * time.Second, `Unbreakablewordthatistoolongforanyline`, "", ""},
+		},
+		"headers",
+	}
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Second, 3 * time.Second, "We have all suffered.\nWe have all lost people we love.", "", ""},
+			{2, 4 * time.Second, 6 * time.Second, `Unbreakablewordthatistoolongforanyline`, "", ""},
+		},
+		"headers",
+	}
+	expectedErrs := []error{errors.New("Subtitle 2 : Text does not fit in 2 lines of 32 characters")}
+
+	res, errs := ReflowSubtitleFile(in, ReflowOptions{MaxLines: 2, MaxLineLength: 32})
+	if !cmp.Equal(res, expected) || !ErrorSlicesEqual(errs, expectedErrs) {
+		t.Errorf("Error in ReflowSubtitleFile : expected %v, %v, got %v, %v", expected, expectedErrs, res, errs)
+	}
+}
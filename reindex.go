@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ReindexOptions configures ReindexSubtitleFile.
+type ReindexOptions struct {
+	RemoveDuplicates bool
+}
+
+// IndexChange reports a subtitle that was out of order, along with its new Index.
+type IndexChange struct {
+	Index    int
+	NewIndex int
+}
+
+func (c IndexChange) String() string {
+	return "Subtitle " + strconv.Itoa(c.Index) + " moved to " + strconv.Itoa(c.NewIndex)
+}
+
+// Duplicate reports a subtitle with the same timing and text as an earlier one.
+type Duplicate struct {
+	Index       int
+	DuplicateOf int
+	Removed     bool
+}
+
+func (d Duplicate) String() string {
+	res := "Subtitle " + strconv.Itoa(d.Index) + " duplicates subtitle " + strconv.Itoa(d.DuplicateOf)
+	if d.Removed {
+		res += " (removed)"
+	}
+	return res
+}
+
+// inOrder returns the positions that are already in order, as the longest
+// increasing subsequence of positions, so that only the others count as moved.
+func inOrder(positions []int) map[int]bool {
+	// tails[k] is the index in positions of the smallest tail of an
+	// increasing subsequence of length k+1, and prev links them together
+	var tails []int
+	prev := make([]int, len(positions))
+	for i, p := range positions {
+		k := sort.Search(len(tails), func(k int) bool { return positions[tails[k]] >= p })
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	res := map[int]bool{}
+	if len(tails) == 0 {
+		return res
+	}
+	for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+		res[positions[i]] = true
+	}
+	return res
+}
+
+// ReindexSubtitleFile sorts the subtitles by their Start, then by their End,
+// keeping the original order of identical timings, and renumbers them. It
+// reports the subtitles that were out of order, as the fewest subtitles
+// that had to move, and the ones with the same timing and text as an
+// earlier one, which are dropped if RemoveDuplicates is set. The input
+// subtitle file is not modified.
+func ReindexSubtitleFile(subfile SubtitleFile, opts ReindexOptions) (SubtitleFile, []IndexChange, []Duplicate) {
+	var moved []IndexChange
+	var duplicates []Duplicate
+	order := make([]int, len(subfile.Subtitles))
+	for i := range order {
+		order[i] = i
+	}
+	subs := subfile.Subtitles
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := subs[order[i]], subs[order[j]]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.End < b.End
+	})
+
+	type cue struct {
+		start, end time.Duration
+		content    string
+	}
+	seen := map[cue]int{}
+	var kept []int
+	for _, i := range order {
+		sub := subs[i]
+		key := cue{sub.Start, sub.End, sub.Content}
+		if first, ok := seen[key]; ok {
+			duplicates = append(duplicates, Duplicate{sub.Index, subs[first].Index, opts.RemoveDuplicates})
+			if opts.RemoveDuplicates {
+				continue
+			}
+		} else {
+			seen[key] = i
+		}
+		kept = append(kept, i)
+	}
+
+	res := SubtitleFile{make([]Subtitle, len(kept)), subfile.Headers}
+	stay := inOrder(kept)
+	for n, i := range kept {
+		res.Subtitles[n] = subs[i]
+		res.Subtitles[n].Index = n + 1
+		if !stay[i] {
+			moved = append(moved, IndexChange{subs[i].Index, n + 1})
+		}
+	}
+	return res, moved, duplicates
+}
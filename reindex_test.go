@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReindexSubtitleFile(t *testing.T) {
+	type testpair struct {
+		opts               ReindexOptions
+		expected           SubtitleFile
+		expectedMoved      []IndexChange
+		expectedDuplicates []Duplicate
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+			{3, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{4, time.Duration(time.Second * 14), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+			{5, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `Same start, ends first`, "", ""},
+			{6, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		},
+		"",
+	}
+
+	var tests = []testpair{
+		{
+			ReindexOptions{},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+					{2, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+					{3, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+					{4, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `Same start, ends first`, "", ""},
+					{5, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+					{6, time.Duration(time.Second * 14), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+				},
+				"",
+			},
+			[]IndexChange{{6, 2}, {3, 3}, {5, 4}},
+			[]Duplicate{{6, 1, false}},
+		},
+		{
+			ReindexOptions{RemoveDuplicates: true},
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+					{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+					{3, time.Duration(time.Second * 10), time.Duration(time.Second * 12), `Same start, ends first`, "", ""},
+					{4, time.Duration(time.Second * 10), time.Duration(time.Second * 14), `Αυτό δεν αφορά τους Οίκους των ευγενών`, "", ""},
+					{5, time.Duration(time.Second * 14), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+				},
+				"",
+			},
+			[]IndexChange{{3, 2}, {5, 3}},
+			[]Duplicate{{6, 1, true}},
+		},
+	}
+
+	for _, pair := range tests {
+		res, moved, duplicates := ReindexSubtitleFile(input, pair.opts)
+		if !cmp.Equal(res, pair.expected) {
+			t.Errorf("Testing ReindexSubtitleFile with %v. Expected %v but got %v instead!", pair.opts, pair.expected, res)
+		}
+		if !cmp.Equal(moved, pair.expectedMoved) {
+			t.Errorf("Testing ReindexSubtitleFile with %v. Expected moves %v but got %v instead!", pair.opts, pair.expectedMoved, moved)
+		}
+		if !cmp.Equal(duplicates, pair.expectedDuplicates) {
+			t.Errorf("Testing ReindexSubtitleFile with %v. Expected duplicates %v but got %v instead!", pair.opts, pair.expectedDuplicates, duplicates)
+		}
+	}
+	if input.Subtitles[1].Index != 2 || input.Subtitles[1].Start != 10*time.Second {
+		t.Errorf("Testing ReindexSubtitleFile. The input subtitle file was modified")
+	}
+
+	// Subtitles already in order are only renumbered
+	res, moved, duplicates := ReindexSubtitleFile(SubtitleFile{input.Subtitles[:2], ""}, ReindexOptions{})
+	if len(moved) != 0 || len(duplicates) != 0 || res.Subtitles[1].Index != 2 {
+		t.Errorf("Testing ReindexSubtitleFile. Expected no changes but got %v, %v and %v", res, moved, duplicates)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ReplaceOptions configures ReplaceInSubtitleFile. The pattern is taken
+// literally, unless Regexp is set. Replacements are restricted to subtitles
+// with an Index between FromIndex and ToIndex, starting between From and To,
+// where zero values leave the range open. With DryRun set, the subtitle file
+// is left unchanged, and only the report of what would change is returned.
+type ReplaceOptions struct {
+	Regexp     bool
+	IgnoreCase bool
+	WholeWord  bool
+	FromIndex  int
+	ToIndex    int
+	From       time.Duration
+	To         time.Duration
+	DryRun     bool
+}
+
+// Replacement reports the changes made to a single subtitle.
+type Replacement struct {
+	Index  int
+	Count  int
+	Before string
+	After  string
+}
+
+// String returns a diff of the subtitle's text, with the removed lines
+// prefixed by "-" and the added ones by "+".
+func (r Replacement) String() string {
+	res := "Subtitle " + strconv.Itoa(r.Index) + " : " + strconv.Itoa(r.Count) + " replacement"
+	if r.Count != 1 {
+		res += "s"
+	}
+	for _, line := range strings.Split(r.Before, "\n") {
+		res += "\n- " + line
+	}
+	for _, line := range strings.Split(r.After, "\n") {
+		res += "\n+ " + line
+	}
+	return res
+}
+
+// isWordRune reports whether r can be part of a word, in any script.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+// isWholeWord reports whether content[start:end] is not surrounded by letters or digits.
+func isWholeWord(content string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(content[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(content[end:]); end < len(content) && isWordRune(after) {
+		return false
+	}
+	return true
+}
+
+// inMarkup reports whether content[start:end] overlaps a formatting tag,
+// such as <i> or {\an8}, which are never replaced.
+func inMarkup(content string, start, end int) bool {
+	for _, re := range []*regexp.Regexp{tagRegexp, assTagRegexp} {
+		for _, loc := range re.FindAllStringIndex(content, -1) {
+			if start < loc[1] && end > loc[0] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inRange reports whether the subtitle is within the ranges of the options.
+func (opts ReplaceOptions) inRange(sub Subtitle) bool {
+	return (opts.FromIndex == 0 || sub.Index >= opts.FromIndex) &&
+		(opts.ToIndex == 0 || sub.Index <= opts.ToIndex) &&
+		sub.Start >= opts.From &&
+		(opts.To == 0 || sub.Start <= opts.To)
+}
+
+// ReplaceInSubtitleFile replaces every occurrence of the pattern in the text
+// of the subtitles. In regular expression mode, the replacement can refer to
+// capture groups as $1 or ${name}, otherwise it's taken literally. Whole-word
+// matching treats letters of every script as word characters, unlike \b.
+// Formatting tags are left alone, so that eg. "i" never matches <i>.
+// It returns the resulting file along with a report of every changed subtitle.
+func ReplaceInSubtitleFile(subfile SubtitleFile, pattern, replacement string, opts ReplaceOptions) (SubtitleFile, []Replacement, error) {
+	var report []Replacement
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+
+	if pattern == "" {
+		return res, report, errors.New("The search term should not be empty")
+	}
+	expr := pattern
+	if !opts.Regexp {
+		expr = regexp.QuoteMeta(pattern)
+		replacement = strings.Replace(replacement, "$", "$$", -1)
+	}
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return res, report, errors.New("The provided search term is invalid :`" + pattern + "`")
+	}
+
+	for i := range res.Subtitles {
+		sub := &res.Subtitles[i]
+		if !opts.inRange(*sub) {
+			continue
+		}
+		var out []byte
+		count, last := 0, 0
+		for _, m := range r.FindAllStringSubmatchIndex(sub.Content, -1) {
+			if m[0] == m[1] || (opts.WholeWord && !isWholeWord(sub.Content, m[0], m[1])) || inMarkup(sub.Content, m[0], m[1]) {
+				continue
+			}
+			out = append(out, sub.Content[last:m[0]]...)
+			out = r.ExpandString(out, replacement, sub.Content, m)
+			last = m[1]
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		out = append(out, sub.Content[last:]...)
+		report = append(report, Replacement{sub.Index, count, sub.Content, string(out)})
+		if !opts.DryRun {
+			sub.Content = string(out)
+		}
+	}
+	return res, report, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReplaceInSubtitleFile(t *testing.T) {
+	type testpair struct {
+		pattern        string
+		replacement    string
+		opts           ReplaceOptions
+		expected       []string
+		expectedReport []Replacement
+		expectedErr    error
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+			{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), "Meet me at 10:30,\nnot at 11:45. Cost: $5", "", ""},
+			{4, time.Duration(time.Second * 15), time.Duration(time.Second * 17), `The cat scattered the CAT food.`, "", ""},
+			{5, time.Duration(time.Second * 20), time.Duration(time.Second * 22), `<i>i know,</i> {\i1}i{\i0} do.`, "", ""},
+		},
+		"headers",
+	}
+	contents := func(replaced map[int]string) []string {
+		var res []string
+		for i, sub := range input.Subtitles {
+			if content, ok := replaced[i]; ok {
+				res = append(res, content)
+				continue
+			}
+			res = append(res, sub.Content)
+		}
+		return res
+	}
+	var noReplacements []Replacement
+
+	var tests = []testpair{
+		{
+			`Έχουμε`, `Είχαμε`, ReplaceOptions{},
+			contents(map[int]string{0: `Είχαμε όλοι υποφέρει.`, 1: `Είχαμε χάσει αγαπημένους μας.`}),
+			[]Replacement{
+				{1, 1, `Έχουμε όλοι υποφέρει.`, `Είχαμε όλοι υποφέρει.`},
+				{2, 1, `Έχουμε χάσει αγαπημένους μας.`, `Είχαμε χάσει αγαπημένους μας.`},
+			},
+			nil,
+		},
+		{
+			`(\d+):(\d+)`, `${1}h$2`, ReplaceOptions{Regexp: true},
+			contents(map[int]string{2: "Meet me at 10h30,\nnot at 11h45. Cost: $5"}),
+			[]Replacement{{3, 2, "Meet me at 10:30,\nnot at 11:45. Cost: $5", "Meet me at 10h30,\nnot at 11h45. Cost: $5"}},
+			nil,
+		},
+		{
+			`$5`, `$1 and $2`, ReplaceOptions{},
+			contents(map[int]string{2: "Meet me at 10:30,\nnot at 11:45. Cost: $1 and $2"}),
+			[]Replacement{{3, 1, "Meet me at 10:30,\nnot at 11:45. Cost: $5", "Meet me at 10:30,\nnot at 11:45. Cost: $1 and $2"}},
+			nil,
+		},
+		{
+			`cat`, `dog`, ReplaceOptions{IgnoreCase: true, WholeWord: true},
+			contents(map[int]string{3: `The dog scattered the dog food.`}),
+			[]Replacement{{4, 2, `The cat scattered the CAT food.`, `The dog scattered the dog food.`}},
+			nil,
+		},
+		{
+			`i`, `I`, ReplaceOptions{WholeWord: true},
+			contents(map[int]string{4: `<i>I know,</i> {\i1}I{\i0} do.`}),
+			[]Replacement{{5, 2, `<i>i know,</i> {\i1}i{\i0} do.`, `<i>I know,</i> {\i1}I{\i0} do.`}},
+			nil,
+		},
+		{
+			`<?i>?`, `x`, ReplaceOptions{Regexp: true, FromIndex: 5},
+			contents(map[int]string{4: `<i>x know,</i> {\i1}x{\i0} do.`}),
+			[]Replacement{{5, 2, `<i>i know,</i> {\i1}i{\i0} do.`, `<i>x know,</i> {\i1}x{\i0} do.`}},
+			nil,
+		},
+		{
+			`όλ`, `ΟΛ`, ReplaceOptions{WholeWord: true},
+			contents(nil),
+			noReplacements,
+			nil,
+		},
+		{
+			`Έχουμε`, `Είχαμε`, ReplaceOptions{FromIndex: 2, ToIndex: 3},
+			contents(map[int]string{1: `Είχαμε χάσει αγαπημένους μας.`}),
+			[]Replacement{{2, 1, `Έχουμε χάσει αγαπημένους μας.`, `Είχαμε χάσει αγαπημένους μας.`}},
+			nil,
+		},
+		{
+			`e`, `E`, ReplaceOptions{From: 5 * time.Second, To: 12 * time.Second},
+			contents(map[int]string{2: "MEEt mE at 10:30,\nnot at 11:45. Cost: $5"}),
+			[]Replacement{{3, 3, "Meet me at 10:30,\nnot at 11:45. Cost: $5", "MEEt mE at 10:30,\nnot at 11:45. Cost: $5"}},
+			nil,
+		},
+		{
+			`cat`, `dog`, ReplaceOptions{DryRun: true},
+			contents(nil),
+			[]Replacement{{4, 2, `The cat scattered the CAT food.`, `The dog sdogtered the CAT food.`}},
+			nil,
+		},
+		{
+			`(unclosed`, `x`, ReplaceOptions{Regexp: true},
+			contents(nil),
+			noReplacements,
+			errors.New("The provided search term is invalid :`(unclosed`"),
+		},
+		{
+			``, `x`, ReplaceOptions{},
+			contents(nil),
+			noReplacements,
+			errors.New("The search term should not be empty"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, report, err := ReplaceInSubtitleFile(input, pair.pattern, pair.replacement, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing ReplaceInSubtitleFile with %q. Expected error %v but got %v instead!", pair.pattern, pair.expectedErr, err)
+		}
+		var actualContents []string
+		for _, sub := range actual.Subtitles {
+			actualContents = append(actualContents, sub.Content)
+		}
+		if !cmp.Equal(actualContents, pair.expected) {
+			t.Errorf("Testing ReplaceInSubtitleFile with %q and %+v. Expected %q but got %q instead!", pair.pattern, pair.opts, pair.expected, actualContents)
+		}
+		if !cmp.Equal(report, pair.expectedReport) {
+			t.Errorf("Testing ReplaceInSubtitleFile with %q and %+v. Expected report %v but got %v instead!", pair.pattern, pair.opts, pair.expectedReport, report)
+		}
+	}
+	if input.Subtitles[0].Content != `Έχουμε όλοι υποφέρει.` {
+		t.Errorf("ReplaceInSubtitleFile modified its input")
+	}
+}
+
+func TestReplacementString(t *testing.T) {
+	r := Replacement{3, 2, "Meet me at 10:30,\nnot at 11:45.", "Meet me at 10h30,\nnot at 11h45."}
+	expected := "Subtitle 3 : 2 replacements\n- Meet me at 10:30,\n- not at 11:45.\n+ Meet me at 10h30,\n+ not at 11h45."
+	if r.String() != expected {
+		t.Errorf("Testing Replacement.String. Expected %q but got %q instead!", expected, r.String())
+	}
+}
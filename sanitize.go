@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// TextFormat is a subtitle format, deciding which formatting can be written.
+type TextFormat int
+
+const (
+	FormatSRT TextFormat = iota
+	FormatWebVTT
+	FormatASS
+)
+
+func (f TextFormat) String() string {
+	switch f {
+	case FormatWebVTT:
+		return "vtt"
+	case FormatASS:
+		return "ass"
+	}
+	return "srt"
+}
+
+// ParseTextFormat reads the name of a format, as used for file extensions,
+// ie. "srt", "vtt", or "ass" and "ssa".
+func ParseTextFormat(name string) (TextFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "srt":
+		return FormatSRT, nil
+	case "vtt":
+		return FormatWebVTT, nil
+	case "ass", "ssa":
+		return FormatASS, nil
+	}
+	return FormatSRT, errors.New("Unknown subtitle format :`" + name + "`")
+}
+
+// formatForFile returns the format of a file by its extension, defaulting to SRT.
+func formatForFile(filename string) TextFormat {
+	format, _ := ParseTextFormat(filepath.Ext(filename))
+	return format
+}
+
+// formatFeatures is the allowlist of the formatting each format supports.
+// The names are those accepted by SanitizeOptions.Allow.
+var formatFeatures = map[TextFormat][]string{
+	FormatSRT:    {"b", "i", "u", "s", "color", "align"},
+	FormatWebVTT: {"b", "i", "u", "color", "voice", "class", "ruby", "align"},
+	FormatASS:    {"b", "i", "u", "s", "color", "align"},
+}
+
+// SanitizeOptions configures SanitizeText. The formatting that's kept is
+// whatever the Format supports, or only the features listed in Allow, eg.
+// "i" to keep nothing but italics, if it's not empty. StripFormatting
+// removes all formatting, leaving the plain text.
+type SanitizeOptions struct {
+	Format          TextFormat
+	Allow           []string
+	StripFormatting bool
+}
+
+// allowed returns the set of features to keep.
+func (opts SanitizeOptions) allowed() (map[string]bool, error) {
+	res := map[string]bool{}
+	if opts.StripFormatting {
+		return res, nil
+	}
+	supported := map[string]bool{}
+	for _, feature := range formatFeatures[opts.Format] {
+		supported[feature] = true
+	}
+	if len(opts.Allow) == 0 {
+		return supported, nil
+	}
+	for _, feature := range opts.Allow {
+		known := false
+		for _, features := range formatFeatures {
+			for _, f := range features {
+				known = known || f == feature
+			}
+		}
+		if !known {
+			return res, errors.New("Unknown formatting :`" + feature + "`")
+		}
+		res[feature] = supported[feature]
+	}
+	return res, nil
+}
+
+// filter drops the formatting that's not allowed, merging the spans that
+// end up with the same style.
+func (t StyledText) filter(allowed map[string]bool) StyledText {
+	res := StyledText{}
+	if allowed["align"] {
+		res.Position = t.Position
+	}
+	for _, span := range t.Spans {
+		s := span.Style
+		style := TextStyle{
+			Bold:          s.Bold && allowed["b"],
+			Italic:        s.Italic && allowed["i"],
+			Underline:     s.Underline && allowed["u"],
+			Strikethrough: s.Strikethrough && allowed["s"],
+		}
+		if allowed["color"] {
+			style.Color = s.Color
+		}
+		if allowed["voice"] {
+			style.Voice = s.Voice
+		}
+		if allowed["class"] {
+			style.Class = s.Class
+		}
+		if allowed["ruby"] {
+			style.Ruby = s.Ruby
+		}
+		if n := len(res.Spans); n > 0 && res.Spans[n-1].Style == style && style.Ruby == "" {
+			res.Spans[n-1].Text += span.Text
+			continue
+		}
+		res.Spans = append(res.Spans, Span{span.Text, style})
+	}
+	return res
+}
+
+// format serialises the text for a subtitle format.
+func (t StyledText) format(f TextFormat) string {
+	switch f {
+	case FormatWebVTT:
+		return t.WebVTT()
+	case FormatASS:
+		return t.ASS()
+	}
+	return t.SRT()
+}
+
+// SanitizeText rewrites the formatting of a subtitle's content for a format.
+// Supported tags are kept, convertible ones are converted, eg. <font color>
+// to a WebVTT class or an ASS \c override, and the rest are stripped.
+// Unclosed and mis-nested tags are repaired along the way.
+func SanitizeText(content string, opts SanitizeOptions) (string, error) {
+	allowed, err := opts.allowed()
+	if err != nil {
+		return content, err
+	}
+	return ParseStyledText(content).filter(allowed).format(opts.Format), nil
+}
+
+// SanitizeSubtitleFile sanitises the text of every subtitle, see
+// SanitizeText. It returns the Index of every changed subtitle. The result
+// only keeps the formatting the format supports, but in the form used by
+// Subtitle.Content, see StyledText.Markup, leaving it to the writer of the
+// format to convert it.
+func SanitizeSubtitleFile(subfile SubtitleFile, opts SanitizeOptions) (SubtitleFile, []int, error) {
+	var changed []int
+	allowed, err := opts.allowed()
+	if err != nil {
+		return subfile, changed, err
+	}
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	for i := range res.Subtitles {
+		sub := &res.Subtitles[i]
+		content := ParseStyledText(sub.Content).filter(allowed).Markup()
+		if content != sub.Content {
+			sub.Content = content
+			changed = append(changed, sub.Index)
+		}
+	}
+	return res, changed, nil
+}
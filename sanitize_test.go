@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSanitizeText(t *testing.T) {
+	type testpair struct {
+		input       string
+		opts        SanitizeOptions
+		expected    string
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{"<i>Fine line</i>", SanitizeOptions{}, "<i>Fine line</i>", nil},
+		{"<b>Unclosed bold", SanitizeOptions{}, "<b>Unclosed bold</b>", nil},
+		{"<b>Mis<i>nested</b> tags</i>", SanitizeOptions{}, "<b>Mis<i>nested</i></b> tags", nil},
+		{"<blink>Illegal</blink> <i>tag</i>", SanitizeOptions{}, "Illegal <i>tag</i>", nil},
+		{"<v Jon><c.loud>Ghost!</c></v>", SanitizeOptions{}, "Ghost!", nil},
+		{`<font color="red">Red</font> <s>wedding</s>`, SanitizeOptions{Format: FormatWebVTT}, "<c.red>Red</c> wedding", nil},
+		{`<font color="#123456">Odd colour</font>`, SanitizeOptions{Format: FormatWebVTT}, "Odd colour", nil},
+		{`{\an8}<font color="yellow"><i>Top</i></font>`, SanitizeOptions{Format: FormatASS}, `{\an8\i1\c&H00FFFF&}Top`, nil},
+		{"<b>Bold</b> and\n<i>italic</i>", SanitizeOptions{Format: FormatASS}, `{\b1}Bold{\b0} and\N{\i1}italic`, nil},
+		{`{\an8}<b><i>Only</i></b> italics`, SanitizeOptions{Allow: []string{"i"}}, "<i>Only</i> italics", nil},
+		{`{\an8}<b><i>No</i></b> formatting`, SanitizeOptions{StripFormatting: true}, "No formatting", nil},
+		{"<i>Text</i>", SanitizeOptions{Allow: []string{"blink"}}, "<i>Text</i>", errors.New("Unknown formatting :`blink`")},
+	}
+
+	for _, pair := range tests {
+		res, err := SanitizeText(pair.input, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Error("For", pair.input, "expected error", pair.expectedErr, "got", err)
+		}
+		if res != pair.expected {
+			t.Error("For", pair.input, "expected", pair.expected, "got", res)
+		}
+	}
+}
+
+func TestSanitizeSubtitleFile(t *testing.T) {
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `<i>Fine line</i>`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 6), `<b>Unclosed bold`, "", ""},
+		},
+		"",
+	}
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `<i>Fine line</i>`, "", ""},
+			{2, time.Duration(time.Second * 4), time.Duration(time.Second * 6), `<b>Unclosed bold</b>`, "", ""},
+		},
+		"",
+	}
+
+	res, changed, err := SanitizeSubtitleFile(input, SanitizeOptions{})
+	if err != nil || !cmp.Equal(res, expected) || !cmp.Equal(changed, []int{2}) {
+		t.Error("Expected", expected, "with subtitle 2 changed, got", res, changed, err)
+	}
+	if input.Subtitles[1].Content != `<b>Unclosed bold` {
+		t.Error("The input subtitle file was modified")
+	}
+
+	// The result is left for the WebVTT writer to escape and convert
+	input.Subtitles = append(input.Subtitles, Subtitle{3, time.Duration(time.Second * 7), time.Duration(time.Second * 9), `{\an8}Tom & <font color="red">Jerry</font> <s>wedding</s>`, "", ""})
+	res, changed, err = SanitizeSubtitleFile(input, SanitizeOptions{Format: FormatWebVTT})
+	if expected := `{\an8}Tom & <font color="red">Jerry</font> wedding`; err != nil || res.Subtitles[2].Content != expected || !cmp.Equal(changed, []int{2, 3}) {
+		t.Error("Expected", expected, "with subtitles 2 and 3 changed, got", res, changed, err)
+	}
+}
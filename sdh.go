@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// SDHOptions configures RemoveSDH, the patterns being regular expressions.
+// Patterns match annotations that are cut out wherever they appear, such as
+// sound descriptions, SpeakerLabel matches the name of the speaker at the
+// start of a line, and lines matching MusicLine are dropped altogether.
+// With RemoveCapsLines, lines written entirely in capitals without any
+// punctuation, eg. "DOOR SLAMS", are dropped as descriptions as well.
+type SDHOptions struct {
+	Patterns        []string
+	SpeakerLabel    string
+	MusicLine       string
+	RemoveCapsLines bool
+}
+
+// DefaultSDHOptions returns the options used for English subtitles.
+func DefaultSDHOptions() SDHOptions {
+	return SDHOptions{
+		Patterns: []string{
+			`\[[^\]]*\]`,
+			`\([^)]*\)`,
+			`\{[^\\}][^}]*\}`,
+		},
+		SpeakerLabel:    `^\p{Lu}[\p{Lu}\p{N} .'’-]*(?:\s*\([^)]*\))?:\s*`,
+		MusicLine:       `^\s*[♪♫#]`,
+		RemoveCapsLines: true,
+	}
+}
+
+// SDHLanguages returns the built-in options for each language, by code.
+// Japanese subtitles mark descriptions and speakers with full-width brackets.
+func SDHLanguages() map[string]SDHOptions {
+	ja := DefaultSDHOptions()
+	ja.Patterns = append(ja.Patterns, `（[^）]*）`, `［[^］]*］`, `【[^】]*】`, `〈[^〉]*〉`)
+	ja.SpeakerLabel = `^[^\s：:]{1,12}[：:]\s*`
+	ja.RemoveCapsLines = false
+	return map[string]SDHOptions{
+		"en": DefaultSDHOptions(),
+		"el": DefaultSDHOptions(),
+		"ja": ja,
+	}
+}
+
+// sdhLanguageNames returns the codes of the built-in languages, sorted.
+func sdhLanguageNames() []string {
+	var names []string
+	for name := range SDHLanguages() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SDHRemoval reports what RemoveSDH cut from a single subtitle. Dropped
+// is set if nothing was left of it, in which case it was removed.
+type SDHRemoval struct {
+	Index   int
+	Removed []string
+	Dropped bool
+}
+
+func (r SDHRemoval) String() string {
+	res := "Subtitle " + strconv.Itoa(r.Index) + " : removed " + strings.Join(r.Removed, ", ")
+	if r.Dropped {
+		res += " (dropped)"
+	}
+	return res
+}
+
+// sdhMatchers holds the compiled patterns of SDHOptions.
+type sdhMatchers struct {
+	patterns     []*regexp.Regexp
+	speakerLabel *regexp.Regexp
+	musicLine    *regexp.Regexp
+}
+
+func compileSDHOptions(opts SDHOptions) (sdhMatchers, error) {
+	var res sdhMatchers
+	compile := func(pattern string) (*regexp.Regexp, error) {
+		if pattern == "" {
+			return nil, nil
+		}
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.New("Invalid SDH pattern :`" + pattern + "`")
+		}
+		return r, nil
+	}
+	for _, pattern := range opts.Patterns {
+		r, err := compile(pattern)
+		if err != nil {
+			return res, err
+		}
+		if r != nil {
+			res.patterns = append(res.patterns, r)
+		}
+	}
+	var err error
+	if res.speakerLabel, err = compile(opts.SpeakerLabel); err != nil {
+		return res, err
+	}
+	res.musicLine, err = compile(opts.MusicLine)
+	return res, err
+}
+
+var (
+	emptyTagRegexp    = regexp.MustCompile(`<([a-zA-Z]+)[^>]*>\s*</[a-zA-Z]+>`)
+	doubleSpaceRegexp = regexp.MustCompile(`[ \t]{2,}`)
+	dialogueDash      = regexp.MustCompile(`^\s*-\s*`)
+	tagSpaceRegexp    = regexp.MustCompile(`(<[a-zA-Z][^>]*>)\s+|\s+(</[a-zA-Z]+>)`)
+)
+
+// isCapsDescription reports whether a line is written entirely in capitals,
+// without any punctuation that would make it shouted dialogue.
+func isCapsDescription(line string) bool {
+	capitals := 0
+	for _, r := range StripMarkup(line) {
+		switch {
+		case unicode.IsLower(r) || unicode.IsPunct(r) && r != '-' && r != '\'':
+			return false
+		case unicode.IsUpper(r):
+			capitals++
+		case unicode.IsLetter(r):
+			// Scripts without case, such as Chinese, have no captions in capitals
+			return false
+		}
+	}
+	return capitals >= 3
+}
+
+// hasText reports whether a line has anything left to read.
+func hasText(line string) bool {
+	return strings.IndexFunc(StripMarkup(line), func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0
+}
+
+// removeSDHLines strips the annotations from the text of a subtitle,
+// returning what's left and what was removed.
+func removeSDHLines(content string, m sdhMatchers, opts SDHOptions) (string, []string) {
+	var removed, kept []string
+	for _, line := range strings.Split(content, "\n") {
+		if m.musicLine != nil && m.musicLine.MatchString(StripMarkup(line)) {
+			removed = append(removed, line)
+			continue
+		}
+		for _, r := range m.patterns {
+			removed = append(removed, r.FindAllString(line, -1)...)
+			line = r.ReplaceAllString(line, "")
+		}
+		if m.speakerLabel != nil {
+			dash := dialogueDash.FindString(line)
+			if label := m.speakerLabel.FindString(line[len(dash):]); label != "" {
+				removed = append(removed, strings.TrimSpace(label))
+				line = dash + line[len(dash)+len(label):]
+			}
+		}
+		if opts.RemoveCapsLines && isCapsDescription(line) {
+			removed = append(removed, strings.TrimSpace(line))
+			continue
+		}
+		line = emptyTagRegexp.ReplaceAllString(line, "")
+		line = tagSpaceRegexp.ReplaceAllString(doubleSpaceRegexp.ReplaceAllString(line, " "), "$1$2")
+		line = strings.TrimSpace(line)
+		if hasText(line) {
+			kept = append(kept, line)
+		}
+	}
+	// A dash is only needed when there's more than one speaker
+	speakers := 0
+	for _, line := range kept {
+		if dialogueDash.MatchString(line) {
+			speakers++
+		}
+	}
+	if speakers == 1 && len(kept) > 0 {
+		kept[0] = dialogueDash.ReplaceAllString(kept[0], "")
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+// RemoveSDH produces a non-SDH version of a subtitle file, removing sound
+// descriptions, speaker labels, music and captions written in capitals,
+// along with the dashes and lines they leave behind. Subtitles with
+// nothing left are dropped, and the result is renumbered. It returns a
+// report of what was removed from each subtitle.
+func RemoveSDH(subfile SubtitleFile, opts SDHOptions) (SubtitleFile, []SDHRemoval, error) {
+	var report []SDHRemoval
+	m, err := compileSDHOptions(opts)
+	if err != nil {
+		return subfile, report, err
+	}
+	var subs []Subtitle
+	for _, sub := range subfile.Subtitles {
+		content, removed := removeSDHLines(sub.Content, m, opts)
+		if len(removed) > 0 {
+			report = append(report, SDHRemoval{sub.Index, removed, content == ""})
+		}
+		if content == "" && len(removed) > 0 {
+			continue
+		}
+		if len(removed) > 0 {
+			sub.Content = content
+		}
+		subs = append(subs, sub)
+	}
+	return SerializeSubtitles(SubtitleFile{subs, subfile.Headers}), report, nil
+}
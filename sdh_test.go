@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRemoveSDH(t *testing.T) {
+	type testpair struct {
+		input          SubtitleFile
+		opts           SDHOptions
+		expected       SubtitleFile
+		expectedReport []SDHRemoval
+		expectedErr    error
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `[LOUD MUSIC]`, "", ""},
+			{2, time.Duration(time.Second * 3), time.Duration(time.Second * 4), "JON: Winter is coming.\n(sighs) I know.", "", ""},
+			{3, time.Duration(time.Second * 5), time.Duration(time.Second * 6), "- [GROANS]\n- ARYA: Stick them\nwith the pointy end.", "", ""},
+			{4, time.Duration(time.Second * 7), time.Duration(time.Second * 8), "♪ The Rains of Castamere ♪\nDOOR SLAMS", "", ""},
+			{5, time.Duration(time.Second * 9), time.Duration(time.Second * 10), "NO! Not today.\n<i>(whispering) Run.</i>", "", ""},
+			{6, time.Duration(time.Second * 11), time.Duration(time.Second * 12), `{\an8}Nothing to remove here.`, "", ""},
+		},
+		"headers",
+	}
+
+	var tests = []testpair{
+		{
+			input,
+			DefaultSDHOptions(),
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 3), time.Duration(time.Second * 4), "Winter is coming.\nI know.", "", ""},
+					{2, time.Duration(time.Second * 5), time.Duration(time.Second * 6), "Stick them\nwith the pointy end.", "", ""},
+					{3, time.Duration(time.Second * 9), time.Duration(time.Second * 10), "NO! Not today.\n<i>Run.</i>", "", ""},
+					{4, time.Duration(time.Second * 11), time.Duration(time.Second * 12), `{\an8}Nothing to remove here.`, "", ""},
+				},
+				"headers",
+			},
+			[]SDHRemoval{
+				{1, []string{"[LOUD MUSIC]"}, true},
+				{2, []string{"JON:", "(sighs)"}, false},
+				{3, []string{"[GROANS]", "ARYA:"}, false},
+				{4, []string{"♪ The Rains of Castamere ♪", "DOOR SLAMS"}, true},
+				{5, []string{"(whispering)"}, false},
+			},
+			nil,
+		},
+		{
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), "（ジョン）冬が来る\n【雷鳴】", "", ""},
+					{2, time.Duration(time.Second * 3), time.Duration(time.Second * 4), "アリア：針で刺せ", "", ""},
+				},
+				"",
+			},
+			SDHLanguages()["ja"],
+			SubtitleFile{
+				[]Subtitle{
+					{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), "冬が来る", "", ""},
+					{2, time.Duration(time.Second * 3), time.Duration(time.Second * 4), "針で刺せ", "", ""},
+				},
+				"",
+			},
+			[]SDHRemoval{
+				{1, []string{"（ジョン）", "【雷鳴】"}, false},
+				{2, []string{"アリア："}, false},
+			},
+			nil,
+		},
+		{
+			input,
+			SDHOptions{Patterns: []string{`[unclosed`}},
+			input,
+			nil,
+			errors.New("Invalid SDH pattern :`[unclosed`"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, report, err := RemoveSDH(pair.input, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing RemoveSDH with %+v. Expected error %v but got %v instead!", pair.opts, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing RemoveSDH with %+v. Expected %q but got %q instead!", pair.opts, pair.expected, actual)
+		}
+		if !cmp.Equal(report, pair.expectedReport) {
+			t.Errorf("Testing RemoveSDH with %+v. Expected report %q but got %q instead!", pair.opts, pair.expectedReport, report)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseMetadata reads the structured metadata of a subtitle, stored
+// in its Metadata field as "key=value" pairs separated by semicolons.
+func ParseMetadata(metadata string) map[string]string {
+	res := map[string]string{}
+	for _, pair := range strings.Split(metadata, ";") {
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		res[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return res
+}
+
+// FormatMetadata is the inverse of ParseMetadata, with the keys sorted.
+func FormatMetadata(metadata map[string]string) string {
+	var keys []string
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+metadata[key])
+	}
+	return strings.Join(pairs, ";")
+}
+
+// SoundCueStyle is how sound and effect descriptions are written,
+// eg. "[DOOR SLAMS]" or "(door slams)".
+type SoundCueStyle struct {
+	Open      string
+	Close     string
+	Uppercase bool
+}
+
+// DefaultSoundCueStyle writes descriptions in capitals, within square brackets.
+var DefaultSoundCueStyle = SoundCueStyle{"[", "]", true}
+
+// Format returns a description written in the style.
+func (s SoundCueStyle) Format(description string) string {
+	description = strings.TrimSpace(description)
+	if s.Uppercase {
+		description = strings.ToUpper(description)
+	} else {
+		description = strings.ToLower(description)
+	}
+	return s.Open + description + s.Close
+}
+
+// InsertSoundCue adds a subtitle describing a sound or effect, such as
+// "[THUNDER RUMBLING]", between existing subtitles, the way AddSubtitle
+// does. The new subtitle is marked with "sound" in its metadata.
+func InsertSoundCue(subfile SubtitleFile, start, end time.Duration, description string, style SoundCueStyle) (SubtitleFile, error) {
+	if strings.TrimSpace(description) == "" {
+		return subfile, errors.New("The sound description should not be empty")
+	}
+	content := style.Format(description)
+	metadata := FormatMetadata(map[string]string{"sound": strings.TrimSpace(description)})
+	if len(subfile.Subtitles) == 0 {
+		if start < 0 || end < start {
+			return subfile, errors.New("Start and End times should be positive and ordered, ignoring input... " + start.String() + " - " + end.String())
+		}
+		return SubtitleFile{[]Subtitle{{1, start, end, content, metadata, ""}}, subfile.Headers}, nil
+	}
+	return AddSubtitle(subfile, start.String(), end.String(), content, metadata, "")
+}
+
+// Speakers returns the speakers of a subtitle, one per line of dialogue,
+// as stored by SetSpeakers.
+func Speakers(sub Subtitle) []string {
+	speakers := ParseMetadata(sub.Metadata)["speaker"]
+	if speakers == "" {
+		return nil
+	}
+	return strings.Split(speakers, ",")
+}
+
+// SetSpeakers tags the subtitle with the given Index with the names of its
+// speakers, in the order they speak. No names clears the speakers.
+func SetSpeakers(subfile SubtitleFile, index int, speakers ...string) (SubtitleFile, error) {
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	for _, speaker := range speakers {
+		if strings.TrimSpace(speaker) == "" || strings.ContainsAny(speaker, ",;=") {
+			return subfile, errors.New("Invalid speaker name :`" + speaker + "`")
+		}
+	}
+	for i := range res.Subtitles {
+		if res.Subtitles[i].Index != index {
+			continue
+		}
+		metadata := ParseMetadata(res.Subtitles[i].Metadata)
+		delete(metadata, "speaker")
+		if len(speakers) > 0 {
+			metadata["speaker"] = strings.Join(speakers, ",")
+		}
+		res.Subtitles[i].Metadata = FormatMetadata(metadata)
+		return res, nil
+	}
+	return subfile, errors.New("No subtitle with index " + strconv.Itoa(index))
+}
+
+// SpeakerStyle selects how RenderSpeakers shows who is speaking.
+type SpeakerStyle int
+
+const (
+	// SpeakerLabels prefixes lines with the speaker's name, eg. "JON: ",
+	// whenever the speaker changes
+	SpeakerLabels SpeakerStyle = iota
+	// DialogueDashes prefixes each line with a dash when
+	// a subtitle holds the lines of more than one speaker
+	DialogueDashes
+)
+
+// RenderSpeakers writes the speakers stored in the subtitles' metadata
+// into their text. Subtitles with more speakers than lines are left as
+// they are, and so is text that already starts with a dash or its label,
+// so that rendering the speakers again changes nothing.
+func RenderSpeakers(subfile SubtitleFile, style SpeakerStyle) SubtitleFile {
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+	previous := ""
+	for i := range res.Subtitles {
+		sub := &res.Subtitles[i]
+		speakers := Speakers(*sub)
+		lines := strings.Split(sub.Content, "\n")
+		if len(speakers) == 0 || len(speakers) > len(lines) {
+			continue
+		}
+		// A single speaker may have several lines,
+		// otherwise there's a speaker for every line
+		lineSpeakers := speakers
+		if len(speakers) == 1 {
+			lineSpeakers = []string{speakers[0]}
+			for range lines[1:] {
+				lineSpeakers = append(lineSpeakers, "")
+			}
+		} else if len(speakers) != len(lines) {
+			continue
+		}
+		for n, speaker := range lineSpeakers {
+			switch {
+			case speaker == "":
+				continue
+			case style == SpeakerLabels && speaker != previous &&
+				!strings.HasPrefix(strings.TrimSpace(StripMarkup(lines[n])), strings.ToUpper(speaker)+":"):
+				lines[n] = strings.ToUpper(speaker) + ": " + lines[n]
+			case style == DialogueDashes && len(speakers) > 1 && !isDialogue(lines[n]):
+				lines[n] = "- " + lines[n]
+			}
+			previous = speaker
+		}
+		sub.Content = strings.Join(lines, "\n")
+	}
+	return res
+}
+
+var soundCueRegexp = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+
+// soundCueStyles returns the brackets and case used by each description in the
+// subtitles, keyed by subtitle position, along with the most common of each.
+func soundCueStyles(subfile SubtitleFile) ([][]string, [][]bool, string, bool) {
+	brackets := make([][]string, len(subfile.Subtitles))
+	uppercase := make([][]bool, len(subfile.Subtitles))
+	bracketCount := map[string]int{}
+	upperCount := map[bool]int{}
+	for i, sub := range subfile.Subtitles {
+		for _, cue := range soundCueRegexp.FindAllString(StripMarkup(sub.Content), -1) {
+			inner := cue[1 : len(cue)-1]
+			if !hasText(inner) {
+				continue
+			}
+			upper := strings.ToUpper(inner) == inner
+			brackets[i] = append(brackets[i], cue[:1])
+			uppercase[i] = append(uppercase[i], upper)
+			bracketCount[cue[:1]]++
+			upperCount[upper]++
+		}
+	}
+	commonBracket := "["
+	if bracketCount["("] > bracketCount["["] {
+		commonBracket = "("
+	}
+	return brackets, uppercase, commonBracket, upperCount[true] >= upperCount[false]
+}
+
+// SDHLintRules returns linter rules checking the conventions of subtitles for
+// the deaf and hard-of-hearing, to be used along with DefaultLintRules.
+func SDHLintRules() []LintRule {
+	return []LintRule{
+		{
+			Name:        "sdh-brackets",
+			Description: "Sound descriptions using different brackets than most of the file",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				brackets, _, common, _ := soundCueStyles(subfile)
+				for i := range brackets {
+					for _, bracket := range brackets[i] {
+						if bracket != common {
+							res = append(res, subtitleIssue(subfile, i, "Sound description in "+bracket+" brackets, while most use "+common))
+							break
+						}
+					}
+				}
+				return res
+			},
+		},
+		{
+			Name:        "sdh-caps",
+			Description: "Sound descriptions capitalised differently than most of the file",
+			Severity:    SeverityWarning,
+			Check: func(subfile SubtitleFile, cfg LintConfig) []LintIssue {
+				var res []LintIssue
+				_, uppercase, _, common := soundCueStyles(subfile)
+				expected := "lowercase"
+				if common {
+					expected = "capitals"
+				}
+				for i := range uppercase {
+					for _, upper := range uppercase[i] {
+						if upper != common {
+							res = append(res, subtitleIssue(subfile, i, "Sound description is not in "+expected+", like most of the file"))
+							break
+						}
+					}
+				}
+				return res
+			},
+		},
+	}
+}
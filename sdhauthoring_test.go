@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMetadata(t *testing.T) {
+	type testpair struct {
+		input     string
+		expected  map[string]string
+		formatted string
+	}
+
+	var tests = []testpair{
+		{"", map[string]string{}, ""},
+		{"speaker=JON", map[string]string{"speaker": "JON"}, "speaker=JON"},
+		{"sound=thunder; speaker=JON,ARYA;flag", map[string]string{"sound": "thunder", "speaker": "JON,ARYA", "flag": ""}, "flag=;sound=thunder;speaker=JON,ARYA"},
+	}
+
+	for _, pair := range tests {
+		actual := ParseMetadata(pair.input)
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing ParseMetadata with %q. Expected %v but got %v instead!", pair.input, pair.expected, actual)
+		}
+		if formatted := FormatMetadata(actual); formatted != pair.formatted {
+			t.Errorf("Testing FormatMetadata with %v. Expected %q but got %q instead!", actual, pair.formatted, formatted)
+		}
+	}
+}
+
+var sdhTestFile = SubtitleFile{
+	[]Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Winter is coming.`, "", ""},
+		{2, time.Duration(time.Second * 6), time.Duration(time.Second * 8), "Who is there?\nOnly me.", "", ""},
+		{3, time.Duration(time.Second * 9), time.Duration(time.Second * 10), `Then we ride.`, "", ""},
+	},
+	"headers",
+}
+
+func TestInsertSoundCue(t *testing.T) {
+	type testpair struct {
+		input       SubtitleFile
+		start       time.Duration
+		end         time.Duration
+		description string
+		style       SoundCueStyle
+		expected    SubtitleFile
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{
+			sdhTestFile, 4 * time.Second, 5 * time.Second, "thunder rumbling", DefaultSoundCueStyle,
+			SubtitleFile{
+				[]Subtitle{
+					sdhTestFile.Subtitles[0],
+					{2, time.Duration(time.Second * 4), time.Duration(time.Second * 5), `[THUNDER RUMBLING]`, "sound=thunder rumbling", ""},
+					{3, time.Duration(time.Second * 6), time.Duration(time.Second * 8), "Who is there?\nOnly me.", "", ""},
+					{4, time.Duration(time.Second * 9), time.Duration(time.Second * 10), `Then we ride.`, "", ""},
+				},
+				"headers",
+			},
+			nil,
+		},
+		{
+			SubtitleFile{}, time.Second, 2 * time.Second, "Sighs", SoundCueStyle{"(", ")", false},
+			SubtitleFile{[]Subtitle{{1, time.Second, 2 * time.Second, `(sighs)`, "sound=Sighs", ""}}, ""},
+			nil,
+		},
+		{
+			sdhTestFile, 2 * time.Second, 5 * time.Second, "thunder", DefaultSoundCueStyle,
+			sdhTestFile,
+			errors.New("New subtitle would overlap with existing ones, ignoring it...2s - 5s"),
+		},
+		{
+			sdhTestFile, 4 * time.Second, 5 * time.Second, " ", DefaultSoundCueStyle,
+			sdhTestFile,
+			errors.New("The sound description should not be empty"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, err := InsertSoundCue(pair.input, pair.start, pair.end, pair.description, pair.style)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing InsertSoundCue with %q. Expected error %v but got %v instead!", pair.description, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing InsertSoundCue with %q. Expected %v but got %v instead!", pair.description, pair.expected, actual)
+		}
+	}
+}
+
+func TestRenderSpeakers(t *testing.T) {
+	subfile, err := SetSpeakers(sdhTestFile, 1, "Jon")
+	if err == nil {
+		subfile, err = SetSpeakers(subfile, 2, "Arya", "Jon")
+	}
+	if err == nil {
+		subfile, err = SetSpeakers(subfile, 3, "Jon")
+	}
+	if err != nil {
+		t.Fatalf("Testing SetSpeakers. Unexpected error %v", err)
+	}
+	if speakers := Speakers(subfile.Subtitles[1]); !cmp.Equal(speakers, []string{"Arya", "Jon"}) {
+		t.Errorf("Testing Speakers. Expected [Arya Jon] but got %v instead!", speakers)
+	}
+
+	labels := []string{"JON: Winter is coming.", "ARYA: Who is there?\nJON: Only me.", "Then we ride."}
+	dashes := []string{"Winter is coming.", "- Who is there?\n- Only me.", "Then we ride."}
+	for style, expected := range map[SpeakerStyle][]string{SpeakerLabels: labels, DialogueDashes: dashes} {
+		var actual, again []string
+		rendered := RenderSpeakers(subfile, style)
+		for _, sub := range rendered.Subtitles {
+			actual = append(actual, sub.Content)
+		}
+		if !cmp.Equal(actual, expected) {
+			t.Errorf("Testing RenderSpeakers with style %v. Expected %q but got %q instead!", style, expected, actual)
+		}
+		// Rendering them again leaves the text as it is
+		for _, sub := range RenderSpeakers(rendered, style).Subtitles {
+			again = append(again, sub.Content)
+		}
+		if !cmp.Equal(again, expected) {
+			t.Errorf("Testing RenderSpeakers twice with style %v. Expected %q but got %q instead!", style, expected, again)
+		}
+	}
+
+	if _, err := SetSpeakers(sdhTestFile, 7, "Jon"); err == nil || err.Error() != "No subtitle with index 7" {
+		t.Errorf("Testing SetSpeakers with a missing index. Got %v", err)
+	}
+	if _, err := SetSpeakers(sdhTestFile, 1, "Jon,Arya"); err == nil || err.Error() != "Invalid speaker name :`Jon,Arya`" {
+		t.Errorf("Testing SetSpeakers with an invalid name. Got %v", err)
+	}
+}
+
+func TestSDHLintRules(t *testing.T) {
+	in := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second * 1), time.Duration(time.Second * 2), `[THUNDER]`, "", ""},
+			{2, time.Duration(time.Second * 3), time.Duration(time.Second * 4), `[DOOR SLAMS] Who is there?`, "", ""},
+			{3, time.Duration(time.Second * 5), time.Duration(time.Second * 6), `(sighs) Only me.`, "", ""},
+			{4, time.Duration(time.Second * 7), time.Duration(time.Second * 8), `[Horse neighs]`, "", ""},
+		},
+		"",
+	}
+	expected := []string{
+		"warning: subtitle #3 (00:00:05,000): Sound description in ( brackets, while most use [ [sdh-brackets]",
+		"warning: subtitle #3 (00:00:05,000): Sound description is not in capitals, like most of the file [sdh-caps]",
+		"warning: subtitle #4 (00:00:07,000): Sound description is not in capitals, like most of the file [sdh-caps]",
+	}
+
+	actual := lintStrings(Lint(in, SDHLintRules(), DefaultLintConfig()))
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("Testing SDHLintRules. Expected\n%v\nbut got\n%v\ninstead!", expected, actual)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SearchOptions configures SearchSubtitles. Only subtitles starting between
+// From and To, and shown for between MinDuration and MaxDuration, are
+// searched, where zero values leave the range open. Context is the number
+// of subtitles before and after each match returned along with it. With
+// IgnoreMarkup, the plain text of the subtitles is searched instead of
+// their content, so that formatting tags cannot get in the way of a match,
+// and the spans of the matches refer to the plain text.
+type SearchOptions struct {
+	IgnoreCase   bool
+	IgnoreMarkup bool
+	From         time.Duration
+	To           time.Duration
+	MinDuration  time.Duration
+	MaxDuration  time.Duration
+	Context      int
+}
+
+// MatchSpan locates a match within the text of a subtitle, both as byte
+// offsets, for slicing the content, and as rune offsets, for editors.
+type MatchSpan struct {
+	Start     int
+	End       int
+	RuneStart int
+	RuneEnd   int
+	Text      string
+}
+
+// SearchResult is a subtitle that matched a search. File is empty unless
+// the search went over a directory. Position is the 1-based position of
+// the subtitle in its file.
+type SearchResult struct {
+	File     string
+	Position int
+	Subtitle Subtitle
+	Matches  []MatchSpan
+	Before   []Subtitle
+	After    []Subtitle
+}
+
+// searchLine formats a subtitle on a single line, grep-style, using sep
+// to tell matching subtitles (":") from context ("-").
+func searchLine(file string, sub Subtitle, sep string) string {
+	res := strconv.Itoa(sub.Index) + sep + DurationToTimestampSRT(sub.Start) + " --> " + DurationToTimestampSRT(sub.End) + sep + " " + strings.Replace(sub.Content, "\n", " | ", -1)
+	if file != "" {
+		res = file + sep + res
+	}
+	return res
+}
+
+// String returns the result the way grep would print it, along with its context.
+func (r SearchResult) String() string {
+	var lines []string
+	for _, sub := range r.Before {
+		lines = append(lines, searchLine(r.File, sub, "-"))
+	}
+	lines = append(lines, searchLine(r.File, r.Subtitle, ":"))
+	for _, sub := range r.After {
+		lines = append(lines, searchLine(r.File, sub, "-"))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// matches reports whether the subtitle is within the ranges of the options.
+func (opts SearchOptions) matches(sub Subtitle) bool {
+	d := sub.End - sub.Start
+	return sub.Start >= opts.From &&
+		(opts.To == 0 || sub.Start <= opts.To) &&
+		d >= opts.MinDuration &&
+		(opts.MaxDuration == 0 || d <= opts.MaxDuration)
+}
+
+// SearchSubtitles scans the text of the subtitles for matches with the
+// provided regular expression, like SearchSubtitleFile, returning where
+// each match was found along with the surrounding subtitles.
+func SearchSubtitles(subfile SubtitleFile, pattern string, opts SearchOptions) ([]SearchResult, error) {
+	var res []SearchResult
+	if opts.Context < 0 {
+		return res, errors.New("The number of context subtitles should not be negative")
+	}
+	expr := pattern
+	if opts.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	r, err := regexp.Compile(expr)
+	if err != nil {
+		return res, errors.New("The provided search term is invalid :`" + pattern + "`")
+	}
+
+	subs := subfile.Subtitles
+	for i, sub := range subs {
+		if !opts.matches(sub) {
+			continue
+		}
+		text := sub.Content
+		if opts.IgnoreMarkup {
+			text = sub.PlainText()
+		}
+		var spans []MatchSpan
+		for _, m := range r.FindAllStringIndex(text, -1) {
+			runeStart := utf8.RuneCountInString(text[:m[0]])
+			spans = append(spans, MatchSpan{m[0], m[1], runeStart, runeStart + utf8.RuneCountInString(text[m[0]:m[1]]), text[m[0]:m[1]]})
+		}
+		if len(spans) == 0 {
+			continue
+		}
+		from, to := i-opts.Context, i+opts.Context+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(subs) {
+			to = len(subs)
+		}
+		before := append([]Subtitle(nil), subs[from:i]...)
+		after := append([]Subtitle(nil), subs[i+1:to]...)
+		res = append(res, SearchResult{"", i + 1, sub, spans, before, after})
+	}
+	return res, nil
+}
+
+// SearchDirectory searches every subtitle file under a directory, such as a
+// whole season, in lexical order. SRT, WebVTT and SSA/ASS files are read,
+// see ParseSubtitleFile, and other files are skipped. Files that cannot be parsed are reported
+// and skipped, while parsing errors within a file only produce a warning.
+func SearchDirectory(dir string, pattern string, opts SearchOptions) ([]SearchResult, []error) {
+	var res []SearchResult
+	var errs []error
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if _, err := ParseTextFormat(filepath.Ext(path)); !info.IsDir() && err == nil {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return res, []error{errors.New("Could not read directory " + dir)}
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		subfile, parseErrs := ParseSubtitleFile(file)
+		for _, err := range parseErrs {
+			errs = append(errs, errors.New(file+" : "+err.Error()))
+		}
+		results, err := SearchSubtitles(subfile, pattern, opts)
+		if err != nil {
+			return res, append(errs, err)
+		}
+		for i := range results {
+			results[i].File = file
+		}
+		res = append(res, results...)
+	}
+	return res, errs
+}
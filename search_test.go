@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSearchSubtitles(t *testing.T) {
+	type testpair struct {
+		pattern     string
+		opts        SearchOptions
+		expected    []SearchResult
+		expectedErr error
+	}
+
+	subs := []Subtitle{
+		{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), `Έχουμε όλοι υποφέρει.`, "", ""},
+		{2, time.Duration(time.Second * 4), time.Duration(time.Second * 7), `Έχουμε χάσει αγαπημένους μας.`, "", ""},
+		{3, time.Duration(time.Second * 10), time.Duration(time.Second * 14), "Jon Snow,\nthe King in the North.", "", ""},
+		{4, time.Duration(time.Second * 15), time.Duration(time.Second * 16), `Κι εγώ σκοπεύω να ζήσω.`, "", ""},
+	}
+	input := SubtitleFile{subs, ""}
+	var noResults []SearchResult
+	var noSubtitles []Subtitle
+
+	var tests = []testpair{
+		{
+			`μας|όλοι`,
+			SearchOptions{},
+			[]SearchResult{
+				{"", 1, subs[0], []MatchSpan{{13, 21, 7, 11, `όλοι`}}, noSubtitles, noSubtitles},
+				{"", 2, subs[1], []MatchSpan{{47, 53, 25, 28, `μας`}}, noSubtitles, noSubtitles},
+			},
+			nil,
+		},
+		{
+			`the`,
+			SearchOptions{IgnoreCase: true, Context: 1},
+			[]SearchResult{
+				{"", 3, subs[2], []MatchSpan{{10, 13, 10, 13, `the`}, {22, 25, 22, 25, `the`}}, subs[1:2], subs[3:4]},
+			},
+			nil,
+		},
+		{
+			`Έχουμε`,
+			SearchOptions{From: 2 * time.Second, Context: 5},
+			[]SearchResult{
+				{"", 2, subs[1], []MatchSpan{{0, 12, 0, 6, `Έχουμε`}}, subs[0:1], subs[2:4]},
+			},
+			nil,
+		},
+		{
+			`χάσει|Jon`,
+			SearchOptions{To: 12 * time.Second, MinDuration: 3 * time.Second, MaxDuration: 3 * time.Second},
+			[]SearchResult{
+				{"", 2, subs[1], []MatchSpan{{13, 23, 7, 12, `χάσει`}}, noSubtitles, noSubtitles},
+			},
+			nil,
+		},
+		{
+			`Tyrion`,
+			SearchOptions{},
+			noResults,
+			nil,
+		},
+		{
+			`(unclosed`,
+			SearchOptions{},
+			noResults,
+			errors.New("The provided search term is invalid :`(unclosed`"),
+		},
+		{
+			`Jon`,
+			SearchOptions{Context: -1},
+			noResults,
+			errors.New("The number of context subtitles should not be negative"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, err := SearchSubtitles(input, pair.pattern, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing SearchSubtitles with %q. Expected error %v but got %v instead!", pair.pattern, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing SearchSubtitles with %q and %+v. Expected %v but got %v instead!", pair.pattern, pair.opts, pair.expected, actual)
+		}
+	}
+}
+
+func TestSearchResultString(t *testing.T) {
+	r := SearchResult{
+		"s01e01.srt", 2,
+		Subtitle{2, time.Second * 4, time.Second * 7, "Jon Snow,\nthe King in the North.", "", ""},
+		nil,
+		[]Subtitle{{1, time.Second, time.Second * 3, `Winter is coming.`, "", ""}},
+		nil,
+	}
+	expected := "s01e01.srt-1-00:00:01,000 --> 00:00:03,000- Winter is coming.\n" +
+		"s01e01.srt:2:00:00:04,000 --> 00:00:07,000: Jon Snow, | the King in the North."
+	if r.String() != expected {
+		t.Errorf("Testing SearchResult.String. Expected %q but got %q instead!", expected, r.String())
+	}
+}
+
+func TestSearchDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A copy of some of the samples, so that other files in samples/ don't matter
+	fixtures := map[string]string{
+		"sample.srt":                  "sample.srt",
+		"sample_en.srt":               "sample_en.srt",
+		"sample.vtt":                  "sample.vtt",
+		"sample.ass":                  "sample.ass",
+		"lint_config.json":            "lint_config.json",
+		"sample_wrong_timestamps.srt": "sample_wrong_timestamps.srt",
+		"sample_short_nix_eol.srt":    filepath.Join("season2", "sample_short_nix_eol.srt"),
+	}
+	for sample, copied := range fixtures {
+		data, err := ioutil.ReadFile(filepath.Join("samples", sample))
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.MkdirAll(filepath.Dir(filepath.Join(dir, copied)), 0755)
+		if err := ioutil.WriteFile(filepath.Join(dir, copied), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectedFiles := []string{
+		filepath.Join(dir, "sample.ass"),
+		filepath.Join(dir, "sample.srt"),
+		filepath.Join(dir, "sample.vtt"),
+		filepath.Join(dir, "season2", "sample_short_nix_eol.srt"),
+	}
+
+	results, errs := SearchDirectory(dir, `Οίκους`, SearchOptions{})
+	var files []string
+	for _, r := range results {
+		files = append(files, r.File)
+		if r.Subtitle.Index != 3 || len(r.Matches) != 1 {
+			t.Errorf("Testing SearchDirectory. Unexpected result %v", r)
+		}
+	}
+	if !cmp.Equal(files, expectedFiles) {
+		t.Errorf("Testing SearchDirectory. Expected matches in %v but got %v instead!", expectedFiles, files)
+	}
+	// The malformed sample should only produce warnings
+	if len(errs) == 0 {
+		t.Errorf("Testing SearchDirectory. Expected parsing errors for the malformed sample")
+	}
+
+	_, errs = SearchDirectory("wrongdirectory", `Οίκους`, SearchOptions{})
+	if !ErrorSlicesEqual(errs, []error{errors.New("Could not read directory wrongdirectory")}) {
+		t.Errorf("Testing SearchDirectory with a missing directory. Got %v", errs)
+	}
+}
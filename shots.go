@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShotChanges holds the sorted times of the shot changes (cuts) of a video.
+// FPS is the frame rate reported by the keyframe file, or zero if unknown.
+type ShotChanges struct {
+	FPS   float64
+	Times []time.Duration
+}
+
+// SnapOptions controls how subtitle timings are snapped to shot changes.
+// Start and End values within Threshold of a shot change are moved to it;
+// Ends are then kept MinGapFrames frames clear of the cut.
+// If FPS is zero, the frame rate of the ShotChanges is used.
+type SnapOptions struct {
+	Threshold    time.Duration
+	MinGapFrames int
+	FPS          float64
+}
+
+// TimingAdjustment records a single change of a subtitle's Start or End.
+type TimingAdjustment struct {
+	Index int
+	Field string
+	From  time.Duration
+	To    time.Duration
+	Cut   time.Duration
+}
+
+func (a TimingAdjustment) String() string {
+	return "Subtitle " + strconv.Itoa(a.Index) + " : " + a.Field + " moved from " +
+		DurationToTimestampSRT(a.From) + " to " + DurationToTimestampSRT(a.To) +
+		" (shot change at " + DurationToTimestampSRT(a.Cut) + ")"
+}
+
+// ParseKeyframeFile reads a list of shot changes from a file, see ParseKeyframes.
+func ParseKeyframeFile(filename string, fps float64) (ShotChanges, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return ShotChanges{}, errors.New("Could not open file " + filename + " for reading")
+	}
+	return ParseKeyframes(string(content), fps)
+}
+
+// ParseKeyframes reads a list of shot changes in one of the following formats
+//   - Aegisub keyframes ("# keyframe format v1", an "fps" line, then frame numbers)
+//   - ffprobe CSV output, such as `-show_entries frame=pkt_pts_time,pict_type -of csv`
+//     or `-show_entries packet=pts_time,flags -of csv`, with or without print_section=0,
+//     where rows with a pict_type other than I, or flags without K, are skipped
+//   - plain lists of timestamps, either SRT-like (00:01:02,500) or seconds (62.5)
+//
+// The fps argument is required for Aegisub files that do not state their frame rate.
+func ParseKeyframes(content string, fps float64) (ShotChanges, error) {
+	res := ShotChanges{FPS: fps}
+	lines := strings.FieldsFunc(content, EOLSplit)
+	if len(lines) == 0 {
+		return res, errors.New("The keyframe list is empty")
+	}
+
+	var err error
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(lines[0]), "# keyframe format"):
+		err = parseAegisubKeyframes(lines[1:], &res)
+	case isFFprobeCSV(lines[0]):
+		err = parseFFprobeKeyframes(lines, &res)
+	default:
+		err = parseTimestampKeyframes(lines, &res)
+	}
+	if err != nil {
+		return res, err
+	}
+	sort.Slice(res.Times, func(i, j int) bool { return res.Times[i] < res.Times[j] })
+	return res, nil
+}
+
+func parseAegisubKeyframes(lines []string, res *ShotChanges) error {
+	var frames []int
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "fps") {
+			fps, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(line, "fps")), 64)
+			if err != nil {
+				return errors.New("Invalid fps line in keyframe list :" + line)
+			}
+			if fps > 0 {
+				res.FPS = fps
+			}
+			continue
+		}
+		frame, err := strconv.Atoi(line)
+		if err != nil || frame < 0 {
+			return errors.New("Invalid frame number in keyframe list :" + line)
+		}
+		frames = append(frames, frame)
+	}
+	if res.FPS <= 0 {
+		return errors.New("The keyframe list does not state a frame rate, and none was provided")
+	}
+	for _, frame := range frames {
+		res.Times = append(res.Times, FramesToDuration(frame, res.FPS))
+	}
+	return nil
+}
+
+// isFFprobeCSV reports whether a line is a row of ffprobe CSV output. Rows
+// printed with print_section=0 have no frame or packet prefix, but unlike
+// SRT-like timestamps, they have no colons either.
+func isFFprobeCSV(line string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, "frame,") || strings.HasPrefix(line, "packet,") ||
+		(strings.Contains(line, ",") && !strings.Contains(line, ":"))
+}
+
+// isPacketFlags reports whether an ffprobe field holds packet flags, such as
+// K__ for a keyframe or ___ otherwise.
+func isPacketFlags(field string) bool {
+	return field != "" && strings.Trim(field, "KDC_") == "" && strings.ContainsAny(field, "K_")
+}
+
+func parseFFprobeKeyframes(lines []string, res *ShotChanges) error {
+	for _, line := range lines {
+		var ts string
+		keyframe := true
+		for _, field := range strings.Split(strings.TrimSpace(line), ",") {
+			switch {
+			case field == "frame", field == "packet", field == "":
+			case field == "I":
+			case field == "P", field == "B", field == "S", field == "SI", field == "SP", field == "BI":
+				keyframe = false
+			case isPacketFlags(field):
+				keyframe = field[0] == 'K'
+			default:
+				if ts == "" || (!strings.Contains(ts, ".") && strings.Contains(field, ".")) {
+					ts = field
+				}
+			}
+		}
+		if !keyframe || ts == "" {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(ts, 64)
+		if err != nil || seconds < 0 {
+			return errors.New("Invalid timestamp in keyframe list :" + line)
+		}
+		res.Times = append(res.Times, secondsToDuration(seconds))
+	}
+	if len(res.Times) == 0 {
+		return errors.New("The keyframe list has no keyframes")
+	}
+	return nil
+}
+
+func parseTimestampKeyframes(lines []string, res *ShotChanges) error {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.Contains(line, ":") {
+			d, err := TimestampToDurationSRT(line)
+			if err != nil {
+				return errors.New("Invalid timestamp in keyframe list :" + line)
+			}
+			res.Times = append(res.Times, d)
+			continue
+		}
+		seconds, err := strconv.ParseFloat(line, 64)
+		if err != nil || seconds < 0 {
+			return errors.New("Invalid timestamp in keyframe list :" + line)
+		}
+		res.Times = append(res.Times, secondsToDuration(seconds))
+	}
+	return nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds * float64(time.Second)))
+}
+
+// FramesToDuration returns the time at which a frame starts, for a given frame rate.
+func FramesToDuration(frames int, fps float64) time.Duration {
+	return secondsToDuration(float64(frames) / fps)
+}
+
+// nearestShotChange returns the shot change closest to d,
+// and whether it lies within the threshold.
+func (s ShotChanges) nearestShotChange(d, threshold time.Duration) (time.Duration, bool) {
+	i := sort.Search(len(s.Times), func(i int) bool { return s.Times[i] >= d })
+	best, found := time.Duration(0), false
+	for _, j := range []int{i - 1, i} {
+		if j < 0 || j >= len(s.Times) {
+			continue
+		}
+		dist := s.Times[j] - d
+		if dist < 0 {
+			dist = -dist
+		}
+		if dist <= threshold && (!found || dist < absDuration(best-d)) {
+			best, found = s.Times[j], true
+		}
+	}
+	return best, found
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// SnapToShotChanges moves subtitle Start times that are close to a shot
+// change onto it, and End times that are close to a shot change to
+// MinGapFrames before it, so that no subtitle lingers across a cut.
+// Adjustments that would make a subtitle overlap its neighbours or
+// end before it starts are not made.
+// It returns the adjusted file along with a report of every change.
+func SnapToShotChanges(subfile SubtitleFile, shots ShotChanges, opts SnapOptions) (SubtitleFile, []TimingAdjustment, error) {
+	var report []TimingAdjustment
+	res := SubtitleFile{make([]Subtitle, len(subfile.Subtitles)), subfile.Headers}
+	copy(res.Subtitles, subfile.Subtitles)
+
+	if opts.Threshold < 0 || opts.MinGapFrames < 0 {
+		return res, report, errors.New("The snapping threshold and minimum gap should not be negative")
+	}
+	fps := opts.FPS
+	if fps <= 0 {
+		fps = shots.FPS
+	}
+	if opts.MinGapFrames > 0 && fps <= 0 {
+		return res, report, errors.New("A frame rate is required to enforce a minimum gap in frames")
+	}
+	var gap time.Duration
+	if opts.MinGapFrames > 0 {
+		gap = FramesToDuration(opts.MinGapFrames, fps)
+	}
+
+	subs := res.Subtitles
+	for i := range subs {
+		if cut, ok := shots.nearestShotChange(subs[i].Start, opts.Threshold); ok && cut != subs[i].Start {
+			if cut < subs[i].End && (i == 0 || cut >= subs[i-1].End) {
+				report = append(report, TimingAdjustment{subs[i].Index, "Start", subs[i].Start, cut, cut})
+				subs[i].Start = cut
+			}
+		}
+		if cut, ok := shots.nearestShotChange(subs[i].End, opts.Threshold+gap); ok {
+			end := cut - gap
+			// A subtitle already ending on the cut is kept if no gap is required
+			if end != subs[i].End && end > subs[i].Start && (i == len(subs)-1 || end <= subs[i+1].Start) {
+				report = append(report, TimingAdjustment{subs[i].Index, "End", subs[i].End, end, cut})
+				subs[i].End = end
+			}
+		}
+	}
+	return res, report, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseKeyframeFile(t *testing.T) {
+	type testpair struct {
+		input       string
+		fps         float64
+		expected    ShotChanges
+		expectedErr error
+	}
+
+	cuts := []time.Duration{0, 2 * time.Second, 5 * time.Second, 12 * time.Second}
+	var tests = []testpair{
+		{"samples/keyframes_aegisub.txt", 0, ShotChanges{25, cuts}, nil},
+		{"samples/keyframes_ffprobe.csv", 0, ShotChanges{0, cuts}, nil},
+		{"samples/keyframes_ffprobe_packets.csv", 0, ShotChanges{0, cuts}, nil},
+		{"samples/keyframes_timestamps.txt", 23.976, ShotChanges{23.976, cuts}, nil},
+		{"wrongfilename", 0, ShotChanges{}, errors.New("Could not open file wrongfilename for reading")},
+	}
+
+	for _, pair := range tests {
+		actual, err := ParseKeyframeFile(pair.input, pair.fps)
+		if pair.expectedErr != nil {
+			if err == nil || err.Error() != pair.expectedErr.Error() {
+				t.Errorf("Testing ParseKeyframeFile using %v. Expected error %v but got %v instead!", pair.input, pair.expectedErr, err)
+			}
+			continue
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing ParseKeyframeFile using %v. Expected %v but got %v instead!", pair.input, pair.expected, actual)
+		}
+	}
+}
+
+func TestParseKeyframes(t *testing.T) {
+	type testpair struct {
+		input       string
+		fps         float64
+		expected    []time.Duration
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{"# keyframe format v1\nfps 0\n0\n24\n", 24, []time.Duration{0, time.Second}, nil},
+		{"# keyframe format v1\nfps 0\n0\n24\n", 0, nil, errors.New("The keyframe list does not state a frame rate, and none was provided")},
+		{"# keyframe format v1\nfps 25\nten\n", 0, nil, errors.New("Invalid frame number in keyframe list :ten")},
+		{"frame,1,3.003000,I\r\nframe,0,3.045000,P\r\nframe,1,1.001000,I\r\n", 0, []time.Duration{1001 * time.Millisecond, 3003 * time.Millisecond}, nil},
+		{"packet,1.001000,K__\npacket,1.042000,___\npacket,3.003000,K_\n", 0, []time.Duration{1001 * time.Millisecond, 3003 * time.Millisecond}, nil},
+		{"3.003000,I\n3.045000,P\n", 0, []time.Duration{3003 * time.Millisecond}, nil},
+		{"frame,3.045000,P\nframe,3.087000,B\n", 0, nil, errors.New("The keyframe list has no keyframes")},
+		{"12.5\n3\n", 0, []time.Duration{3 * time.Second, 12500 * time.Millisecond}, nil},
+		{"00:61:00,000\n", 0, nil, errors.New("Invalid timestamp in keyframe list :00:61:00,000")},
+		{"", 0, nil, errors.New("The keyframe list is empty")},
+	}
+
+	for _, pair := range tests {
+		actual, err := ParseKeyframes(pair.input, pair.fps)
+		if pair.expectedErr != nil {
+			if err == nil || err.Error() != pair.expectedErr.Error() {
+				t.Errorf("Testing ParseKeyframes using %q. Expected error %v but got %v instead!", pair.input, pair.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil || !cmp.Equal(actual.Times, pair.expected) {
+			t.Errorf("Testing ParseKeyframes using %q. Expected %v but got %v (%v) instead!", pair.input, pair.expected, actual.Times, err)
+		}
+	}
+}
+
+func TestSnapToShotChanges(t *testing.T) {
+	shots := ShotChanges{25, []time.Duration{0, 2 * time.Second, 5 * time.Second, 12 * time.Second}}
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Millisecond * 200), time.Duration(time.Second*1 + time.Millisecond*900), `one`, "", ""},
+			{2, time.Duration(time.Second*2 + time.Millisecond*100), time.Duration(time.Second*4 + time.Millisecond*950), `two`, "", ""},
+			{3, time.Duration(time.Second*5 + time.Millisecond*500), time.Duration(time.Second * 8), `three`, "", ""},
+			{4, time.Duration(time.Second*11 + time.Millisecond*900), time.Duration(time.Second*12 + time.Millisecond*100), `four`, "", ""},
+		},
+		"headers",
+	}
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, 0, time.Duration(time.Second*1 + time.Millisecond*920), `one`, "", ""},
+			{2, time.Duration(time.Second * 2), time.Duration(time.Second*4 + time.Millisecond*920), `two`, "", ""},
+			{3, time.Duration(time.Second*5 + time.Millisecond*500), time.Duration(time.Second * 8), `three`, "", ""},
+			{4, time.Duration(time.Second * 12), time.Duration(time.Second*12 + time.Millisecond*100), `four`, "", ""},
+		},
+		"headers",
+	}
+	expectedReport := []TimingAdjustment{
+		{1, "Start", 200 * time.Millisecond, 0, 0},
+		{1, "End", 1900 * time.Millisecond, 1920 * time.Millisecond, 2 * time.Second},
+		{2, "Start", 2100 * time.Millisecond, 2 * time.Second, 2 * time.Second},
+		{2, "End", 4950 * time.Millisecond, 4920 * time.Millisecond, 5 * time.Second},
+		{4, "Start", 11900 * time.Millisecond, 12 * time.Second, 12 * time.Second},
+	}
+
+	actual, report, err := SnapToShotChanges(input, shots, SnapOptions{Threshold: 300 * time.Millisecond, MinGapFrames: 2})
+	if err != nil {
+		t.Errorf("Testing SnapToShotChanges. Got unexpected error %v", err)
+	}
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("Testing SnapToShotChanges. Expected %v but got %v instead!", expected, actual)
+	}
+	if !cmp.Equal(report, expectedReport) {
+		t.Errorf("Testing SnapToShotChanges. Expected report %v but got %v instead!", expectedReport, report)
+	}
+	if input.Subtitles[0].Start != 200*time.Millisecond {
+		t.Errorf("Testing SnapToShotChanges. The input subtitle file was modified")
+	}
+
+	_, _, err = SnapToShotChanges(input, ShotChanges{0, shots.Times}, SnapOptions{Threshold: time.Second, MinGapFrames: 2})
+	if err == nil || err.Error() != "A frame rate is required to enforce a minimum gap in frames" {
+		t.Errorf("Testing SnapToShotChanges without a frame rate. Got unexpected error %v", err)
+	}
+}
+
+func TestTimingAdjustmentString(t *testing.T) {
+	actual := TimingAdjustment{4, "End", 1900 * time.Millisecond, 1920 * time.Millisecond, 2 * time.Second}.String()
+	expected := "Subtitle 4 : End moved from 00:00:01,900 to 00:00:01,920 (shot change at 00:00:02,000)"
+	if actual != expected {
+		t.Errorf("Testing TimingAdjustment.String. Expected %v but got %v instead!", expected, actual)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SplitOptions configures SplitLongCues. Subtitles with more than MaxChars
+// characters to read, or shown for longer than MaxDuration, are split into
+// consecutive ones, MinGap apart. MaxDuration only decides the number of
+// parts, as time is shared out by the length of their text. Each new
+// subtitle is laid out according to Layout, or kept on a single line if
+// it's zero. Zero limits are not checked.
+type SplitOptions struct {
+	MaxChars    int
+	MaxDuration time.Duration
+	MinGap      time.Duration
+	Layout      ReflowOptions
+}
+
+// MergeOptions configures MergeShortCues. Consecutive subtitles are merged
+// when at least one of them is shown for less than MinDuration, there's no
+// more than MaxGap between them, and the merged subtitle would be no longer
+// than MaxDuration and MaxChars. The merged text is laid out according to
+// Layout, or its lines are stacked if it's zero, and subtitles that would
+// not fit are left alone.
+type MergeOptions struct {
+	MinDuration time.Duration
+	MaxGap      time.Duration
+	MaxDuration time.Duration
+	MaxChars    int
+	Layout      ReflowOptions
+}
+
+// splitPoints returns the indices of the units starting each piece after
+// the first, splitting the units into pieces of at most limit columns,
+// preferring sentence and clause boundaries over evenly sized pieces.
+func splitPoints(units []reflowUnit, pieces, limit int) ([]int, bool) {
+	type result struct {
+		cuts []int
+		cost int
+		ok   bool
+	}
+	memo := map[[2]int]result{}
+	var best func(from, pieces int) result
+	best = func(from, pieces int) result {
+		if pieces == 1 {
+			return result{nil, 0, lineWidth(units, from, len(units)) <= limit}
+		}
+		if res, ok := memo[[2]int{from, pieces}]; ok {
+			return res
+		}
+		var res result
+		ideal := lineWidth(units, from, len(units)) / pieces
+		for cut := from + 1; cut <= len(units)-pieces+1; cut++ {
+			width := lineWidth(units, from, cut)
+			if width > limit {
+				break
+			}
+			rest := best(cut, pieces-1)
+			if !rest.ok {
+				continue
+			}
+			diff := width - ideal
+			if diff < 0 {
+				diff = -diff
+			}
+			cost := rest.cost + 10*breakPenalty(units[cut-1], units[cut]) + diff
+			if !res.ok || cost < res.cost {
+				res = result{append([]int{cut}, rest.cuts...), cost, true}
+			}
+		}
+		memo[[2]int{from, pieces}] = res
+		return res
+	}
+	res := best(0, pieces)
+	return res.cuts, res.ok
+}
+
+// balanceTags closes the formatting tags left open at the end of each
+// piece of text, and opens them again at the start of the next one.
+func balanceTags(pieces []string) []string {
+	res := make([]string, len(pieces))
+	var open []string
+	for i, piece := range pieces {
+		prefix := strings.Join(open, "")
+		for _, m := range tagRegexp.FindAllStringSubmatch(piece, -1) {
+			if m[1] == "" {
+				open = append(open, m[0])
+			} else if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+		suffix := ""
+		for j := len(open) - 1; j >= 0; j-- {
+			suffix += "</" + strings.ToLower(tagRegexp.FindStringSubmatch(open[j])[2]) + ">"
+		}
+		res[i] = prefix + piece + suffix
+	}
+	return res
+}
+
+// splitText joins the units back into a piece of text for each of the
+// cuts, keeping the formatting tags balanced within each piece.
+func splitText(units []reflowUnit, cuts []int) []string {
+	var texts []string
+	from := 0
+	for _, cut := range append(cuts, len(units)) {
+		var b strings.Builder
+		for i := from; i < cut; i++ {
+			if i > from && units[i].spaceBefore {
+				b.WriteString(" ")
+			}
+			b.WriteString(units[i].text)
+		}
+		texts = append(texts, b.String())
+		from = cut
+	}
+	return balanceTags(texts)
+}
+
+// layoutText lays out the text of a subtitle, keeping it on a single line
+// if no limits are given.
+func layoutText(content string, layout ReflowOptions) (string, error) {
+	if layout.MaxLines == 0 && layout.MaxLineLength == 0 {
+		layout.MaxLines = 1
+	}
+	return ReflowText(content, layout)
+}
+
+// SplitCue splits a single subtitle at sentence or clause boundaries, so
+// that every part respects the limits, sharing out its time in proportion
+// to the length of each part's text. The parts keep the original Index.
+func SplitCue(sub Subtitle, opts SplitOptions) ([]Subtitle, error) {
+	if opts.MaxChars < 0 || opts.MaxDuration < 0 || opts.MinGap < 0 {
+		return []Subtitle{sub}, errors.New("The split limits and gap should not be negative")
+	}
+	units := reflowUnits(sub.Content)
+	total := CountReadingChars(sub.Content, ReadingOptions{})
+	pieces := 1
+	if opts.MaxChars > 0 {
+		pieces = (total + opts.MaxChars - 1) / opts.MaxChars
+	}
+	if duration := sub.End - sub.Start; opts.MaxDuration > 0 && duration > opts.MaxDuration {
+		if n := int((duration + opts.MaxDuration - 1) / opts.MaxDuration); n > pieces {
+			pieces = n
+		}
+	}
+	if pieces <= 1 {
+		return []Subtitle{sub}, nil
+	}
+
+	limit := opts.MaxChars
+	if limit == 0 {
+		limit = lineWidth(units, 0, len(units))
+	}
+	var cuts []int
+	found := false
+	for ; pieces <= len(units) && !found; pieces++ {
+		cuts, found = splitPoints(units, pieces, limit)
+	}
+	if !found {
+		return []Subtitle{sub}, errors.New("Subtitle " + strconv.Itoa(sub.Index) + " cannot be split into parts of " + strconv.Itoa(opts.MaxChars) + " characters")
+	}
+
+	texts := splitText(units, cuts)
+
+	gap := opts.MinGap
+	available := sub.End - sub.Start - gap*time.Duration(len(texts)-1)
+	if available <= 0 {
+		gap, available = 0, sub.End-sub.Start
+	}
+	res := make([]Subtitle, len(texts))
+	start, done := sub.Start, 0
+	for i, text := range texts {
+		content, err := layoutText(text, opts.Layout)
+		if err != nil {
+			return []Subtitle{sub}, err
+		}
+		done += CountReadingChars(text, ReadingOptions{})
+		end := sub.Start + gap*time.Duration(i) + time.Duration(float64(available)*float64(done)/float64(total)).Round(time.Millisecond)
+		if i == len(texts)-1 {
+			end = sub.End
+		}
+		res[i] = Subtitle{sub.Index, start, end, content, sub.Metadata, sub.Header}
+		start = end + gap
+	}
+	return res, nil
+}
+
+// SplitLongCues splits every subtitle that is too long to read comfortably,
+// see SplitCue. Subtitles that cannot be split are left unchanged and
+// reported. The result is renumbered.
+func SplitLongCues(subfile SubtitleFile, opts SplitOptions) (SubtitleFile, []error) {
+	var errs []error
+	var subs []Subtitle
+	for _, sub := range subfile.Subtitles {
+		parts, err := SplitCue(sub, opts)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		subs = append(subs, parts...)
+	}
+	return SerializeSubtitles(SubtitleFile{subs, subfile.Headers}), errs
+}
+
+// isDialogue reports whether a subtitle's text is a line of dialogue,
+// starting with a dash.
+func isDialogue(content string) bool {
+	return strings.HasPrefix(strings.TrimSpace(StripMarkup(content)), "-")
+}
+
+// mergeText joins the text of two subtitles, keeping lines of dialogue apart.
+func mergeText(a, b string, layout ReflowOptions) (string, error) {
+	if isDialogue(a) || isDialogue(b) {
+		res := a + "\n" + b
+		if layout.MaxLines > 0 && strings.Count(res, "\n")+1 > layout.MaxLines {
+			return a, errors.New("Too many lines of dialogue")
+		}
+		return res, nil
+	}
+	if layout.MaxLines == 0 && layout.MaxLineLength == 0 {
+		return a + "\n" + b, nil
+	}
+	return ReflowText(a+" "+b, layout)
+}
+
+// MergeShortCues joins runs of consecutive short subtitles, such as
+// dialogue fragmented into many brief ones, within the limits of the
+// options. The merged subtitle spans from the first Start to the last End.
+// The result is renumbered.
+func MergeShortCues(subfile SubtitleFile, opts MergeOptions) (SubtitleFile, error) {
+	if opts.MinDuration < 0 || opts.MaxGap < 0 || opts.MaxDuration < 0 || opts.MaxChars < 0 {
+		return subfile, errors.New("The merge limits and gap should not be negative")
+	}
+	var subs []Subtitle
+	for _, sub := range subfile.Subtitles {
+		if len(subs) == 0 {
+			subs = append(subs, sub)
+			continue
+		}
+		last := &subs[len(subs)-1]
+		short := last.End-last.Start < opts.MinDuration || sub.End-sub.Start < opts.MinDuration
+		fits := sub.Start >= last.End && sub.Start-last.End <= opts.MaxGap &&
+			(opts.MaxDuration == 0 || sub.End-last.Start <= opts.MaxDuration) &&
+			(opts.MaxChars == 0 || CountReadingChars(last.Content+sub.Content, ReadingOptions{}) <= opts.MaxChars)
+		if !short || !fits {
+			subs = append(subs, sub)
+			continue
+		}
+		content, err := mergeText(last.Content, sub.Content, opts.Layout)
+		if err != nil {
+			subs = append(subs, sub)
+			continue
+		}
+		last.End, last.Content = sub.End, content
+	}
+	return SerializeSubtitles(SubtitleFile{subs, subfile.Headers}), nil
+}
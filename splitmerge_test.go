@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitCue(t *testing.T) {
+	type testpair struct {
+		input       Subtitle
+		opts        SplitOptions
+		expected    []Subtitle
+		expectedErr error
+	}
+
+	long := Subtitle{7, time.Second, 7 * time.Second, "We have all suffered. We have all lost people we love,\nbut this is not about the noble houses.", "", ""}
+	var tests = []testpair{
+		{
+			long,
+			SplitOptions{MaxChars: 42, MinGap: 80 * time.Millisecond},
+			[]Subtitle{
+				{7, time.Second, time.Second*2 + time.Millisecond*319, "We have all suffered.", "", ""},
+				{7, time.Second*2 + time.Millisecond*399, time.Second*4 + time.Millisecond*408, "We have all lost people we love,", "", ""},
+				{7, time.Second*4 + time.Millisecond*488, 7 * time.Second, "but this is not about the noble houses.", "", ""},
+			},
+			nil,
+		},
+		{
+			long,
+			SplitOptions{MaxDuration: 4 * time.Second, Layout: ReflowOptions{2, 42, BottomHeavy}},
+			[]Subtitle{
+				{7, time.Second, time.Second*2 + time.Millisecond*355, "We have all suffered.", "", ""},
+				{7, time.Second*2 + time.Millisecond*355, 7 * time.Second, "We have all lost people we love,\nbut this is not about the noble houses.", "", ""},
+			},
+			nil,
+		},
+		{
+			Subtitle{3, time.Second, 3 * time.Second, "<i>I will come back, I promise you, no matter what</i>", "", ""},
+			SplitOptions{MaxChars: 30},
+			[]Subtitle{
+				{3, time.Second, time.Second + time.Millisecond*723, "<i>I will come back,</i>", "", ""},
+				{3, time.Second + time.Millisecond*723, 3 * time.Second, "<i>I promise you, no matter what</i>", "", ""},
+			},
+			nil,
+		},
+		{
+			long,
+			SplitOptions{MaxChars: 100},
+			[]Subtitle{long},
+			nil,
+		},
+		{
+			Subtitle{4, time.Second, 3 * time.Second, "Supercalifragilisticexpialidocious indeed", "", ""},
+			SplitOptions{MaxChars: 20},
+			[]Subtitle{{4, time.Second, 3 * time.Second, "Supercalifragilisticexpialidocious indeed", "", ""}},
+			errors.New("Subtitle 4 cannot be split into parts of 20 characters"),
+		},
+		{
+			long,
+			SplitOptions{MaxChars: -1},
+			[]Subtitle{long},
+			errors.New("The split limits and gap should not be negative"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, err := SplitCue(pair.input, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing SplitCue with %+v. Expected error %v but got %v instead!", pair.opts, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing SplitCue with %+v. Expected %v but got %v instead!", pair.opts, pair.expected, actual)
+		}
+	}
+}
+
+func TestSplitLongCues(t *testing.T) {
+	in := SubtitleFile{
+		[]Subtitle{
+			{1, 0, time.Second, "Short.", "", ""},
+			{2, 2 * time.Second, 4 * time.Second, "First sentence here. Second sentence there.", "", ""},
+			{3, 5 * time.Second, 6 * time.Second, "Supercalifragilisticexpialidocious", "", ""},
+		},
+		"headers",
+	}
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, 0, time.Second, "Short.", "", ""},
+			{2, 2 * time.Second, 2*time.Second + 930*time.Millisecond, "First sentence here.", "", ""},
+			{3, 2*time.Second + 930*time.Millisecond, 4 * time.Second, "Second sentence there.", "", ""},
+			{4, 5 * time.Second, 6 * time.Second, "Supercalifragilisticexpialidocious", "", ""},
+		},
+		"headers",
+	}
+	expectedErrs := []error{errors.New("Subtitle 3 cannot be split into parts of 25 characters")}
+
+	actual, errs := SplitLongCues(in, SplitOptions{MaxChars: 25})
+	if !cmp.Equal(actual, expected) || !ErrorSlicesEqual(errs, expectedErrs) {
+		t.Errorf("Testing SplitLongCues. Expected %v, %v but got %v, %v instead!", expected, expectedErrs, actual, errs)
+	}
+}
+
+func TestMergeShortCues(t *testing.T) {
+	type testpair struct {
+		opts        MergeOptions
+		expected    SubtitleFile
+		expectedErr error
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, 0, 500 * time.Millisecond, "Wait,", "", ""},
+			{2, 600 * time.Millisecond, 1100 * time.Millisecond, "don't go.", "", ""},
+			{3, 1200 * time.Millisecond, 1700 * time.Millisecond, "Please.", "", ""},
+			{4, 5 * time.Second, 5500 * time.Millisecond, "- Who?", "", ""},
+			{5, 5600 * time.Millisecond, 6 * time.Second, "- Me.", "", ""},
+			{6, 9 * time.Second, 12 * time.Second, "A long, comfortable subtitle.", "", ""},
+		},
+		"headers",
+	}
+
+	var tests = []testpair{
+		{
+			MergeOptions{MinDuration: time.Second, MaxGap: 200 * time.Millisecond, Layout: ReflowOptions{2, 42, BottomHeavy}},
+			SubtitleFile{
+				[]Subtitle{
+					{1, 0, 1700 * time.Millisecond, "Wait, don't go. Please.", "", ""},
+					{2, 5 * time.Second, 6 * time.Second, "- Who?\n- Me.", "", ""},
+					{3, 9 * time.Second, 12 * time.Second, "A long, comfortable subtitle.", "", ""},
+				},
+				"headers",
+			},
+			nil,
+		},
+		{
+			MergeOptions{MinDuration: time.Second, MaxGap: 200 * time.Millisecond, MaxChars: 16},
+			SubtitleFile{
+				[]Subtitle{
+					{1, 0, 1100 * time.Millisecond, "Wait,\ndon't go.", "", ""},
+					{2, 1200 * time.Millisecond, 1700 * time.Millisecond, "Please.", "", ""},
+					{3, 5 * time.Second, 6 * time.Second, "- Who?\n- Me.", "", ""},
+					{4, 9 * time.Second, 12 * time.Second, "A long, comfortable subtitle.", "", ""},
+				},
+				"headers",
+			},
+			nil,
+		},
+		{
+			MergeOptions{MinDuration: time.Second, MaxGap: 50 * time.Millisecond},
+			input,
+			nil,
+		},
+		{
+			MergeOptions{MaxGap: -time.Second},
+			input,
+			errors.New("The merge limits and gap should not be negative"),
+		},
+	}
+
+	for _, pair := range tests {
+		actual, err := MergeShortCues(input, pair.opts)
+		if (err == nil) != (pair.expectedErr == nil) || (err != nil && err.Error() != pair.expectedErr.Error()) {
+			t.Errorf("Testing MergeShortCues with %+v. Expected error %v but got %v instead!", pair.opts, pair.expectedErr, err)
+		}
+		if !cmp.Equal(actual, pair.expected) {
+			t.Errorf("Testing MergeShortCues with %+v. Expected %v but got %v instead!", pair.opts, pair.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TextStyle holds the formatting of a run of subtitle text. Color is either
+// a name, eg. "yellow", or a "#rrggbb" value. Voice is the WebVTT speaker,
+// Class the dot-separated WebVTT classes, and Ruby the annotation shown
+// above the text, as used for Japanese furigana.
+type TextStyle struct {
+	Bold          bool
+	Italic        bool
+	Underline     bool
+	Strikethrough bool
+	Color         string
+	Voice         string
+	Class         string
+	Ruby          string
+}
+
+// Span is a run of text sharing the same style. Text may contain line breaks.
+type Span struct {
+	Text  string
+	Style TextStyle
+}
+
+// StyledText is the parsed form of a subtitle's content, along with its
+// position on screen.
+type StyledText struct {
+	Spans    []Span
+	Position Position
+}
+
+// namedColors are the colour classes predefined by WebVTT, with their values.
+var namedColors = map[string]string{
+	"white": "#ffffff", "lime": "#00ff00", "cyan": "#00ffff", "red": "#ff0000",
+	"yellow": "#ffff00", "magenta": "#ff00ff", "blue": "#0000ff", "black": "#000000",
+}
+
+// colorName returns the WebVTT class for a colour, or an empty string if
+// there's none for it.
+func colorName(color string) string {
+	if _, ok := namedColors[color]; ok {
+		return color
+	}
+	for name, value := range namedColors {
+		if value == color {
+			return name
+		}
+	}
+	return ""
+}
+
+// colorValue returns the "#rrggbb" value of a colour, or an empty string
+// if it's not known.
+func colorValue(color string) string {
+	if value, ok := namedColors[color]; ok {
+		return value
+	}
+	if len(color) == 7 && strings.HasPrefix(color, "#") {
+		if _, err := strconv.ParseUint(color[1:], 16, 32); err == nil {
+			return color
+		}
+	}
+	return ""
+}
+
+var (
+	markupTagRegexp = regexp.MustCompile(`^<(/?)([a-zA-Z]+)((?:\.[\w-]+)*)([^>]*)>`)
+	colorAttrRegexp = regexp.MustCompile(`(?i)color\s*=\s*["']?([^"'\s>]+)`)
+	assBlockRegexp  = regexp.MustCompile(`^\{(\\[^}]*)\}`)
+	assColorRegexp  = regexp.MustCompile(`^1?c&H([0-9a-fA-F]{1,8})&?$`)
+)
+
+// styleParser builds a StyledText out of markup, one tag at a time.
+type styleParser struct {
+	res    StyledText
+	style  TextStyle
+	stack  []tagState
+	inRuby bool
+	// rubyBase starts a new span for the text annotated by a <ruby> tag
+	rubyBase bool
+}
+
+// tagState remembers the style before an HTML-like tag was opened.
+type tagState struct {
+	name  string
+	style TextStyle
+}
+
+func (p *styleParser) text(s string) {
+	if s == "" {
+		return
+	}
+	if p.inRuby {
+		if n := len(p.res.Spans); n > 0 {
+			p.res.Spans[n-1].Style.Ruby += s
+		}
+		return
+	}
+	if n := len(p.res.Spans); n > 0 && p.res.Spans[n-1].Style == p.style && !p.rubyBase {
+		p.res.Spans[n-1].Text += s
+		return
+	}
+	p.res.Spans = append(p.res.Spans, Span{s, p.style})
+	p.rubyBase = false
+}
+
+func (p *styleParser) openTag(name, classes, attrs string) {
+	p.stack = append(p.stack, tagState{name, p.style})
+	switch name {
+	case "b":
+		p.style.Bold = true
+	case "i":
+		p.style.Italic = true
+	case "u":
+		p.style.Underline = true
+	case "s":
+		p.style.Strikethrough = true
+	case "font":
+		if m := colorAttrRegexp.FindStringSubmatch(attrs); m != nil {
+			p.style.Color = strings.ToLower(m[1])
+		}
+	case "v":
+		p.style.Voice = strings.TrimSpace(attrs)
+	case "ruby":
+		p.rubyBase = true
+	case "rt":
+		p.inRuby = true
+	}
+	if classes != "" {
+		for _, class := range strings.Split(classes[1:], ".") {
+			if _, ok := namedColors[class]; ok {
+				p.style.Color = class
+				continue
+			}
+			if p.style.Class != "" {
+				p.style.Class += "."
+			}
+			p.style.Class += class
+		}
+	}
+}
+
+func (p *styleParser) closeTag(name string) {
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i].name != name {
+			continue
+		}
+		p.style = p.stack[i].style
+		p.stack = p.stack[:i]
+		if name == "rt" {
+			p.inRuby = false
+		}
+		return
+	}
+}
+
+// assOverrides applies an SSA/ASS override block, eg. {\an8\i1}.
+func (p *styleParser) assOverrides(block string) {
+	for _, o := range strings.Split(block, `\`)[1:] {
+		switch {
+		case strings.HasPrefix(o, "an") && len(o) == 3:
+			if n, err := strconv.Atoi(o[2:]); err == nil {
+				p.res.Position.Alignment = n
+			}
+		case strings.HasPrefix(o, "pos(") && strings.HasSuffix(o, ")"):
+			if x, y, ok := parseASSPos(o[4 : len(o)-1]); ok {
+				p.res.Position.X, p.res.Position.Y, p.res.Position.Explicit = x, y, true
+			}
+		case o == "i1" || o == "i0":
+			p.style.Italic = o == "i1"
+		case o == "b1" || o == "b0":
+			p.style.Bold = o == "b1"
+		case o == "u1" || o == "u0":
+			p.style.Underline = o == "u1"
+		case o == "s1" || o == "s0":
+			p.style.Strikethrough = o == "s1"
+		case o == "c" || o == "1c":
+			p.style.Color = ""
+		case o == "r":
+			p.style = TextStyle{}
+		case assColorRegexp.MatchString(o):
+			// ASS colours are written as &HBBGGRR&
+			hex := assColorRegexp.FindStringSubmatch(o)[1]
+			hex = strings.Repeat("0", 8-len(hex)) + strings.ToLower(hex)
+			p.style.Color = "#" + hex[6:8] + hex[4:6] + hex[2:4]
+		}
+	}
+}
+
+var webVTTEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&nbsp;", " ", "&lrm;", "‎", "&rlm;", "‏", "&amp;", "&")
+
+// markupEntities are the character references of text that would otherwise
+// be read as markup, see escapeMarkup.
+var markupEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+var (
+	markupEscaper      = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	markupEscapeRegexp = regexp.MustCompile(`&(lt|gt|amp);|</?[a-zA-Z][^>]*>`)
+)
+
+// escapeMarkup escapes the text that ParseStyledText would read as a tag or
+// a character reference, eg. "use <i> for italics", leaving the rest as it is.
+func escapeMarkup(text string) string {
+	return markupEscapeRegexp.ReplaceAllStringFunc(text, markupEscaper.Replace)
+}
+
+func parseStyledText(content string, decodeEntities bool) StyledText {
+	p := &styleParser{}
+	start := 0
+	flush := func(end int) {
+		text := content[start:end]
+		if decodeEntities {
+			text = webVTTEntities.Replace(text)
+		} else {
+			text = markupEntities.Replace(text)
+		}
+		p.text(text)
+	}
+	for i := 0; i < len(content); {
+		switch content[i] {
+		case '<':
+			if m := markupTagRegexp.FindStringSubmatch(content[i:]); m != nil {
+				flush(i)
+				name := strings.ToLower(m[2])
+				if m[1] == "" {
+					p.openTag(name, m[3], m[4])
+				} else {
+					p.closeTag(name)
+				}
+				i += len(m[0])
+				start = i
+				continue
+			}
+		case '{':
+			if m := assBlockRegexp.FindStringSubmatch(content[i:]); m != nil {
+				flush(i)
+				p.assOverrides(m[1])
+				i += len(m[0])
+				start = i
+				continue
+			}
+		}
+		i++
+	}
+	flush(len(content))
+	return p.res
+}
+
+// ParseStyledText parses the content of a subtitle, as written in SRT files,
+// understanding HTML-like tags such as <i>, <b>, <u>, <s> and <font color>,
+// the WebVTT <c.class>, <v Speaker> and <ruby> tags and SSA/ASS override
+// blocks such as {\an8} and {\i1}. Unknown tags are dropped, and text that
+// was escaped not to be read as markup, eg. "&lt;i&gt;", is decoded.
+func ParseStyledText(content string) StyledText {
+	return parseStyledText(content, false)
+}
+
+// ParseWebVTTText parses the text of a WebVTT cue, like ParseStyledText,
+// also decoding character references such as &amp;.
+func ParseWebVTTText(content string) StyledText {
+	return parseStyledText(content, true)
+}
+
+// PlainText returns the text without any formatting, the way a viewer reads it.
+func (t StyledText) PlainText() string {
+	var b strings.Builder
+	for _, span := range t.Spans {
+		b.WriteString(span.Text)
+	}
+	return b.String()
+}
+
+// markupTag is a pair of opening and closing tags.
+type markupTag struct {
+	open  string
+	close string
+}
+
+func containsTag(tags []markupTag, tag markupTag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// render serialises the spans, keeping tags shared by consecutive spans
+// open, so that "<i>a <b>b</b></i>" is written back the same way.
+func (t StyledText) render(tags func(TextStyle) []markupTag, text func(Span) string) string {
+	var b strings.Builder
+	var open []markupTag
+	for _, span := range append(t.Spans, Span{}) {
+		want := map[markupTag]bool{}
+		for _, tag := range tags(span.Style) {
+			want[tag] = true
+		}
+		kept := 0
+		for kept < len(open) && want[open[kept]] {
+			kept++
+		}
+		for i := len(open) - 1; i >= kept; i-- {
+			b.WriteString(open[i].close)
+		}
+		open = open[:kept]
+		for _, tag := range tags(span.Style) {
+			if !containsTag(open[:kept], tag) {
+				b.WriteString(tag.open)
+				open = append(open, tag)
+			}
+		}
+		b.WriteString(text(span))
+	}
+	return b.String()
+}
+
+// srtTags returns the HTML-like tags of SRT files for a style.
+func srtTags(s TextStyle) []markupTag {
+	var tags []markupTag
+	if s.Color != "" {
+		tags = append(tags, markupTag{`<font color="` + s.Color + `">`, "</font>"})
+	}
+	for _, tag := range []struct {
+		on   bool
+		name string
+	}{{s.Bold, "b"}, {s.Italic, "i"}, {s.Underline, "u"}, {s.Strikethrough, "s"}} {
+		if tag.on {
+			tags = append(tags, markupTag{"<" + tag.name + ">", "</" + tag.name + ">"})
+		}
+	}
+	return tags
+}
+
+// withPosition prefixes the text with an ASS override block for the position.
+func (t StyledText) withPosition(text string) string {
+	if overrides := t.Position.assOverrides(); overrides != "" {
+		return "{" + overrides + "}" + text
+	}
+	return text
+}
+
+// SRT serialises the text for SRT files, using HTML-like tags and an ASS
+// override block for the position. Voices, classes and ruby annotations
+// cannot be represented, and are dropped.
+func (t StyledText) SRT() string {
+	return t.withPosition(t.render(srtTags, func(span Span) string { return escapeMarkup(span.Text) }))
+}
+
+// Markup serialises the text in the form kept in Subtitle.Content, which
+// ParseStyledText reads back whatever the file was parsed from: the markup
+// of SRT files, along with the WebVTT voice, class and ruby tags that SRT
+// lacks. Text that would be read as markup is escaped. Writers then convert
+// it to the syntax of their format.
+func (t StyledText) Markup() string {
+	return t.withPosition(t.render(func(s TextStyle) []markupTag {
+		var tags []markupTag
+		if s.Voice != "" {
+			tags = append(tags, markupTag{"<v " + s.Voice + ">", "</v>"})
+		}
+		if s.Class != "" {
+			tags = append(tags, markupTag{"<c." + s.Class + ">", "</c>"})
+		}
+		return append(tags, srtTags(s)...)
+	}, func(span Span) string {
+		if span.Style.Ruby != "" {
+			return "<ruby>" + escapeMarkup(span.Text) + "<rt>" + escapeMarkup(span.Style.Ruby) + "</rt></ruby>"
+		}
+		return escapeMarkup(span.Text)
+	}))
+}
+
+var webVTTEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// WebVTT serialises the text for WebVTT cues. Colours become classes, when
+// WebVTT has one for them, and strikethrough, which WebVTT lacks, is
+// dropped, as is the position, which belongs to the cue settings.
+func (t StyledText) WebVTT() string {
+	return t.render(func(s TextStyle) []markupTag {
+		var tags []markupTag
+		if s.Voice != "" {
+			tags = append(tags, markupTag{"<v " + s.Voice + ">", "</v>"})
+		}
+		class := s.Class
+		if name := colorName(s.Color); name != "" {
+			class = strings.TrimPrefix(class+"."+name, ".")
+		}
+		if class != "" {
+			tags = append(tags, markupTag{"<c." + class + ">", "</c>"})
+		}
+		for _, tag := range []struct {
+			on   bool
+			name string
+		}{{s.Bold, "b"}, {s.Italic, "i"}, {s.Underline, "u"}} {
+			if tag.on {
+				tags = append(tags, markupTag{"<" + tag.name + ">", "</" + tag.name + ">"})
+			}
+		}
+		return tags
+	}, func(span Span) string {
+		if span.Style.Ruby != "" {
+			return "<ruby>" + webVTTEscaper.Replace(span.Text) + "<rt>" + webVTTEscaper.Replace(span.Style.Ruby) + "</rt></ruby>"
+		}
+		return webVTTEscaper.Replace(span.Text)
+	})
+}
+
+// ASS serialises the text for SSA/ASS events, using override blocks that
+// turn formatting on and off, and \N for line breaks. Voices, classes and
+// ruby annotations cannot be represented, and are dropped.
+func (t StyledText) ASS() string {
+	var b strings.Builder
+	var prev TextStyle
+	overrides := t.Position.assOverrides()
+	for _, span := range t.Spans {
+		for _, toggle := range []struct {
+			was, is bool
+			tag     string
+		}{
+			{prev.Bold, span.Style.Bold, "b"},
+			{prev.Italic, span.Style.Italic, "i"},
+			{prev.Underline, span.Style.Underline, "u"},
+			{prev.Strikethrough, span.Style.Strikethrough, "s"},
+		} {
+			switch {
+			case toggle.is && !toggle.was:
+				overrides += `\` + toggle.tag + "1"
+			case toggle.was && !toggle.is:
+				overrides += `\` + toggle.tag + "0"
+			}
+		}
+		if was, is := colorValue(prev.Color), colorValue(span.Style.Color); was != is {
+			// An empty \c restores the colour of the style
+			overrides += `\c`
+			if is != "" {
+				overrides += "&H" + strings.ToUpper(is[5:7]+is[3:5]+is[1:3]) + "&"
+			}
+		}
+		if overrides != "" {
+			b.WriteString("{" + overrides + "}")
+			overrides = ""
+		}
+		b.WriteString(strings.Replace(span.Text, "\n", `\N`, -1))
+		prev = span.Style
+	}
+	if overrides != "" {
+		b.WriteString("{" + overrides + "}")
+	}
+	return b.String()
+}
+
+// Styled returns the parsed form of the subtitle's content.
+func (s Subtitle) Styled() StyledText {
+	return ParseStyledText(s.Content)
+}
+
+// PlainText returns the subtitle's content without any formatting.
+func (s Subtitle) PlainText() string {
+	return ParseStyledText(s.Content).PlainText()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseStyledText(t *testing.T) {
+	type testpair struct {
+		input    string
+		expected StyledText
+	}
+
+	var tests = []testpair{
+		{"Plain text\nover two lines", StyledText{[]Span{{"Plain text\nover two lines", TextStyle{}}}, Position{}}},
+		{"<i>Winter</i> is <b>coming</b>", StyledText{[]Span{
+			{"Winter", TextStyle{Italic: true}},
+			{" is ", TextStyle{}},
+			{"coming", TextStyle{Bold: true}},
+		}, Position{}}},
+		{"<i>Nested <b>tags</b></i>", StyledText{[]Span{
+			{"Nested ", TextStyle{Italic: true}},
+			{"tags", TextStyle{Bold: true, Italic: true}},
+		}, Position{}}},
+		{`<font color="#FF0000">Red</font> <U>wedding</U>`, StyledText{[]Span{
+			{"Red", TextStyle{Color: "#ff0000"}},
+			{" ", TextStyle{}},
+			{"wedding", TextStyle{Underline: true}},
+		}, Position{}}},
+		{`{\an8}{\i1}Top{\i0} text`, StyledText{[]Span{
+			{"Top", TextStyle{Italic: true}},
+			{" text", TextStyle{}},
+		}, Position{Alignment: 8}}},
+		{`{\c&H00FFFF&}Yellow{\r}`, StyledText{[]Span{{"Yellow", TextStyle{Color: "#ffff00"}}}, Position{}}},
+		{"<v Jon Snow><c.yellow.loud>Ghost!</c></v>", StyledText{[]Span{
+			{"Ghost!", TextStyle{Color: "yellow", Voice: "Jon Snow", Class: "loud"}},
+		}, Position{}}},
+		{"<ruby>北<rt>きた</rt></ruby>の王", StyledText{[]Span{
+			{"北", TextStyle{Ruby: "きた"}},
+			{"の王", TextStyle{}},
+		}, Position{}}},
+		// Stray closing tags are ignored, and unclosed ones last until the end
+		{"</b>Mis<i>matched</b>", StyledText{[]Span{
+			{"Mis", TextStyle{}},
+			{"matched", TextStyle{Italic: true}},
+		}, Position{}}},
+		{"3 < 4 {not a tag}", StyledText{[]Span{{"3 < 4 {not a tag}", TextStyle{}}}, Position{}}},
+	}
+
+	for _, pair := range tests {
+		res := ParseStyledText(pair.input)
+		if !cmp.Equal(res, pair.expected) {
+			t.Error("For", pair.input, "expected", pair.expected, "got", res)
+		}
+	}
+
+	res := ParseWebVTTText("Tom &amp; Jerry &lt;3")
+	expected := StyledText{[]Span{{"Tom & Jerry <3", TextStyle{}}}, Position{}}
+	if !cmp.Equal(res, expected) {
+		t.Error("Expected", expected, "got", res)
+	}
+}
+
+func TestStyledTextFormats(t *testing.T) {
+	type testpair struct {
+		input          string
+		expectedSRT    string
+		expectedWebVTT string
+		expectedPlain  string
+	}
+
+	var tests = []testpair{
+		{"Plain & simple", "Plain & simple", "Plain &amp; simple", "Plain & simple"},
+		{"<i>Nested <b>tags</b></i>\n<b>Bold</b>", "<i>Nested <b>tags</b></i>\n<b>Bold</b>", "<i>Nested <b>tags</b></i>\n<b>Bold</b>", "Nested tags\nBold"},
+		{`{\an8}<font color="yellow">Top</font>`, `{\an8}<font color="yellow">Top</font>`, "<c.yellow>Top</c>", "Top"},
+		{"<v Arya><c.loud>No</c> <s>one</s></v>", "No <s>one</s>", "<v Arya><c.loud>No</c> one</v>", "No one"},
+		{"<ruby>北<rt>きた</rt></ruby>の王", "北の王", "<ruby>北<rt>きた</rt></ruby>の王", "北の王"},
+		{"Use &lt;i&gt; for italics", "Use &lt;i&gt; for italics", "Use &lt;i&gt; for italics", "Use <i> for italics"},
+		{"<<<<<<< ours (a --> b)", "<<<<<<< ours (a --> b)", "&lt;&lt;&lt;&lt;&lt;&lt;&lt; ours (a --&gt; b)", "<<<<<<< ours (a --> b)"},
+	}
+
+	for _, pair := range tests {
+		styled := ParseStyledText(pair.input)
+		if res := styled.SRT(); res != pair.expectedSRT {
+			t.Error("For", pair.input, "expected SRT", pair.expectedSRT, "got", res)
+		}
+		if res := styled.WebVTT(); res != pair.expectedWebVTT {
+			t.Error("For", pair.input, "expected WebVTT", pair.expectedWebVTT, "got", res)
+		}
+		if res := styled.PlainText(); res != pair.expectedPlain {
+			t.Error("For", pair.input, "expected plain text", pair.expectedPlain, "got", res)
+		}
+		if res := ParseStyledText(styled.Markup()); !cmp.Equal(res, styled) {
+			t.Error("For", pair.input, "expected the markup to parse back to", styled, "got", res)
+		}
+	}
+}
+
+func TestSearchIgnoreMarkup(t *testing.T) {
+	sub := Subtitle{1, time.Duration(time.Second * 1), time.Duration(time.Second * 3), "The <i>King</i> in the <b>North</b>", "", ""}
+	input := SubtitleFile{[]Subtitle{sub}, ""}
+
+	res, err := SearchSubtitles(input, `King in`, SearchOptions{})
+	if err != nil || len(res) != 0 {
+		t.Error("Expected the tags to prevent a match, got", res, err)
+	}
+	res, err = SearchSubtitles(input, `King in`, SearchOptions{IgnoreMarkup: true})
+	expected := []MatchSpan{{4, 11, 4, 11, "King in"}}
+	if err != nil || len(res) != 1 || !cmp.Equal(res[0].Matches, expected) {
+		t.Error("Expected", expected, "got", res, err)
+	}
+}
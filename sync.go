@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultSyncRates are the pace rates tried when synchronizing against
+// an audio track. Besides leaving the pace untouched, they cover the
+// usual mismatches between 23.976, 24 and 25 frames-per-second releases.
+var DefaultSyncRates = []float64{
+	1.,
+	23.976 / 24.,
+	24. / 23.976,
+	23.976 / 25.,
+	25. / 23.976,
+	24. / 25.,
+	25. / 24.,
+}
+
+// SyncResult describes the best alignment found between a subtitle file
+// and a speech timeline. The subtitles should first be paced by Rate
+// and then timeshifted by Offset, see ApplySync.
+type SyncResult struct {
+	Offset time.Duration
+	Rate   float64
+	// Fraction of the total subtitle display time that falls on speech
+	Overlap float64
+}
+
+// FindAudioSync searches for the pace rate and offset (up to maxOffset
+// in either direction) that best line up the subtitle entries with the
+// speech detected in an audio track. Subtitles over silence are
+// penalized as much as subtitles over speech are rewarded, while speech
+// without subtitles is ignored, as it's common for untranslated chatter.
+// If rates is empty, DefaultSyncRates is used.
+func FindAudioSync(subfile SubtitleFile, speech SpeechTimeline, maxOffset time.Duration, rates []float64) (SyncResult, error) {
+	var res SyncResult
+	if len(subfile.Subtitles) == 0 {
+		return res, errors.New("Cannot synchronize an empty subtitle file")
+	}
+	if speech.Frame <= 0 || len(speech.Speech) == 0 {
+		return res, errors.New("Cannot synchronize against an empty speech timeline")
+	}
+	if maxOffset < 0 {
+		return res, errors.New("The maximum offset should not be negative")
+	}
+	if len(rates) == 0 {
+		rates = DefaultSyncRates
+	}
+
+	// prefix[i] holds the number of speech frames before frame i,
+	// so that speech within any interval is counted in constant time
+	n := len(speech.Speech)
+	prefix := make([]int, n+1)
+	for i, v := range speech.Speech {
+		prefix[i+1] = prefix[i]
+		if v {
+			prefix[i+1]++
+		}
+	}
+	if prefix[n] == 0 {
+		return res, errors.New("No speech was detected in the audio track")
+	}
+	speechIn := func(a, b int) int {
+		a, b = clampFrame(a, n), clampFrame(b, n)
+		if b <= a {
+			return 0
+		}
+		return prefix[b] - prefix[a]
+	}
+
+	maxShift := int(maxOffset / speech.Frame)
+	bestScore, bestInside, bestTotal := 0, 0, 0
+	found := false
+	starts := make([]int, len(subfile.Subtitles))
+	ends := make([]int, len(subfile.Subtitles))
+	for _, rate := range rates {
+		if rate <= 0 {
+			return res, errors.New("Input rate should be a positive, floating-point number")
+		}
+		total := 0
+		for i, sub := range subfile.Subtitles {
+			starts[i] = int(paceDuration(sub.Start, rate) / speech.Frame)
+			ends[i] = int(paceDuration(sub.End, rate) / speech.Frame)
+			if ends[i] > starts[i] {
+				total += ends[i] - starts[i]
+			}
+		}
+		if total == 0 {
+			continue
+		}
+
+		// Offsets are tried from zero outwards, so that on equal scores
+		// the smallest correction is preferred
+		for k := 0; k <= 2*maxShift; k++ {
+			shift := (k + 1) / 2
+			if k%2 == 0 {
+				shift = -shift
+			}
+			inside := 0
+			for i := range starts {
+				inside += speechIn(starts[i]+shift, ends[i]+shift)
+			}
+			score := 2*inside - total
+			if !found || score > bestScore {
+				found = true
+				bestScore, bestInside, bestTotal = score, inside, total
+				res.Rate = rate
+				res.Offset = time.Duration(shift) * speech.Frame
+			}
+		}
+	}
+	if !found {
+		return res, errors.New("The subtitle entries have no running time to synchronize")
+	}
+	res.Overlap = float64(bestInside) / float64(bestTotal)
+	return res, nil
+}
+
+func clampFrame(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// ApplySync paces and then timeshifts a subtitle file, according
+// to the alignment returned by FindAudioSync.
+func ApplySync(subfile SubtitleFile, sync SyncResult) (SubtitleFile, error) {
+	res, err := PaceSubtitleFile(subfile, sync.Rate)
+	if err != nil {
+		return subfile, err
+	}
+	res = TimeshiftSubtitleFile(res, sync.Offset)
+	res.Headers = subfile.Headers
+	return res, nil
+}
+
+// SyncSubtitleFileToAudio runs voice activity detection on a PCM WAV file
+// and synchronizes the subtitle file to the speech found in it.
+// The WAV track should be extracted from the video beforehand, eg. by
+// `ffmpeg -i video.mkv -vn -ac 1 -ar 16000 audio.wav`.
+func SyncSubtitleFileToAudio(subfile SubtitleFile, wavfile string, maxOffset time.Duration) (SubtitleFile, SyncResult, error) {
+	var res SyncResult
+	audio, err := ParseWAVFile(wavfile)
+	if err != nil {
+		return subfile, res, err
+	}
+	speech, err := DetectVoiceActivity(audio, DefaultVADFrame)
+	if err != nil {
+		return subfile, res, err
+	}
+	res, err = FindAudioSync(subfile, speech, maxOffset, nil)
+	if err != nil {
+		return subfile, res, err
+	}
+	synced, err := ApplySync(subfile, res)
+	return synced, res, err
+}
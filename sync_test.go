@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var syncTestSpeech = []testInterval{
+	{2 * time.Second, 3200 * time.Millisecond},
+	{4 * time.Second, 6500 * time.Millisecond},
+	{8100 * time.Millisecond, 8900 * time.Millisecond},
+	{11 * time.Second, 14 * time.Second},
+	{15500 * time.Millisecond, 16200 * time.Millisecond},
+	{19 * time.Second, 21400 * time.Millisecond},
+	{23 * time.Second, 24 * time.Second},
+	{26500 * time.Millisecond, 29 * time.Second},
+}
+
+// subtitlesFromIntervals creates a subtitle entry for each interval, with
+// its timestamps passed through `timing` to simulate an out-of-sync file.
+func subtitlesFromIntervals(intervals []testInterval, timing func(time.Duration) time.Duration) SubtitleFile {
+	var res SubtitleFile
+	for i, iv := range intervals {
+		res.Subtitles = append(res.Subtitles, Subtitle{i + 1, timing(iv.start), timing(iv.end), "line", "", ""})
+	}
+	return res
+}
+
+func TestFindAudioSync(t *testing.T) {
+	audio := PCMAudio{8000, synthesizeSpeech(8000, 32*time.Second, syncTestSpeech)}
+	speech, err := DetectVoiceActivity(audio, DefaultVADFrame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type testpair struct {
+		input          SubtitleFile
+		expectedRate   float64
+		expectedOffset time.Duration
+	}
+	var tests = []testpair{
+		{
+			subtitlesFromIntervals(syncTestSpeech, func(d time.Duration) time.Duration { return d }),
+			1.,
+			0,
+		},
+		{
+			subtitlesFromIntervals(syncTestSpeech, func(d time.Duration) time.Duration { return d - 1500*time.Millisecond }),
+			1.,
+			1500 * time.Millisecond,
+		},
+		{
+			subtitlesFromIntervals(syncTestSpeech, func(d time.Duration) time.Duration { return d + 3210*time.Millisecond }),
+			1.,
+			-3210 * time.Millisecond,
+		},
+		{
+			// Timed against a 25fps release, but the video runs at 23.976fps
+			subtitlesFromIntervals(syncTestSpeech, func(d time.Duration) time.Duration {
+				return time.Duration(float64(d)*23.976/25.) + 700*time.Millisecond
+			}),
+			23.976 / 25.,
+			-700 * time.Millisecond,
+		},
+	}
+
+	for _, pair := range tests {
+		actual, err := FindAudioSync(pair.input, speech, 5*time.Second, nil)
+		if err != nil {
+			t.Errorf("Testing FindAudioSync. Got unexpected error %v", err)
+			continue
+		}
+		diff := actual.Offset - pair.expectedOffset
+		if actual.Rate != pair.expectedRate || diff > 50*time.Millisecond || diff < -50*time.Millisecond {
+			t.Errorf("Testing FindAudioSync. Expected rate %v and offset %v but got %v and %v instead", pair.expectedRate, pair.expectedOffset, actual.Rate, actual.Offset)
+		}
+		if actual.Overlap < 0.9 {
+			t.Errorf("Testing FindAudioSync. Expected an overlap of at least 0.9 but got %v instead", actual.Overlap)
+		}
+	}
+
+	_, err = FindAudioSync(SubtitleFile{}, speech, time.Second, nil)
+	if err == nil || err.Error() != "Cannot synchronize an empty subtitle file" {
+		t.Errorf("Testing FindAudioSync with an empty file. Got unexpected error %v", err)
+	}
+	silence := SpeechTimeline{DefaultVADFrame, make([]bool, 100)}
+	_, err = FindAudioSync(tests[0].input, silence, time.Second, nil)
+	if err == nil || err.Error() != "No speech was detected in the audio track" {
+		t.Errorf("Testing FindAudioSync with silence. Got unexpected error %v", err)
+	}
+}
+
+func TestApplySync(t *testing.T) {
+	type testpair struct {
+		rate     float64
+		offset   time.Duration
+		expected time.Duration
+	}
+
+	// A cue an hour into a feature, which should not drift
+	input := SubtitleFile{[]Subtitle{{1, time.Hour, time.Hour + 2*time.Second, "line", "", ""}}, "headers"}
+	var tests = []testpair{
+		{24. / 23.976, 0, 59*time.Minute + 56*time.Second + 400*time.Millisecond},
+		{24. / 25., 0, time.Hour + 2*time.Minute + 30*time.Second},
+		{25. / 24., -time.Second, 57*time.Minute + 35*time.Second},
+	}
+
+	for _, pair := range tests {
+		actual, err := ApplySync(input, SyncResult{pair.offset, pair.rate, 1})
+		if err != nil || actual.Subtitles[0].Start.Round(time.Millisecond) != pair.expected {
+			t.Errorf("Testing ApplySync with rate %v. Expected the cue to start at %v but got %v and %v instead", pair.rate, pair.expected, actual.Subtitles[0].Start, err)
+		}
+	}
+}
+
+func TestSyncSubtitleFileToAudio(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gophersub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fn := filepath.Join(dir, "speech.wav")
+	samples := synthesizeSpeech(16000, 32*time.Second, syncTestSpeech)
+	if err := ioutil.WriteFile(fn, encodeWAV(samples, 16000, 2, 16), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	input := subtitlesFromIntervals(syncTestSpeech, func(d time.Duration) time.Duration { return d + 2*time.Second })
+	input.Headers = "headers"
+	actual, sync, err := SyncSubtitleFileToAudio(input, fn, 4*time.Second)
+	if err != nil {
+		t.Fatalf("Testing SyncSubtitleFileToAudio. Got unexpected error %v", err)
+	}
+	if sync.Rate != 1. || sync.Offset < -2050*time.Millisecond || sync.Offset > -1950*time.Millisecond {
+		t.Errorf("Testing SyncSubtitleFileToAudio. Expected rate 1 and offset -2s but got %v and %v instead", sync.Rate, sync.Offset)
+	}
+	if actual.Headers != input.Headers || len(actual.Subtitles) != len(input.Subtitles) {
+		t.Errorf("Testing SyncSubtitleFileToAudio. Expected the headers and entries to be kept, but got %v", actual)
+	}
+	for i, sub := range actual.Subtitles {
+		if diff := sub.Start - syncTestSpeech[i].start; diff > 50*time.Millisecond || diff < -50*time.Millisecond {
+			t.Errorf("Testing SyncSubtitleFileToAudio. Expected entry %v to start at %v but got %v instead", sub.Index, syncTestSpeech[i].start, sub.Start)
+		}
+	}
+
+	if _, _, err := SyncSubtitleFileToAudio(input, "wrongfilename", time.Second); err == nil {
+		t.Errorf("Testing SyncSubtitleFileToAudio with a missing file. Expected an error but got none")
+	}
+}
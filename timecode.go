@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timecode is an SMPTE timecode, stored as a frame count from 00:00:00:00.
+// FPS is the actual frame rate of the video, eg. 25 or 29.97; the NTSC
+// rates (23.976, 29.97, 59.94) are treated as exact multiples of 1000/1001.
+// DropFrame timecodes skip frame labels to keep in step with the wall
+// clock, and are only defined for the 29.97 and 59.94 rates.
+type Timecode struct {
+	Frames    int
+	FPS       float64
+	DropFrame bool
+}
+
+// frameRate returns the exact frame rate as a fraction, and the
+// nominal number of frames per timecode second.
+func frameRate(fps float64) (num, den int64, nominal int, err error) {
+	if fps <= 0 || math.IsNaN(fps) || math.IsInf(fps, 0) {
+		return 0, 0, 0, errors.New("The frame rate should be a positive number")
+	}
+	rounded := math.Round(fps)
+	if math.Abs(fps-rounded) < 1e-6 {
+		return int64(rounded), 1, int(rounded), nil
+	}
+	ntsc := math.Round(fps * 1.001)
+	if math.Abs(fps-ntsc*1000/1001) < 0.005 {
+		return int64(ntsc) * 1000, 1001, int(ntsc), nil
+	}
+	return 0, 0, 0, errors.New("Unsupported frame rate " + strconv.FormatFloat(fps, 'f', -1, 64) + ", expected an integer or NTSC (x/1.001) rate")
+}
+
+// droppedFrames returns how many frame labels are skipped every minute
+// (except every tenth minute) by drop-frame timecode for a nominal rate.
+func droppedFrames(nominal int) (int, error) {
+	switch nominal {
+	case 30:
+		return 2, nil
+	case 60:
+		return 4, nil
+	}
+	return 0, errors.New("Drop-frame timecode is only defined for 29.97 and 59.94 frames per second")
+}
+
+func validateTimecodeRate(fps float64, dropFrame bool) (int, error) {
+	_, den, nominal, err := frameRate(fps)
+	if err != nil {
+		return 0, err
+	}
+	if dropFrame {
+		if den != 1001 {
+			return 0, errors.New("Drop-frame timecode is only defined for 29.97 and 59.94 frames per second")
+		}
+		if _, err := droppedFrames(nominal); err != nil {
+			return 0, err
+		}
+	}
+	return nominal, nil
+}
+
+// NewTimecode builds a Timecode from its HH:MM:SS:FF components.
+func NewTimecode(hours, minutes, seconds, frames int, fps float64, dropFrame bool) (Timecode, error) {
+	res := Timecode{0, fps, dropFrame}
+	nominal, err := validateTimecodeRate(fps, dropFrame)
+	if err != nil {
+		return res, err
+	}
+	if hours < 0 || minutes < 0 || seconds < 0 || frames < 0 {
+		return res, errors.New("Timecode components should not be negative")
+	}
+	if minutes > 59 {
+		return res, errors.New("Unexpected parsed minute value, should be between 0 and 60")
+	}
+	if seconds > 59 {
+		return res, errors.New("Unexpected parsed seconds value, should be between 0 and 60")
+	}
+	if frames >= nominal {
+		return res, errors.New("Unexpected parsed frame value, should be between 0 and " + strconv.Itoa(nominal))
+	}
+
+	totalMinutes := hours*60 + minutes
+	res.Frames = ((totalMinutes*60)+seconds)*nominal + frames
+	if dropFrame {
+		drop, _ := droppedFrames(nominal)
+		if seconds == 0 && frames < drop && minutes%10 != 0 {
+			return res, errors.New("Frame label does not exist in drop-frame timecode :" + fmt.Sprintf("%02d:%02d:%02d;%02d", hours, minutes, seconds, frames))
+		}
+		res.Frames -= drop * (totalMinutes - totalMinutes/10)
+	}
+	return res, nil
+}
+
+// ParseTimecode reads an SMPTE timecode of the form HH:MM:SS:FF.
+// A semicolon (or period) before the frames field marks a drop-frame timecode.
+func ParseTimecode(in string, fps float64) (Timecode, error) {
+	in = strings.TrimSpace(in)
+	dropFrame := strings.ContainsAny(in, ";.")
+	fields := strings.FieldsFunc(in, func(r rune) bool { return r == ':' || r == ';' || r == '.' })
+	if len(fields) != 4 {
+		return Timecode{0, fps, dropFrame}, errors.New("Wrong Number of fields resulting from input timecode")
+	}
+	var parts [4]int
+	for i, field := range fields {
+		v, err := strconv.Atoi(field)
+		if err != nil {
+			return Timecode{0, fps, dropFrame}, errors.New("Invalid timecode field :" + field)
+		}
+		parts[i] = v
+	}
+	return NewTimecode(parts[0], parts[1], parts[2], parts[3], fps, dropFrame)
+}
+
+// TimecodeFromDuration returns the timecode of the frame closest to d.
+func TimecodeFromDuration(d time.Duration, fps float64, dropFrame bool) (Timecode, error) {
+	res := Timecode{0, fps, dropFrame}
+	if _, err := validateTimecodeRate(fps, dropFrame); err != nil {
+		return res, err
+	}
+	if d < 0 {
+		return res, errors.New("Timecodes cannot represent negative durations")
+	}
+	num, den, _, _ := frameRate(fps)
+	res.Frames = int(math.Round(float64(d) * float64(num) / float64(den) / float64(time.Second)))
+	return res, nil
+}
+
+// Duration returns the real time elapsed from 00:00:00:00 until this frame.
+func (tc Timecode) Duration() time.Duration {
+	num, den, _, err := frameRate(tc.FPS)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(math.Round(float64(tc.Frames) * float64(den) / float64(num) * float64(time.Second)))
+}
+
+// Components returns the HH, MM, SS and FF labels of the timecode.
+func (tc Timecode) Components() (hours, minutes, seconds, frames int) {
+	_, _, nominal, err := frameRate(tc.FPS)
+	if err != nil {
+		return 0, 0, 0, 0
+	}
+	f := tc.Frames
+	if tc.DropFrame {
+		if drop, err := droppedFrames(nominal); err == nil {
+			perMinute := nominal*60 - drop
+			perTenMinutes := nominal*600 - drop*9
+			tens, rem := f/perTenMinutes, f%perTenMinutes
+			f += drop * 9 * tens
+			if rem > drop {
+				f += drop * ((rem - drop) / perMinute)
+			}
+		}
+	}
+	frames = f % nominal
+	seconds = (f / nominal) % 60
+	minutes = (f / (nominal * 60)) % 60
+	hours = f / (nominal * 3600)
+	return
+}
+
+func (tc Timecode) String() string {
+	h, m, s, f := tc.Components()
+	sep := ":"
+	if tc.DropFrame {
+		sep = ";"
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", h, m, s, sep, f)
+}
+
+// AddFrames returns the timecode n frames later (or earlier, if n is negative).
+func (tc Timecode) AddFrames(n int) (Timecode, error) {
+	res := tc
+	res.Frames += n
+	if res.Frames < 0 {
+		return tc, errors.New("The resulting timecode would be negative")
+	}
+	return res, nil
+}
+
+// Add returns the sum of two timecodes of the same frame rate.
+func (tc Timecode) Add(other Timecode) (Timecode, error) {
+	if tc.FPS != other.FPS {
+		return tc, errors.New("Cannot add timecodes of different frame rates")
+	}
+	return tc.AddFrames(other.Frames)
+}
+
+// Sub returns the difference of two timecodes of the same frame rate.
+func (tc Timecode) Sub(other Timecode) (Timecode, error) {
+	if tc.FPS != other.FPS {
+		return tc, errors.New("Cannot subtract timecodes of different frame rates")
+	}
+	return tc.AddFrames(-other.Frames)
+}
+
+// ToProgrammeTime shifts a subtitle file timed from the start of the media
+// so that it's timed against the programme start timecode, eg. 10:00:00:00.
+func ToProgrammeTime(subfile SubtitleFile, start Timecode) SubtitleFile {
+	res := TimeshiftSubtitleFile(subfile, start.Duration())
+	res.Headers = subfile.Headers
+	return res
+}
+
+// FromProgrammeTime is the reverse of ToProgrammeTime. It returns an
+// error if any subtitle would start before the programme does.
+func FromProgrammeTime(subfile SubtitleFile, start Timecode) (SubtitleFile, error) {
+	offset := start.Duration()
+	for _, sub := range subfile.Subtitles {
+		if sub.Start < offset {
+			return subfile, errors.New("Subtitle " + strconv.Itoa(sub.Index) + " starts before the programme start timecode " + start.String())
+		}
+	}
+	res := TimeshiftSubtitleFile(subfile, -offset)
+	res.Headers = subfile.Headers
+	return res, nil
+}
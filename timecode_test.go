@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseTimecode(t *testing.T) {
+	type testpair struct {
+		input          string
+		fps            float64
+		expectedFrames int
+		expectedDur    time.Duration
+		expectedErr    error
+	}
+
+	var tests = []testpair{
+		{"01:00:00:00", 25, 90000, time.Hour, nil},
+		{"00:00:01:12", 25, 37, time.Duration(time.Second*1 + time.Millisecond*480), nil},
+		{"01:00:00:00", 29.97, 108000, time.Duration(time.Hour + time.Second*3 + time.Millisecond*600), nil},
+		{"00:01:00;02", 29.97, 1800, time.Duration(time.Minute*1 + time.Millisecond*60), nil},
+		{"00:10:00;00", 29.97, 17982, time.Duration(time.Minute*9 + time.Second*59 + time.Microsecond*999400), nil},
+		{"10:00:00;00", 29.97, 1078920, time.Duration(time.Hour*9 + time.Minute*59 + time.Second*59 + time.Millisecond*964), nil},
+		{"01:00:00;00", 59.94, 215784, time.Duration(time.Minute*59 + time.Second*59 + time.Microsecond*996400), nil},
+		{"00:00:01:00", 23.976, 24, time.Duration(time.Second*1 + time.Millisecond*1), nil},
+		{"00:01:00;00", 29.97, 0, 0, errors.New("Frame label does not exist in drop-frame timecode :00:01:00;00")},
+		{"00:00:00;00", 25, 0, 0, errors.New("Drop-frame timecode is only defined for 29.97 and 59.94 frames per second")},
+		{"00:00:00;00", 23.976, 0, 0, errors.New("Drop-frame timecode is only defined for 29.97 and 59.94 frames per second")},
+		{"00:00:00:25", 25, 0, 0, errors.New("Unexpected parsed frame value, should be between 0 and 25")},
+		{"00:60:00:00", 25, 0, 0, errors.New("Unexpected parsed minute value, should be between 0 and 60")},
+		{"00:00:00", 25, 0, 0, errors.New("Wrong Number of fields resulting from input timecode")},
+		{"00:00:0a:00", 25, 0, 0, errors.New("Invalid timecode field :0a")},
+		{"00:00:00:00", 12.5, 0, 0, errors.New("Unsupported frame rate 12.5, expected an integer or NTSC (x/1.001) rate")},
+		{"00:00:00:00", 0, 0, 0, errors.New("The frame rate should be a positive number")},
+	}
+
+	for _, pair := range tests {
+		actual, err := ParseTimecode(pair.input, pair.fps)
+		if pair.expectedErr != nil {
+			if err == nil || err.Error() != pair.expectedErr.Error() {
+				t.Errorf("Testing ParseTimecode with %v@%v. Expected error %v but got %v instead!", pair.input, pair.fps, pair.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Testing ParseTimecode with %v@%v. Got unexpected error %v", pair.input, pair.fps, err)
+			continue
+		}
+		if actual.Frames != pair.expectedFrames {
+			t.Errorf("Testing ParseTimecode with %v@%v. Expected %v frames but got %v instead", pair.input, pair.fps, pair.expectedFrames, actual.Frames)
+		}
+		if actual.Duration() != pair.expectedDur {
+			t.Errorf("Testing ParseTimecode with %v@%v. Expected duration %v but got %v instead", pair.input, pair.fps, pair.expectedDur, actual.Duration())
+		}
+		if actual.String() != pair.input {
+			t.Errorf("Testing ParseTimecode with %v@%v. Expected it to be formatted back, but got %v instead", pair.input, pair.fps, actual.String())
+		}
+	}
+}
+
+func TestTimecodeDropFrameRoundtrip(t *testing.T) {
+	for _, fps := range []float64{29.97, 59.94} {
+		for frames := 0; frames < 40000; frames++ {
+			tc := Timecode{frames, fps, true}
+			parsed, err := ParseTimecode(tc.String(), fps)
+			if err != nil || parsed != tc {
+				t.Fatalf("Testing drop-frame roundtrip at %v frames@%v. Formatted as %v, parsed back as %v (%v)", frames, fps, tc, parsed, err)
+			}
+		}
+	}
+}
+
+func TestTimecodeFromDuration(t *testing.T) {
+	type testpair struct {
+		input       time.Duration
+		fps         float64
+		dropFrame   bool
+		expected    string
+		expectedErr error
+	}
+
+	var tests = []testpair{
+		{time.Duration(time.Second*1 + time.Millisecond*480), 25, false, "00:00:01:12", nil},
+		{time.Duration(time.Second*1 + time.Millisecond*490), 25, false, "00:00:01:12", nil},
+		{time.Duration(time.Hour + time.Second*3 + time.Millisecond*600), 29.97, false, "01:00:00:00", nil},
+		{time.Duration(time.Hour*9 + time.Minute*59 + time.Second*59 + time.Millisecond*964), 29.97, true, "10:00:00;00", nil},
+		// SRT timestamps are rounded to milliseconds, the closest frame is picked
+		{time.Duration(time.Millisecond * 33), 29.97, true, "00:00:00;01", nil},
+		{time.Duration(-time.Second), 25, false, "", errors.New("Timecodes cannot represent negative durations")},
+		{time.Second, 30, true, "", errors.New("Drop-frame timecode is only defined for 29.97 and 59.94 frames per second")},
+	}
+
+	for _, pair := range tests {
+		actual, err := TimecodeFromDuration(pair.input, pair.fps, pair.dropFrame)
+		if pair.expectedErr != nil {
+			if err == nil || err.Error() != pair.expectedErr.Error() {
+				t.Errorf("Testing TimecodeFromDuration with %v. Expected error %v but got %v instead!", pair.input, pair.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil || actual.String() != pair.expected {
+			t.Errorf("Testing TimecodeFromDuration with %v. Expected %v but got %v (%v) instead", pair.input, pair.expected, actual, err)
+		}
+	}
+}
+
+func TestTimecodeArithmetic(t *testing.T) {
+	start, _ := ParseTimecode("00:00:59;28", 29.97)
+	length, _ := ParseTimecode("00:00:00;04", 29.97)
+
+	sum, err := start.Add(length)
+	if err != nil || sum.String() != "00:01:00;04" {
+		t.Errorf("Testing Timecode.Add. Expected 00:01:00;04 but got %v (%v) instead", sum, err)
+	}
+	diff, err := sum.Sub(start)
+	if err != nil || diff != length {
+		t.Errorf("Testing Timecode.Sub. Expected %v but got %v (%v) instead", length, diff, err)
+	}
+	if _, err := start.Sub(sum); err == nil || err.Error() != "The resulting timecode would be negative" {
+		t.Errorf("Testing Timecode.Sub with a negative result. Got unexpected error %v", err)
+	}
+	pal, _ := ParseTimecode("00:00:01:00", 25)
+	if _, err := start.Add(pal); err == nil || err.Error() != "Cannot add timecodes of different frame rates" {
+		t.Errorf("Testing Timecode.Add with different frame rates. Got unexpected error %v", err)
+	}
+	back, err := start.AddFrames(-2)
+	if err != nil || back.String() != "00:00:59;26" {
+		t.Errorf("Testing Timecode.AddFrames. Expected 00:00:59;26 but got %v (%v) instead", back, err)
+	}
+}
+
+func TestProgrammeTime(t *testing.T) {
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second*1 + time.Millisecond*602), time.Duration(time.Second*3 + time.Millisecond*314), `one`, "", ""},
+			{2, time.Duration(time.Second*4 + time.Millisecond*536), time.Duration(time.Second*7 + time.Millisecond*379), `two`, "", ""},
+		},
+		"headers",
+	}
+	expected := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Hour*10 + time.Second*1 + time.Millisecond*602), time.Duration(time.Hour*10 + time.Second*3 + time.Millisecond*314), `one`, "", ""},
+			{2, time.Duration(time.Hour*10 + time.Second*4 + time.Millisecond*536), time.Duration(time.Hour*10 + time.Second*7 + time.Millisecond*379), `two`, "", ""},
+		},
+		"headers",
+	}
+	start, _ := ParseTimecode("10:00:00:00", 25)
+
+	actual := ToProgrammeTime(input, start)
+	if !cmp.Equal(actual, expected) {
+		t.Errorf("Testing ToProgrammeTime. Expected %v but got %v instead!", expected, actual)
+	}
+	back, err := FromProgrammeTime(actual, start)
+	if err != nil || !cmp.Equal(back, input) {
+		t.Errorf("Testing FromProgrammeTime. Expected %v but got %v (%v) instead!", input, back, err)
+	}
+	_, err = FromProgrammeTime(input, start)
+	if err == nil || err.Error() != "Subtitle 1 starts before the programme start timecode 10:00:00:00" {
+		t.Errorf("Testing FromProgrammeTime with media-timed input. Got unexpected error %v", err)
+	}
+}
@@ -27,6 +27,18 @@ func DurationToTimestampSRT(d time.Duration) string {
 	return res
 }
 
+// DurationToTimestampVTT formats a duration as a WebVTT timestamp, eg. 00:01:02.500
+func DurationToTimestampVTT(d time.Duration) string {
+	return strings.Replace(DurationToTimestampSRT(d), ",", ".", 1)
+}
+
+// DurationToTimestampASS formats a duration as an SSA/ASS timestamp, with
+// a single digit for the hours and centiseconds, eg. 0:01:02.50
+func DurationToTimestampASS(d time.Duration) string {
+	cs := int64(d.Round(10*time.Millisecond) / (10 * time.Millisecond))
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
 func TimestampToDurationSRT(in string) (time.Duration, error) {
 	var res time.Duration
 
@@ -3,8 +3,10 @@ package main
 import (
 	"bufio"
 	"errors"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Exports a SubtitleFile object to an SRT file format.
@@ -18,7 +20,14 @@ func ToSRTFile(subfile SubtitleFile, outfile string) error {
 	}
 	defer f.Close()
 
-	w := bufio.NewWriter(f)
+	return WriteSRT(f, subfile)
+}
+
+// WriteSRT writes a SubtitleFile to out in the SRT file format, converting
+// the formatting of the subtitles to the tags SRT players understand, see
+// StyledText.SRT. WebVTT voices, classes and ruby annotations are dropped.
+func WriteSRT(out io.Writer, subfile SubtitleFile) error {
+	w := bufio.NewWriter(out)
 
 	// SRT Files do not feature header or metadata information,
 	// so this information will not be written to the file
@@ -29,14 +38,70 @@ func ToSRTFile(subfile SubtitleFile, outfile string) error {
 		startStr = DurationToTimestampSRT(sub.Start)
 		endStr = DurationToTimestampSRT(sub.End)
 		idxStr = strconv.Itoa(sub.Index)
-		_, err = w.WriteString(idxStr + "\n")
-		_, err = w.WriteString(startStr + " --> " + endStr + "\n")
-		_, err = w.WriteString(sub.Content)
-		_, err = w.WriteString("\n\n")
-		w.Flush()
+		w.WriteString(idxStr + "\n")
+		w.WriteString(startStr + " --> " + endStr + "\n")
+		w.WriteString(sub.Styled().SRT())
+		w.WriteString("\n\n")
 	}
 	//w.WriteString("\n")
-	w.Flush()
+	return w.Flush()
+}
+
+// WriteWebVTT writes a SubtitleFile to out in the WebVTT file format,
+// converting the formatting of the subtitles, see StyledText.WebVTT, and
+// their position into cue settings.
+func WriteWebVTT(out io.Writer, subfile SubtitleFile) error {
+	w := bufio.NewWriter(out)
+	w.WriteString("WEBVTT\n\n")
+	for _, sub := range subfile.Subtitles {
+		w.WriteString(strconv.Itoa(sub.Index) + "\n")
+		styled := sub.Styled()
+		w.WriteString(DurationToTimestampVTT(sub.Start) + " --> " + DurationToTimestampVTT(sub.End))
+		if settings := styled.Position.WebVTTSettings(); settings != "" {
+			w.WriteString(" " + settings)
+		}
+		w.WriteString("\n" + styled.WebVTT())
+		w.WriteString("\n\n")
+	}
+	return w.Flush()
+}
+
+// assHeader is the script header written by WriteASS, with a single
+// default style of white text at the bottom center.
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+PlayResX: 384
+PlayResY: 288
 
-	return nil
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,16,&H00FFFFFF,&H000000FF,&H00000000,&H00000000,0,0,0,0,100,100,0,0,1,1,0,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+// WriteASS writes a SubtitleFile to out in the Advanced SubStation Alpha
+// file format, converting the formatting of the subtitles, see StyledText.ASS.
+func WriteASS(out io.Writer, subfile SubtitleFile) error {
+	w := bufio.NewWriter(out)
+	w.WriteString(assHeader)
+	for _, sub := range subfile.Subtitles {
+		fields := []string{"0", DurationToTimestampASS(sub.Start), DurationToTimestampASS(sub.End), "Default", "", "0", "0", "0", "", sub.Styled().ASS()}
+		w.WriteString("Dialogue: " + strings.Join(fields, ",") + "\n")
+	}
+	return w.Flush()
+}
+
+// WriteSubtitles writes a SubtitleFile to out in the given format.
+func WriteSubtitles(out io.Writer, subfile SubtitleFile, format TextFormat) error {
+	switch format {
+	case FormatWebVTT:
+		return WriteWebVTT(out, subfile)
+	case FormatASS:
+		return WriteASS(out, subfile)
+	}
+	return WriteSRT(out, subfile)
 }
@@ -53,3 +53,35 @@ func TestToSRTFile(t *testing.T) {
 		}
 	}
 }
+
+func TestWriteSubtitles(t *testing.T) {
+	type testpair struct {
+		format   TextFormat
+		expected string
+	}
+
+	input := SubtitleFile{
+		[]Subtitle{
+			{1, time.Duration(time.Second*1 + time.Millisecond*602), time.Duration(time.Second*3 + time.Millisecond*314), "<i>Winter</i> & <b>summer", "", ""},
+			{2, time.Duration(time.Hour + time.Second*4 + time.Millisecond*536), time.Duration(time.Hour + time.Second*7 + time.Millisecond*379), "{\\an8}Two\nlines", "", ""},
+			{3, time.Duration(time.Hour + time.Second*8), time.Duration(time.Hour + time.Second*9), "<v Jon><c.loud>North</c></v> <ruby>北<rt>きた</rt></ruby>", "", ""},
+		},
+		"",
+	}
+
+	var tests = []testpair{
+		{FormatSRT, "1\n00:00:01,602 --> 00:00:03,314\n<i>Winter</i> & <b>summer</b>\n\n2\n01:00:04,536 --> 01:00:07,379\n{\\an8}Two\nlines\n\n3\n01:00:08,000 --> 01:00:09,000\nNorth 北\n\n"},
+		{FormatWebVTT, "WEBVTT\n\n1\n00:00:01.602 --> 00:00:03.314\n<i>Winter</i> &amp; <b>summer</b>\n\n2\n01:00:04.536 --> 01:00:07.379 line:0\nTwo\nlines\n\n3\n01:00:08.000 --> 01:00:09.000\n<v Jon><c.loud>North</c></v> <ruby>北<rt>きた</rt></ruby>\n\n"},
+		{FormatASS, assHeader + "Dialogue: 0,0:00:01.60,0:00:03.31,Default,,0,0,0,,{\\i1}Winter{\\i0} & {\\b1}summer\nDialogue: 0,1:00:04.54,1:00:07.38,Default,,0,0,0,,{\\an8}Two\\Nlines\nDialogue: 0,1:00:08.00,1:00:09.00,Default,,0,0,0,,North 北\n"},
+	}
+
+	for _, pair := range tests {
+		var out bytes.Buffer
+		if err := WriteSubtitles(&out, input, pair.format); err != nil {
+			t.Error("Writing", pair.format, "got error", err)
+		}
+		if out.String() != pair.expected {
+			t.Errorf("Writing %v. Expected\n%v\nbut got\n%v", pair.format, pair.expected, out.String())
+		}
+	}
+}